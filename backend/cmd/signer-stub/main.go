@@ -0,0 +1,94 @@
+// Command signer-stub is a minimal remote signer for local development and
+// testing of SIGNER_BACKEND=remote. It signs every request with
+// DEPLOYER_PRIVATE_KEY and serves eth_signTransaction and
+// eth_signTypedData_v4 as well as Clef's account_signTransaction and
+// account_signTypedData. It performs no approval and must never hold a
+// key with real funds.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/joho/godotenv"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
+)
+
+type signAPI struct {
+	signer signer.Signer
+}
+
+func (api *signAPI) SignTransaction(ctx context.Context, args signer.SendTxArgs) (*signer.SignTxResult, error) {
+	if args.From.Address() != api.signer.Address() {
+		return nil, fmt.Errorf("unknown account %s", args.From.Address().Hex())
+	}
+	if args.ChainID == nil || args.GasPrice == nil {
+		return nil, fmt.Errorf("chainId and gasPrice are required")
+	}
+
+	var data []byte
+	if args.Data != nil {
+		data = *args.Data
+	}
+	tx := types.NewTx(&types.LegacyTx{
+		Nonce:    uint64(args.Nonce),
+		To:       args.To,
+		Value:    (*big.Int)(&args.Value),
+		Gas:      uint64(args.Gas),
+		GasPrice: args.GasPrice.ToInt(),
+		Data:     data,
+	})
+	signed, err := api.signer.SignTx(ctx, tx, args.ChainID.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signer.SignTxResult{Raw: raw, Tx: signed}, nil
+}
+
+func (api *signAPI) SignTypedData(ctx context.Context, from common.MixedcaseAddress, data apitypes.TypedData) (hexutil.Bytes, error) {
+	if from.Address() != api.signer.Address() {
+		return nil, fmt.Errorf("unknown account %s", from.Address().Hex())
+	}
+	return api.signer.SignTypedData(ctx, data)
+}
+
+// SignTypedData_v4 is served as eth_signTypedData_v4.
+func (api *signAPI) SignTypedData_v4(ctx context.Context, from common.MixedcaseAddress, data apitypes.TypedData) (hexutil.Bytes, error) {
+	return api.SignTypedData(ctx, from, data)
+}
+
+func main() {
+	_ = godotenv.Load()
+	addr := flag.String("addr", "127.0.0.1:8550", "listen address")
+	flag.Parse()
+
+	s, err := signer.NewKeySigner(os.Getenv("DEPLOYER_PRIVATE_KEY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := rpc.NewServer()
+	api := &signAPI{signer: s}
+	for _, namespace := range []string{"eth", "account"} {
+		if err := server.RegisterName(namespace, api); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	log.Printf("Stub signer for %s listening on http://%s", s.Address().Hex(), *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
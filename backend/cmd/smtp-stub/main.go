@@ -0,0 +1,137 @@
+// Command smtp-stub is a minimal SMTP server for local development and
+// testing of email notifications with SMTP_TLS=none. It accepts every
+// message without authentication, prints it and, with -dir, saves it as an
+// .eml file. With -fail it rejects every message with a temporary error,
+// to exercise outbox retries. It must never be exposed beyond localhost.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+type stub struct {
+	dir  string
+	fail bool
+	seq  atomic.Int64
+}
+
+func (s *stub) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) bool {
+		return tp.PrintfLine("%d %s", code, msg) == nil
+	}
+
+	if !reply(220, "smtp-stub ready") {
+		return
+	}
+	var from string
+	var to []string
+	for {
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Minute))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		ok := true
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = tp.PrintfLine("250-smtp-stub") == nil && reply(250, "8BITMIME")
+		case "HELO":
+			ok = reply(250, "smtp-stub")
+		case "MAIL":
+			from, to = address(arg), nil
+			ok = reply(250, "OK")
+		case "RCPT":
+			to = append(to, address(arg))
+			ok = reply(250, "OK")
+		case "DATA":
+			if from == "" || len(to) == 0 {
+				ok = reply(503, "MAIL and RCPT first")
+				break
+			}
+			if !reply(354, "End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			body, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			if s.fail {
+				ok = reply(451, "Rejected by -fail")
+				break
+			}
+			s.save(from, to, body)
+			from, to = "", nil
+			ok = reply(250, "OK queued")
+		case "RSET":
+			from, to = "", nil
+			ok = reply(250, "OK")
+		case "NOOP":
+			ok = reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			ok = reply(502, "Command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (s *stub) save(from string, to []string, body []byte) {
+	n := s.seq.Add(1)
+	log.Printf("Message %d from %s to %s\n%s", n, from, strings.Join(to, ", "), body)
+	if s.dir == "" {
+		return
+	}
+	name := filepath.Join(s.dir, fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405"), n))
+	if err := os.WriteFile(name, body, 0o644); err != nil {
+		log.Printf("Failed to save message %d: %v", n, err)
+	}
+}
+
+// address extracts the address of "FROM:<a@b> SIZE=1" or "TO:<a@b>".
+func address(arg string) string {
+	_, rest, _ := strings.Cut(arg, ":")
+	rest, _, _ = strings.Cut(strings.TrimSpace(rest), " ")
+	return strings.Trim(rest, "<>")
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:2525", "listen address")
+	dir := flag.String("dir", "", "directory to save messages to as .eml files")
+	fail := flag.Bool("fail", false, "reject every message with a temporary error")
+	flag.Parse()
+
+	if *dir != "" {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Stub SMTP server listening on %s", *addr)
+	s := &stub{dir: *dir, fail: *fail}
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go s.serve(conn)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/user/crypto-invoice-generator/backend/internal/application"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+func main() {
+	// Load .env
+	envErr := godotenv.Load()
+
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file; environment variables override it")
+	flag.Parse()
+
+	// Load Config
+	cfg, err := config.Load(*configFile)
+	if flag.Arg(0) == "config" {
+		os.Exit(config.RunCommand(os.Stdout, os.Stderr, flag.Args()[1:], cfg, err))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Setup structured logging before anything else logs
+	if err := logging.Setup(cfg); err != nil {
+		log.Fatal("Invalid logging configuration: ", err)
+	}
+	if envErr != nil {
+		logging.For("app").Info("No .env file found")
+	}
+
+	// Start background jobs only
+	application.StartWorker(cfg)
+}
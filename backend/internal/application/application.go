@@ -8,69 +8,260 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
 	"github.com/user/crypto-invoice-generator/backend/internal/config"
 	"github.com/user/crypto-invoice-generator/backend/internal/db"
+	"github.com/user/crypto-invoice-generator/backend/internal/email"
+	"github.com/user/crypto-invoice-generator/backend/internal/health"
+	"github.com/user/crypto-invoice-generator/backend/internal/leader"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
 	"github.com/user/crypto-invoice-generator/backend/internal/server"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"gorm.io/gorm"
 )
 
 type ServiceClient struct {
 	Database *gorm.DB
+	Eth      chain.Client
+	Signer   signer.Signer
+	Deposits *signer.DepositWallet
+	// Mailer is nil without SMTP_HOST.
+	Mailer email.Sender
+
+	shutdownTracing func(context.Context) error
 }
 
 func StartApp(cfg *config.Config) {
 	client := initServiceClient(cfg)
-	router := gin.Default()
+	router := gin.New()
+	// Without this gin believes X-Forwarded-For from any client, which
+	// would let callers pick the IP that rate limits count against
+	if err := router.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		logging.For("http").Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(
+		otelgin.Middleware(cfg.Tracing.ServiceName),
+		logging.RequestID,
+		logging.AccessLog,
+		gin.RecoveryWithWriter(logging.For("http").WriterLevel(logrus.ErrorLevel)),
+	)
 
 	srv := &http.Server{
 		Addr:    ":" + cfg.HTTP.Port,
 		Handler: router,
 	}
 
-	app := server.NewServer(cfg, router, client.Database)
-	server.ConfigRoutesAndSchedulers(app)
+	app := server.NewServer(cfg, router, client.Database, client.Eth, client.Signer, client.Deposits, client.Mailer)
+	server.ConfigRoutes(app)
+
+	// The funds monitor guards this process's invoice creation, so it runs
+	// on every API instance rather than under leader election.
+	components := []lifecycle.Component{app.Funds}
+	if cfg.Worker.BackgroundJobs {
+		components = append(components, newJobs(app))
+	} else {
+		logging.For("app").Info("Background jobs disabled; run cmd/worker to process payments and expiries")
+	}
+	jobs := startComponents(components...)
 
 	serverErr := make(chan error, 1)
 	go func() {
-		logrus.Infof("Server starting on port %s", cfg.HTTP.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			serverErr <- err
-		}
+		logging.For("app").Infof("Server starting on port %s", cfg.HTTP.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+	waitForShutdown(srv, app, jobs, client, serverErr)
+}
+
+// StartWorker runs only the background jobs (watcher and expiry checker),
+// without serving the API. Metrics and health checks are served on a
+// separate port.
+func StartWorker(cfg *config.Config) {
+	client := initServiceClient(cfg)
+	app := server.NewServer(cfg, nil, client.Database, client.Eth, client.Signer, client.Deposits, client.Mailer)
+
+	jobs := startComponents(newJobs(app))
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	checker := health.NewChecker(cfg, client.Database, client.Eth, client.Signer.Address())
+	mux.HandleFunc("/healthz", checker.Liveness)
+	mux.HandleFunc("/readyz", checker.Readiness)
+	mux.HandleFunc("/debug/status", checker.DebugStatus)
+	srv := &http.Server{
+		Addr:    ":" + cfg.Worker.MetricsPort,
+		Handler: mux,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logging.For("app").Infof("Metrics server starting on port %s", cfg.Worker.MetricsPort)
+		serverErr <- srv.ListenAndServe()
 	}()
-	waitForShutdown(srv, app, serverErr)
+	waitForShutdown(srv, app, jobs, client, serverErr)
 }
 
 func initServiceClient(cfg *config.Config) *ServiceClient {
-	dbConn := db.InitDB(cfg.DB)
+	logrus.AddHook(health.RecentErrors)
+
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logging.For("app").Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	dbConn := db.InitDB(cfg.DB, cfg.Logging.SlowQueryThreshold)
+
+	rpcClient, err := ethclient.Dial(cfg.Ethereum.RPCURL)
+	if err != nil {
+		logging.For("app").Fatalf("Failed to connect to Ethereum RPC: %v", err)
+	}
+	ethClient := chain.NewClient(rpcClient)
+
+	// Metric labels need the chain ID; ask the node if it is not configured.
+	if cfg.Ethereum.ChainID == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		chainID, err := ethClient.ChainID(ctx)
+		cancel()
+		if err != nil {
+			logging.For("app").Fatalf("Failed to get chain ID: %v", err)
+		}
+		cfg.Ethereum.ChainID = chainID.Int64()
+	}
+
+	if sqlDB, err := dbConn.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB, cfg.DB.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	txSigner, err := signer.New(ctx, cfg)
+	if err != nil {
+		logging.For("app").Fatalf("Failed to set up %s signer: %v", cfg.Signer.Backend, err)
+	}
+	if cfg.Signer.Backend == config.SignerBackendKey {
+		logging.For("app").Warn("Signing with DEPLOYER_PRIVATE_KEY; use the keystore or remote signer outside development")
+	}
+	if err := signer.VerifyOwner(ctx, ethClient, common.HexToAddress(cfg.Ethereum.ContractAddress), txSigner); err != nil {
+		logging.For("app").Fatalf("Signer check failed: %v", err)
+	}
+	logging.For("app").WithField("signer", txSigner.Address().Hex()).Infof("Using %s signer", cfg.Signer.Backend)
+	metrics.RegisterSignerAccount(ethClient, txSigner.Address())
+
+	var deposits *signer.DepositWallet
+	if cfg.Deposit.Enabled {
+		deposits, err = signer.NewDepositWallet(cfg.Signer, cfg.Deposit.XPub)
+		if err != nil {
+			logging.For("app").Fatalf("Failed to load deposit wallet: %v", err)
+		}
+		if deposits == nil {
+			logging.For("app").Warn("SIGNER_DEPOSIT_XPRV is not set; this process will not sweep deposit addresses")
+		}
+	}
+
+	var mailer email.Sender
+	if cfg.Email.Enabled() {
+		sender, err := email.NewSMTPSender(cfg.Email)
+		if err != nil {
+			logging.For("app").Fatalf("Failed to set up email: %v", err)
+		}
+		mailer = sender
+	}
+
 	return &ServiceClient{
-		Database: dbConn,
+		Database:        dbConn,
+		Eth:             ethClient,
+		Signer:          txSigner,
+		Deposits:        deposits,
+		Mailer:          mailer,
+		shutdownTracing: shutdownTracing,
 	}
 }
 
-func waitForShutdown(srv *http.Server, app *server.Server, serverErr chan error) {
+// Close releases the RPC and database connections and flushes pending
+// spans. It must run after every component using them has stopped.
+func (c *ServiceClient) Close(ctx context.Context) {
+	c.Eth.Close()
+	if sqlDB, err := c.Database.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			logging.For("app").Errorf("Failed to close DB: %v", err)
+		}
+	}
+	if err := c.shutdownTracing(ctx); err != nil {
+		logging.For("app").Errorf("Failed to flush traces: %v", err)
+	}
+}
+
+// newJobs builds the background jobs under leader election, so that only one
+// instance across all API and worker replicas runs them at a time.
+func newJobs(app *server.Server) lifecycle.Component {
+	sqlDB, err := app.DB.DB()
+	if err != nil {
+		logging.For("app").Fatalf("Failed to get DB handle for leader election: %v", err)
+	}
+
+	elector := leader.NewElector(sqlDB, app.Cfg.Worker.LeaderLockKey, app.Cfg.Worker.LeaderRetryInterval)
+	return leader.NewSupervisor(elector, func() *lifecycle.Group {
+		return server.NewSchedulers(app)
+	})
+}
+
+// startComponents starts the process's long-running components as a group,
+// which waitForShutdown stops in reverse order.
+func startComponents(components ...lifecycle.Component) *lifecycle.Group {
+	group := lifecycle.NewGroup(components...)
+	if err := group.Start(context.Background()); err != nil {
+		logging.For("app").Fatalf("Failed to start background jobs: %v", err)
+	}
+	return group
+}
+
+// waitForShutdown blocks until a signal arrives (or the HTTP server fails),
+// then shuts down in dependency order: stop accepting and drain HTTP requests,
+// stop background jobs (letting in-flight batches finish and releasing the
+// leader lock), and finally close the RPC and DB connections and flush traces.
+func waitForShutdown(srv *http.Server, app *server.Server, jobs *lifecycle.Group, client *ServiceClient, serverErr chan error) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
 
-	logrus.Info("Shutting down server...")
+	select {
+	case <-quit:
+	case err := <-serverErr:
+		logging.For("app").Errorf("Server error: %v", err)
+		serverErr = nil
+	}
+
+	logging.For("app").Info("Shutting down...")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	if err := app.Shutdown(ctx, srv); err != nil {
-		logrus.Errorf("Shutdown error: %v", err)
+	if srv != nil {
+		if err := app.Shutdown(ctx, srv); err != nil {
+			logging.For("app").Errorf("Shutdown error: %v", err)
+		}
+		if serverErr != nil {
+			if err := <-serverErr; err != nil && err != http.ErrServerClosed {
+				logging.For("app").Errorf("Server error: %v", err)
+			}
+		}
 	}
 
-	select {
-	case err := <-serverErr:
-		if err != nil && err != http.ErrServerClosed {
-			logrus.Errorf("Server error: %v", err)
-		}
-	case <-ctx.Done():
-		logrus.Warn("Shutdown timeout exceeded")
+	if err := jobs.Stop(ctx); err != nil {
+		logging.For("app").Errorf("Failed to stop background jobs: %v", err)
 	}
 
-	logrus.Info("Server stopped cleanly.")
+	client.Close(ctx)
+
+	if ctx.Err() != nil {
+		logging.For("app").Warn("Shutdown timeout exceeded")
+		return
+	}
+	logging.For("app").Info("Stopped cleanly.")
 }
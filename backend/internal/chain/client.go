@@ -0,0 +1,186 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Client is the subset of the Ethereum JSON-RPC API used by the backend.
+// Every call goes through an instrumented wrapper, so new RPC usages must be
+// added here rather than calling ethclient directly.
+type Client interface {
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	// TraceBlock returns the call tree of every transaction in the block
+	// (debug_traceBlockByNumber with callTracer). Not all nodes support it.
+	TraceBlock(ctx context.Context, number uint64) ([]TxTrace, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	NetworkID(ctx context.Context) (*big.Int, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	Close()
+}
+
+type instrumentedClient struct {
+	inner *ethclient.Client
+}
+
+// NewClient wraps an ethclient so that every RPC call records latency and
+// error metrics labelled by method, and runs in its own trace span.
+func NewClient(inner *ethclient.Client) Client {
+	return &instrumentedClient{inner: inner}
+}
+
+// call starts instrumentation of one RPC call; the returned function must be
+// called with the call's error once it completes.
+func call(ctx context.Context, method string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := telemetry.Start(ctx, "rpc "+method,
+		attribute.String("rpc.system", "jsonrpc"),
+		attribute.String("rpc.method", method),
+	)
+	return ctx, func(err error) {
+		metrics.RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+		if err != nil {
+			metrics.RPCErrors.WithLabelValues(method).Inc()
+		}
+		telemetry.End(span, err)
+	}
+}
+
+func (c *instrumentedClient) BlockNumber(ctx context.Context) (uint64, error) {
+	ctx, done := call(ctx, "eth_blockNumber")
+	n, err := c.inner.BlockNumber(ctx)
+	done(err)
+	return n, err
+}
+
+func (c *instrumentedClient) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	ctx, done := call(ctx, "eth_getBlockByNumber")
+	block, err := c.inner.BlockByNumber(ctx, number)
+	done(err)
+	return block, err
+}
+
+func (c *instrumentedClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	ctx, done := call(ctx, "eth_getBlockByNumber")
+	header, err := c.inner.HeaderByNumber(ctx, number)
+	done(err)
+	return header, err
+}
+
+func (c *instrumentedClient) TraceBlock(ctx context.Context, number uint64) ([]TxTrace, error) {
+	ctx, done := call(ctx, "debug_traceBlockByNumber")
+	var traces []TxTrace
+	err := c.inner.Client().CallContext(ctx, &traces, "debug_traceBlockByNumber",
+		hexutil.EncodeUint64(number), map[string]string{"tracer": "callTracer"})
+	done(err)
+	return traces, err
+}
+
+func (c *instrumentedClient) ChainID(ctx context.Context) (*big.Int, error) {
+	ctx, done := call(ctx, "eth_chainId")
+	id, err := c.inner.ChainID(ctx)
+	done(err)
+	return id, err
+}
+
+func (c *instrumentedClient) NetworkID(ctx context.Context) (*big.Int, error) {
+	ctx, done := call(ctx, "net_version")
+	id, err := c.inner.NetworkID(ctx)
+	done(err)
+	return id, err
+}
+
+func (c *instrumentedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	ctx, done := call(ctx, "eth_getLogs")
+	logs, err := c.inner.FilterLogs(ctx, q)
+	done(err)
+	return logs, err
+}
+
+func (c *instrumentedClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	ctx, done := call(ctx, "eth_getTransactionCount")
+	nonce, err := c.inner.PendingNonceAt(ctx, account)
+	done(err)
+	return nonce, err
+}
+
+func (c *instrumentedClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	ctx, done := call(ctx, "eth_getTransactionCount")
+	nonce, err := c.inner.NonceAt(ctx, account, blockNumber)
+	done(err)
+	return nonce, err
+}
+
+func (c *instrumentedClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	ctx, done := call(ctx, "eth_getBalance")
+	balance, err := c.inner.BalanceAt(ctx, account, blockNumber)
+	done(err)
+	return balance, err
+}
+
+func (c *instrumentedClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	ctx, done := call(ctx, "eth_getCode")
+	code, err := c.inner.CodeAt(ctx, account, blockNumber)
+	done(err)
+	return code, err
+}
+
+func (c *instrumentedClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ctx, done := call(ctx, "eth_getTransactionReceipt")
+	receipt, err := c.inner.TransactionReceipt(ctx, txHash)
+	done(err)
+	return receipt, err
+}
+
+func (c *instrumentedClient) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	ctx, done := call(ctx, "eth_getTransactionByHash")
+	tx, pending, err := c.inner.TransactionByHash(ctx, txHash)
+	done(err)
+	return tx, pending, err
+}
+
+func (c *instrumentedClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	ctx, done := call(ctx, "eth_call")
+	out, err := c.inner.CallContract(ctx, msg, blockNumber)
+	done(err)
+	return out, err
+}
+
+func (c *instrumentedClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	ctx, done := call(ctx, "eth_gasPrice")
+	price, err := c.inner.SuggestGasPrice(ctx)
+	done(err)
+	return price, err
+}
+
+func (c *instrumentedClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	ctx, done := call(ctx, "eth_sendRawTransaction")
+	err := c.inner.SendTransaction(ctx, tx)
+	done(err)
+	return err
+}
+
+func (c *instrumentedClient) Close() {
+	c.inner.Close()
+}
@@ -0,0 +1,23 @@
+package chain
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TxTrace is one transaction's entry in a debug_traceBlockByNumber result.
+type TxTrace struct {
+	TxHash common.Hash `json:"txHash"`
+	Result CallFrame   `json:"result"`
+}
+
+// CallFrame is a call in callTracer output. Error is set when the call
+// reverted, in which case neither it nor its sub-calls moved any value.
+type CallFrame struct {
+	Type  string          `json:"type"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to,omitempty"`
+	Value *hexutil.Big    `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+	Calls []CallFrame     `json:"calls,omitempty"`
+}
@@ -0,0 +1,174 @@
+// Package checkout renders the hosted checkout, payment link and email
+// preferences pages, server-rendered pages that need no frontend build.
+package checkout
+
+import (
+	"embed"
+	"encoding/base64"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed templates/*.html
+var templates embed.FS
+
+var (
+	checkoutPage = template.Must(template.ParseFS(templates, "templates/layout.html", "templates/checkout.html"))
+	linkPage     = template.Must(template.ParseFS(templates, "templates/layout.html", "templates/link.html"))
+	preferences  = template.Must(template.ParseFS(templates, "templates/layout.html", "templates/preferences.html"))
+)
+
+// State is which screen the checkout shows.
+type State string
+
+const (
+	StatePending State = "pending"
+	StatePaid    State = "paid"
+	StateExpired State = "expired"
+)
+
+// Branding is the merchant's look, shared by all pages.
+type Branding struct {
+	MerchantName string
+	// Logo is a PNG or JPEG image; anything else is not shown.
+	Logo []byte
+	// Accent is a "#RRGGBB" color.
+	Accent string
+}
+
+// Page is what the checkout shows for one invoice.
+type Page struct {
+	Branding
+	InvoiceID string
+	// Reference is the invoice number, or ID without one.
+	Reference string
+	State     State
+	// Poll keeps the page checking for changes; it stops once the invoice
+	// is settled or expired in the database, not just past its expiry.
+	Poll bool
+	// Status, AmountPaidWei and PaymentURI are compared with the invoice
+	// API to tell when to reload.
+	Status        string
+	AmountPaidWei string
+	// AmountDueETH is what is still owed, FiatAmount its price, e.g.
+	// "1,234.50 EUR", or empty without a rate.
+	AmountDueETH string
+	FiatAmount   string
+	// PaidETH is set once a partial invoice received something.
+	PaidETH   string
+	TotalETH  string
+	ExpiresAt time.Time
+	// PaymentURI is the EIP-681 request, QRSVG its QR code; both are empty
+	// if the invoice cannot be paid from a wallet link.
+	PaymentURI string
+	QRSVG      []byte
+	// PayTo is the address to pay, and Notice explains how when there is
+	// no payment URI.
+	PayTo  string
+	Notice string
+	TxHash string
+	// RedirectURL is where the payer is sent after a few seconds on the
+	// success or expired screen.
+	RedirectURL string
+}
+
+// LinkPage shows the amount of a payment link or asks the payer for it, or
+// says why the link cannot be used.
+type LinkPage struct {
+	Branding
+	LinkID      string
+	Title       string
+	Description string
+	Currency    string
+	// FixedAmount is the link's amount; the payer chooses one without it.
+	FixedAmount string
+	// MinAmount and MaxAmount bound the amount, if set.
+	MinAmount string
+	MaxAmount string
+	// Amount and Error are the rejected input and why, when the form is
+	// shown again.
+	Amount string
+	Error  string
+	// Unavailable replaces the form with Error.
+	Unavailable bool
+}
+
+// PreferencesPage lets an email recipient choose which invoice emails they
+// get, without an account: its token comes from the emails' links.
+type PreferencesPage struct {
+	Branding
+	Token string
+	Email string
+	Kinds []PreferenceKind
+	// Unsubscribed stops every email, whatever Kinds says.
+	Unsubscribed bool
+	// Saved confirms the form was stored.
+	Saved bool
+}
+
+// PreferenceKind is one kind of email and whether it is sent.
+type PreferenceKind struct {
+	Kind    string
+	Label   string
+	Enabled bool
+}
+
+// view is Page in the types html/template needs to trust values that its
+// escaping would otherwise reject.
+type view struct {
+	*Page
+	Nonce      string
+	LogoURL    template.URL
+	PaymentURL template.URL
+	QR         template.HTML
+	ExpiresMs  int64
+}
+
+// Render writes the page. nonce must match the script-src of the
+// Content-Security-Policy it is served with.
+func Render(w io.Writer, p *Page, nonce string) error {
+	v := view{Page: p, Nonce: nonce, LogoURL: logoURL(p.Logo), ExpiresMs: p.ExpiresAt.UnixMilli()}
+	// Only our own encoder produces these; the scheme is not one
+	// html/template allows by default
+	if strings.HasPrefix(p.PaymentURI, "ethereum:") {
+		v.PaymentURL = template.URL(p.PaymentURI)
+	}
+	if strings.HasPrefix(string(p.QRSVG), "<svg") {
+		v.QR = template.HTML(p.QRSVG)
+	}
+	return checkoutPage.ExecuteTemplate(w, "layout", v)
+}
+
+type linkView struct {
+	*LinkPage
+	Nonce   string
+	LogoURL template.URL
+}
+
+// RenderLink writes the payment link page, with a nonce like Render's.
+func RenderLink(w io.Writer, p *LinkPage, nonce string) error {
+	return linkPage.ExecuteTemplate(w, "layout", linkView{LinkPage: p, Nonce: nonce, LogoURL: logoURL(p.Logo)})
+}
+
+type preferencesView struct {
+	*PreferencesPage
+	Nonce   string
+	LogoURL template.URL
+}
+
+// RenderPreferences writes the email preferences page, with a nonce like
+// Render's.
+func RenderPreferences(w io.Writer, p *PreferencesPage, nonce string) error {
+	return preferences.ExecuteTemplate(w, "layout", preferencesView{PreferencesPage: p, Nonce: nonce})
+}
+
+// logoURL inlines a PNG or JPEG logo, so the page loads nothing else.
+func logoURL(logo []byte) template.URL {
+	if kind := http.DetectContentType(logo); kind == "image/png" || kind == "image/jpeg" {
+		return template.URL("data:" + kind + ";base64," + base64.StdEncoding.EncodeToString(logo))
+	}
+	return ""
+}
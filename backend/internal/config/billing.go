@@ -0,0 +1,32 @@
+package config
+
+import "time"
+
+// BillingConfig drives the subscription scheduler.
+type BillingConfig struct {
+	CheckInterval time.Duration `json:"check_interval"`
+	// Retries is how many times a period whose invoice expired unpaid is
+	// invoiced again before the subscription becomes unpaid.
+	Retries    int           `json:"retries"`
+	RetryDelay time.Duration `json:"retry_delay"`
+}
+
+func loadBillingConfig(l *loader) *BillingConfig {
+	return &BillingConfig{
+		CheckInterval: l.seconds("BILLING_CHECK_SECONDS", "billing.check_seconds", 60),
+		Retries:       l.integer("BILLING_RETRIES", "billing.retries", 3),
+		RetryDelay:    l.seconds("BILLING_RETRY_SECONDS", "billing.retry_seconds", 86400),
+	}
+}
+
+func (c *BillingConfig) validate(l *loader) {
+	if c.CheckInterval <= 0 {
+		l.errorf("BILLING_CHECK_SECONDS (billing.check_seconds) must be positive")
+	}
+	if c.Retries < 0 {
+		l.errorf("BILLING_RETRIES (billing.retries) must not be negative")
+	}
+	if c.RetryDelay < 0 {
+		l.errorf("BILLING_RETRY_SECONDS (billing.retry_seconds) must not be negative")
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// RunCommand implements the "config" subcommand shared by the binaries.
+// "config check" prints the effective config with secrets redacted, or every
+// validation problem, and returns the process exit code.
+func RunCommand(stdout, stderr io.Writer, args []string, cfg *Config, loadErr error) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprintln(stderr, "usage: [-config file] config check")
+		return 2
+	}
+	if loadErr != nil {
+		fmt.Fprintln(stderr, loadErr)
+		return 1
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
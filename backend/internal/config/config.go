@@ -1,27 +1,75 @@
 package config
 
-import "os"
-
 type Config struct {
-	DB       *DBConfig
-	HTTP     *HTTPConfig
-	Ethereum *EthereumConfig
-	Payment  *PaymentConfig
+	DB       *DBConfig       `json:"db"`
+	HTTP     *HTTPConfig     `json:"http"`
+	Ethereum *EthereumConfig `json:"ethereum"`
+	Signer   *SignerConfig   `json:"signer"`
+	Payment  *PaymentConfig  `json:"payment"`
+	Worker   *WorkerConfig   `json:"worker"`
+	Funds    *FundsConfig    `json:"funds"`
+	Deposit  *DepositConfig  `json:"deposit"`
+	Refund   *RefundConfig   `json:"refund"`
+	Numbers  *NumbersConfig  `json:"numbers"`
+	Fiat     *FiatConfig     `json:"fiat"`
+	Billing  *BillingConfig  `json:"billing"`
+	Reminder *ReminderConfig `json:"reminder"`
+	Email    *EmailConfig    `json:"email"`
+	Health   *HealthConfig   `json:"health"`
+	Logging  *LoggingConfig  `json:"logging"`
+	Tracing  *TracingConfig  `json:"tracing"`
 }
 
-func NewConfig() *Config {
-	return &Config{
-		DB:       LoadDBConfig(),
-		HTTP:     LoadHTTPConfig(),
-		Ethereum: LoadEthereumConfig(),
-		Payment:  LoadPaymentConfig(),
+// Load builds the config from the optional YAML/TOML file at path, with
+// environment variables taking precedence over file values. All invalid
+// settings are reported together in a *ValidationError.
+func Load(path string) (*Config, error) {
+	l, err := newLoader(path)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// Helper to read environment variables
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+	cfg := &Config{
+		DB:       loadDBConfig(l),
+		HTTP:     loadHTTPConfig(l),
+		Ethereum: loadEthereumConfig(l),
+		Signer:   loadSignerConfig(l),
+		Payment:  loadPaymentConfig(l),
+		Worker:   loadWorkerConfig(l),
+		Funds:    loadFundsConfig(l),
+		Deposit:  loadDepositConfig(l),
+		Refund:   loadRefundConfig(l),
+		Numbers:  loadNumbersConfig(l),
+		Fiat:     loadFiatConfig(l),
+		Billing:  loadBillingConfig(l),
+		Reminder: loadReminderConfig(l),
+		Email:    loadEmailConfig(l),
+		Health:   loadHealthConfig(l),
+		Logging:  loadLoggingConfig(l),
+		Tracing:  loadTracingConfig(l),
+	}
+	l.checkUnknownKeys()
+
+	cfg.DB.validate(l)
+	cfg.HTTP.validate(l)
+	cfg.Ethereum.validate(l)
+	cfg.Signer.validate(l, cfg.Ethereum, cfg.DB.AppEnv)
+	cfg.Payment.validate(l)
+	cfg.Worker.validate(l)
+	cfg.Funds.validate(l)
+	cfg.Deposit.validate(l, cfg.Payment)
+	cfg.Refund.validate(l)
+	cfg.Numbers.validate(l)
+	cfg.Fiat.validate(l)
+	cfg.Billing.validate(l)
+	cfg.Reminder.validate(l, cfg.Email)
+	cfg.Email.validate(l)
+	cfg.Health.validate(l)
+	cfg.Logging.validate(l)
+	cfg.Tracing.validate(l)
+
+	if len(l.errs) > 0 {
+		return nil, &ValidationError{Problems: l.errs}
 	}
-	return fallback
+	return cfg, nil
 }
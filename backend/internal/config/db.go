@@ -1,52 +1,64 @@
 package config
 
-import (
-	"log"
-	"os"
-	"strconv"
-)
+import "strconv"
 
 type DBConfig struct {
-	User           string
-	Password       string
-	Driver         string
-	Name           string
-	Host           string
-	Port           string
-	SslMode        string
-	DBMaxOpenConns int
-	DBMaxIdleConns int
-	DBConnMaxLife  int
-	AppEnv         string
+	User           string `json:"user"`
+	Password       string `json:"password"`
+	Driver         string `json:"driver"`
+	Name           string `json:"name"`
+	Host           string `json:"host"`
+	Port           string `json:"port"`
+	SslMode        string `json:"ssl_mode"`
+	DBMaxOpenConns int    `json:"max_open_conns"`
+	DBMaxIdleConns int    `json:"max_idle_conns"`
+	DBConnMaxLife  int    `json:"conn_max_life_seconds"`
+	AppEnv         string `json:"app_env"`
 }
 
-func LoadDBConfig() *DBConfig {
-	maxOpenConns, err := strconv.Atoi(os.Getenv("DB_MAX_OPEN_CONNS"))
-	if err != nil {
-		log.Fatal("Invalid DB_MAX_OPEN_CONNS:", err)
+func loadDBConfig(l *loader) *DBConfig {
+	return &DBConfig{
+		User:           l.str("DB_USER", "db.user", ""),
+		Password:       l.str("DB_PASSWORD", "db.password", ""),
+		Driver:         l.str("DB_DRIVER", "db.driver", "postgres"),
+		Name:           l.str("DB_NAME", "db.name", ""),
+		Host:           l.str("DB_HOST", "db.host", "localhost"),
+		Port:           l.str("DB_PORT", "db.port", "5432"),
+		SslMode:        l.str("DB_SSL", "db.ssl_mode", "disable"),
+		DBMaxOpenConns: l.integer("DB_MAX_OPEN_CONNS", "db.max_open_conns", 25),
+		DBMaxIdleConns: l.integer("DB_MAX_IDLE_CONNS", "db.max_idle_conns", 5),
+		DBConnMaxLife:  l.integer("DB_CONN_MAX_LIFE", "db.conn_max_life_seconds", 300),
+		AppEnv:         l.str("GIN_MODE", "db.app_env", ""),
 	}
+}
 
-	maxIdleConns, err := strconv.Atoi(os.Getenv("DB_MAX_IDLE_CONNS"))
-	if err != nil {
-		log.Fatal("Invalid DB_MAX_IDLE_CONNS:", err)
-	}
+var validSSLModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
 
-	connMaxLife, err := strconv.Atoi(os.Getenv("DB_CONN_MAX_LIFE"))
-	if err != nil {
-		log.Fatal("Invalid DB_CONN_MAX_LIFE:", err)
+func (c *DBConfig) validate(l *loader) {
+	if c.User == "" {
+		l.errorf("DB_USER (db.user) is required")
 	}
-
-	return &DBConfig{
-		User:           os.Getenv("DB_USER"),
-		Password:       os.Getenv("DB_PASSWORD"),
-		Driver:         os.Getenv("DB_DRIVER"),
-		Name:           os.Getenv("DB_NAME"),
-		Host:           os.Getenv("DB_HOST"),
-		Port:           os.Getenv("DB_PORT"),
-		SslMode:        os.Getenv("DB_SSL"),
-		DBMaxOpenConns: maxOpenConns,
-		DBMaxIdleConns: maxIdleConns,
-		DBConnMaxLife:  connMaxLife,
-		AppEnv:         os.Getenv("GIN_MODE"),
+	if c.Name == "" {
+		l.errorf("DB_NAME (db.name) is required")
+	}
+	if c.Host == "" {
+		l.errorf("DB_HOST (db.host) is required")
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		l.errorf("DB_PORT (db.port): invalid port %q", c.Port)
+	}
+	if !validSSLModes[c.SslMode] {
+		l.errorf("DB_SSL (db.ssl_mode): unknown sslmode %q", c.SslMode)
+	}
+	if c.DBMaxOpenConns < 1 {
+		l.errorf("DB_MAX_OPEN_CONNS (db.max_open_conns) must be at least 1")
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		l.errorf("DB_MAX_IDLE_CONNS (db.max_idle_conns) must be between 0 and DB_MAX_OPEN_CONNS")
+	}
+	if c.DBConnMaxLife < 0 {
+		l.errorf("DB_CONN_MAX_LIFE (db.conn_max_life_seconds) must not be negative")
 	}
 }
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"time"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// DepositConfig enables per-invoice deposit addresses derived from an
+// account-level extended public key (BIP-44 m/44'/60'/0'). Invoice i is paid
+// to child 0/i; the matching private key is only needed by the sweeper and
+// is part of the signer config.
+type DepositConfig struct {
+	Enabled bool   `json:"enabled"`
+	XPub    string `json:"xpub"`
+	// SweepInterval is how often paid deposit addresses are emptied to
+	// their merchant.
+	SweepInterval time.Duration `json:"sweep_interval"`
+	// WatchWindow is how long after creation a deposit address is still
+	// watched for incoming transfers, so that late payments reach review.
+	WatchWindow time.Duration `json:"watch_window"`
+}
+
+func loadDepositConfig(l *loader) *DepositConfig {
+	return &DepositConfig{
+		Enabled:       l.boolean("DEPOSIT_ADDRESSES_ENABLED", "deposit.enabled", false),
+		XPub:          l.str("DEPOSIT_XPUB", "deposit.xpub", ""),
+		SweepInterval: l.seconds("DEPOSIT_SWEEP_SECONDS", "deposit.sweep_seconds", 300),
+		WatchWindow:   l.seconds("DEPOSIT_WATCH_SECONDS", "deposit.watch_seconds", 7*24*3600),
+	}
+}
+
+func (c *DepositConfig) validate(l *loader, payment *PaymentConfig) {
+	if payment.InvoiceMode == InvoiceModeDeposit && !c.Enabled {
+		l.errorf("INVOICE_MODE (payment.invoice_mode): %s requires DEPOSIT_ADDRESSES_ENABLED", InvoiceModeDeposit)
+	}
+	if !c.Enabled {
+		return
+	}
+	if payment.Address == "" {
+		l.errorf("PAYMENT_ADDRESS (payment.address) is required when DEPOSIT_ADDRESSES_ENABLED is set")
+	}
+	if c.XPub == "" {
+		l.errorf("DEPOSIT_XPUB (deposit.xpub) is required when DEPOSIT_ADDRESSES_ENABLED is set")
+	} else if key, err := bip32.B58Deserialize(c.XPub); err != nil {
+		l.errorf("DEPOSIT_XPUB (deposit.xpub): not a valid extended key: %v", err)
+	} else if key.IsPrivate {
+		l.errorf("DEPOSIT_XPUB (deposit.xpub): got a private key (xprv); set the xpub here and the xprv in SIGNER_DEPOSIT_XPRV")
+	}
+	if c.SweepInterval <= 0 {
+		l.errorf("DEPOSIT_SWEEP_SECONDS (deposit.sweep_seconds) must be positive")
+	}
+	if c.WatchWindow <= 0 {
+		l.errorf("DEPOSIT_WATCH_SECONDS (deposit.watch_seconds) must be positive")
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// EmailConfig drives invoice emails to customers and merchants.
+type EmailConfig struct {
+	// SMTPHost is the SMTP server; empty disables email.
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+	// SMTPTLS is "starttls", "tls" for implicit TLS, or "none" for a local
+	// stub.
+	SMTPTLS string `json:"smtp_tls"`
+	From    string `json:"from"`
+	// PublicURL is where recipients reach this API; links in emails start
+	// with it.
+	PublicURL string `json:"public_url"`
+	// Events are the invoice events emailed: "created", "paid" and
+	// "expired".
+	Events       []string      `json:"events"`
+	SendInterval time.Duration `json:"send_interval"`
+	// MaxAttempts bounds deliveries of one email; the delay between them
+	// starts at RetryDelay and doubles each time.
+	MaxAttempts int           `json:"max_attempts"`
+	RetryDelay  time.Duration `json:"retry_delay"`
+}
+
+func loadEmailConfig(l *loader) *EmailConfig {
+	return &EmailConfig{
+		SMTPHost:     l.str("SMTP_HOST", "email.smtp_host", ""),
+		SMTPPort:     l.integer("SMTP_PORT", "email.smtp_port", 587),
+		SMTPUsername: l.str("SMTP_USERNAME", "email.smtp_username", ""),
+		SMTPPassword: l.str("SMTP_PASSWORD", "email.smtp_password", ""),
+		SMTPTLS:      strings.ToLower(l.str("SMTP_TLS", "email.smtp_tls", "starttls")),
+		From:         l.str("EMAIL_FROM", "email.from", ""),
+		PublicURL:    strings.TrimRight(l.str("EMAIL_PUBLIC_URL", "email.public_url", ""), "/"),
+		Events:       l.list("EMAIL_EVENTS", "email.events", "created,paid,expired"),
+		SendInterval: l.seconds("EMAIL_SEND_SECONDS", "email.send_seconds", 15),
+		MaxAttempts:  l.integer("EMAIL_MAX_ATTEMPTS", "email.max_attempts", 6),
+		RetryDelay:   l.seconds("EMAIL_RETRY_SECONDS", "email.retry_seconds", 60),
+	}
+}
+
+// Enabled reports whether an SMTP server is configured.
+func (c *EmailConfig) Enabled() bool {
+	return c.SMTPHost != ""
+}
+
+func (c *EmailConfig) validate(l *loader) {
+	if !c.Enabled() {
+		return
+	}
+	if c.SMTPPort < 1 || c.SMTPPort > 65535 {
+		l.errorf("SMTP_PORT (email.smtp_port) must be between 1 and 65535")
+	}
+	switch c.SMTPTLS {
+	case "starttls", "tls", "none":
+	default:
+		l.errorf("SMTP_TLS (email.smtp_tls): want starttls, tls or none")
+	}
+	if _, err := mail.ParseAddress(c.From); err != nil {
+		l.errorf("EMAIL_FROM (email.from) is required with SMTP_HOST and must be an address, e.g. Billing <billing@example.com>")
+	}
+	if u, err := url.Parse(c.PublicURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		l.errorf("EMAIL_PUBLIC_URL (email.public_url) is required with SMTP_HOST and must be an http(s) URL")
+	}
+	for _, event := range c.Events {
+		switch event {
+		case "created", "paid", "expired":
+		default:
+			l.errorf("EMAIL_EVENTS (email.events): unknown event %q, want created, paid or expired", event)
+		}
+	}
+	if c.SendInterval <= 0 {
+		l.errorf("EMAIL_SEND_SECONDS (email.send_seconds) must be positive")
+	}
+	if c.MaxAttempts < 1 {
+		l.errorf("EMAIL_MAX_ATTEMPTS (email.max_attempts) must be at least 1")
+	}
+	if c.RetryDelay <= 0 {
+		l.errorf("EMAIL_RETRY_SECONDS (email.retry_seconds) must be positive")
+	}
+}
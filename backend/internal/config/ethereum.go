@@ -1,17 +1,66 @@
 package config
 
-import "os"
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
 
 type EthereumConfig struct {
-	RPCURL          string
-	ContractAddress string
-	PrivateKey      string
+	RPCURL          string `json:"rpc_url"`
+	ContractAddress string `json:"contract_address"`
+	PrivateKey      string `json:"private_key"`
+	// ChainID is optional; when unset it is read from the RPC node at startup.
+	ChainID int64 `json:"chain_id"`
 }
 
-func LoadEthereumConfig() *EthereumConfig {
+func loadEthereumConfig(l *loader) *EthereumConfig {
 	return &EthereumConfig{
-		RPCURL:          os.Getenv("ETHEREUM_RPC"),
-		ContractAddress: os.Getenv("CONTRACT_ADDRESS"),
-		PrivateKey:      os.Getenv("DEPLOYER_PRIVATE_KEY"),
+		RPCURL:          l.str("ETHEREUM_RPC", "ethereum.rpc_url", ""),
+		ContractAddress: l.str("CONTRACT_ADDRESS", "ethereum.contract_address", ""),
+		PrivateKey:      l.str("DEPLOYER_PRIVATE_KEY", "ethereum.private_key", ""),
+		ChainID:         l.int64("CHAIN_ID", "ethereum.chain_id", 0),
+	}
+}
+
+func (c *EthereumConfig) validate(l *loader) {
+	if c.RPCURL == "" {
+		l.errorf("ETHEREUM_RPC (ethereum.rpc_url) is required")
+	} else if u, err := url.Parse(c.RPCURL); err != nil || u.Host == "" ||
+		(u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ws" && u.Scheme != "wss") {
+		l.errorf("ETHEREUM_RPC (ethereum.rpc_url): want an http(s) or ws(s) URL")
+	}
+
+	if c.ContractAddress == "" {
+		l.errorf("CONTRACT_ADDRESS (ethereum.contract_address) is required")
+	} else if err := validateAddress(c.ContractAddress); err != "" {
+		l.errorf("CONTRACT_ADDRESS (ethereum.contract_address): %s", err)
+	}
+
+	if c.PrivateKey != "" {
+		if _, err := crypto.HexToECDSA(strings.TrimPrefix(c.PrivateKey, "0x")); err != nil {
+			// The parse error is not included as it may echo key material.
+			l.errorf("DEPLOYER_PRIVATE_KEY (ethereum.private_key): not a 32-byte hex secp256k1 key")
+		}
+	}
+
+	if c.ChainID < 0 {
+		l.errorf("CHAIN_ID (ethereum.chain_id) must not be negative")
+	}
+}
+
+// validateAddress checks that s is a 20-byte hex address and, if it uses
+// mixed case, that it carries a valid EIP-55 checksum.
+func validateAddress(s string) string {
+	if !common.IsHexAddress(s) {
+		return "not a 20-byte hex address"
+	}
+	body := strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if body != strings.ToLower(body) && body != strings.ToUpper(body) &&
+		common.HexToAddress(s).Hex() != "0x"+body {
+		return "invalid EIP-55 checksum"
 	}
+	return ""
 }
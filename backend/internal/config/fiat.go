@@ -0,0 +1,64 @@
+package config
+
+import (
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// FiatConfig prices ETH amounts in fiat currencies on invoice documents.
+type FiatConfig struct {
+	// Currency is the ISO 4217 code used when neither the customer nor the
+	// merchant prefers one.
+	Currency string `json:"currency"`
+	// StaticRates are fixed prices of one ETH by currency, used when no
+	// price feed is configured or it cannot be reached.
+	StaticRates map[string]*big.Rat `json:"static_rates"`
+	// PriceURL is a CoinGecko-compatible /simple/price endpoint; empty
+	// disables live prices.
+	PriceURL string        `json:"price_url"`
+	CoinID   string        `json:"coin_id"`
+	CacheTTL time.Duration `json:"cache_ttl"`
+}
+
+func loadFiatConfig(l *loader) *FiatConfig {
+	c := &FiatConfig{
+		Currency:    strings.ToUpper(l.str("FIAT_CURRENCY", "fiat.currency", "USD")),
+		StaticRates: map[string]*big.Rat{},
+		PriceURL:    l.str("FIAT_PRICE_URL", "fiat.price_url", ""),
+		CoinID:      l.str("FIAT_COIN_ID", "fiat.coin_id", "ethereum"),
+		CacheTTL:    l.seconds("FIAT_CACHE_SECONDS", "fiat.cache_seconds", 300),
+	}
+	for _, item := range l.list("FIAT_RATES", "fiat.rates", "") {
+		code, rate, _ := strings.Cut(item, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+		if !currencyPattern.MatchString(code) || !ok || r.Sign() <= 0 {
+			l.invalid("FIAT_RATES", "fiat.rates", item, "CODE=price entries, e.g. USD=3500")
+			continue
+		}
+		c.StaticRates[code] = r
+	}
+	return c
+}
+
+func (c *FiatConfig) validate(l *loader) {
+	if !currencyPattern.MatchString(c.Currency) {
+		l.errorf("FIAT_CURRENCY (fiat.currency) must be a 3-letter ISO 4217 code")
+	}
+	if c.PriceURL != "" {
+		if u, err := url.Parse(c.PriceURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			l.errorf("FIAT_PRICE_URL (fiat.price_url): want an http(s) URL")
+		}
+		if c.CoinID == "" {
+			l.errorf("FIAT_COIN_ID (fiat.coin_id) is required with FIAT_PRICE_URL")
+		}
+	}
+	if c.CacheTTL <= 0 {
+		l.errorf("FIAT_CACHE_SECONDS (fiat.cache_seconds) must be positive")
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"math/big"
+	"time"
+)
+
+type FundsConfig struct {
+	// WarningWei and CriticalWei are signer balance thresholds. Below the
+	// critical one invoice creation is rejected; zero disables it.
+	WarningWei    *big.Int      `json:"warning_wei"`
+	CriticalWei   *big.Int      `json:"critical_wei"`
+	CheckInterval time.Duration `json:"check_interval"`
+	// GasLookbackBlocks is how far back recent createInvoice transactions
+	// are sampled to estimate gas per invoice.
+	GasLookbackBlocks uint64 `json:"gas_lookback_blocks"`
+	// DefaultInvoiceGas is used until any createInvoice transaction is seen.
+	DefaultInvoiceGas uint64 `json:"default_invoice_gas"`
+}
+
+func loadFundsConfig(l *loader) *FundsConfig {
+	return &FundsConfig{
+		WarningWei:        l.bigInt("FUNDS_WARNING_WEI", "funds.warning_wei", "50000000000000000"),
+		CriticalWei:       l.bigInt("FUNDS_CRITICAL_WEI", "funds.critical_wei", "10000000000000000"),
+		CheckInterval:     l.seconds("FUNDS_CHECK_SECONDS", "funds.check_seconds", 30),
+		GasLookbackBlocks: l.uint64("FUNDS_GAS_LOOKBACK_BLOCKS", "funds.gas_lookback_blocks", 5000),
+		DefaultInvoiceGas: l.uint64("FUNDS_DEFAULT_INVOICE_GAS", "funds.default_invoice_gas", 300000),
+	}
+}
+
+func (c *FundsConfig) validate(l *loader) {
+	if c.CriticalWei.Cmp(c.WarningWei) > 0 {
+		l.errorf("FUNDS_CRITICAL_WEI (funds.critical_wei) must not exceed FUNDS_WARNING_WEI")
+	}
+	if c.CheckInterval <= 0 {
+		l.errorf("FUNDS_CHECK_SECONDS (funds.check_seconds) must be positive")
+	}
+	if c.DefaultInvoiceGas == 0 {
+		l.errorf("FUNDS_DEFAULT_INVOICE_GAS (funds.default_invoice_gas) must be positive")
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "math/big"
+
+type HealthConfig struct {
+	// MaxWatcherLag is the largest head lag (in blocks) still reported ready.
+	MaxWatcherLag uint64 `json:"max_watcher_lag"`
+	// MinSignerBalanceWei disables the balance check when zero.
+	MinSignerBalanceWei *big.Int `json:"min_signer_balance_wei"`
+	// DebugToken protects /debug/status; the endpoint is disabled when empty.
+	DebugToken string `json:"debug_token"`
+}
+
+func loadHealthConfig(l *loader) *HealthConfig {
+	return &HealthConfig{
+		MaxWatcherLag:       l.uint64("READY_MAX_WATCHER_LAG", "health.max_watcher_lag", 100),
+		MinSignerBalanceWei: l.bigInt("READY_MIN_SIGNER_BALANCE_WEI", "health.min_signer_balance_wei", "0"),
+		DebugToken:          l.str("DEBUG_TOKEN", "health.debug_token", ""),
+	}
+}
+
+func (c *HealthConfig) validate(l *loader) {
+	if c.DebugToken != "" && len(c.DebugToken) < 16 {
+		l.errorf("DEBUG_TOKEN (health.debug_token) must be at least 16 characters")
+	}
+}
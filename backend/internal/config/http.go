@@ -1,11 +1,51 @@
 package config
 
+import (
+	"net"
+	"strconv"
+)
+
 type HTTPConfig struct {
-	Port string
+	Port string `json:"port"`
+	// AllowedOrigins lists CORS origins; a single "*" echoes any origin.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AdminToken protects the /api/admin endpoints; they are disabled when
+	// empty.
+	AdminToken string `json:"admin_token"`
+	// LinkOpensPerMinute limits the invoices each client IP can create from
+	// payment links.
+	LinkOpensPerMinute int `json:"link_opens_per_minute"`
+	// TrustedProxies lists the IPs and CIDRs of reverse proxies whose
+	// X-Forwarded-For is believed; with none, the client IP is the peer's.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
-func LoadHTTPConfig() *HTTPConfig {
+func loadHTTPConfig(l *loader) *HTTPConfig {
 	return &HTTPConfig{
-		Port: getEnv("PORT", "8080"),
+		Port:           l.str("PORT", "http.port", "8080"),
+		AllowedOrigins: l.list("ALLOWED_ORIGINS", "http.allowed_origins", ""),
+		AdminToken:     l.str("ADMIN_TOKEN", "http.admin_token", ""),
+
+		LinkOpensPerMinute: l.integer("PAYMENT_LINK_OPENS_PER_MINUTE", "http.link_opens_per_minute", 10),
+		TrustedProxies:     l.list("TRUSTED_PROXIES", "http.trusted_proxies", ""),
+	}
+}
+
+func (c *HTTPConfig) validate(l *loader) {
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		l.errorf("PORT (http.port): invalid port %q", c.Port)
+	}
+	if c.AdminToken != "" && len(c.AdminToken) < 16 {
+		l.errorf("ADMIN_TOKEN (http.admin_token) must be at least 16 characters")
+	}
+	if c.LinkOpensPerMinute <= 0 {
+		l.errorf("PAYMENT_LINK_OPENS_PER_MINUTE (http.link_opens_per_minute) must be positive")
+	}
+	for _, proxy := range c.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				l.errorf("TRUSTED_PROXIES (http.trusted_proxies): %q is not an IP or CIDR", proxy)
+			}
+		}
 	}
 }
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// loader resolves settings from, in order of precedence, environment
+// variables, the optional config file and built-in defaults. Every setting
+// has an env name (DB_HOST) and a file key (db.host). Parse and validation
+// problems are collected so they can all be reported at once.
+type loader struct {
+	file map[string]string
+	used map[string]bool
+	errs []string
+}
+
+func newLoader(path string) (*loader, error) {
+	l := &loader{file: map[string]string{}, used: map[string]bool{}}
+	if path == "" {
+		return l, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	raw := map[string]interface{}{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q (want .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+
+	flatten("", raw, l.file)
+	return l, nil
+}
+
+// flatten turns nested sections into dotted keys; lists become
+// comma-separated values, matching the env var representation.
+func flatten(prefix string, in map[string]interface{}, out map[string]string) {
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flatten(key, val, out)
+		case []interface{}:
+			parts := make([]string, len(val))
+			for i, item := range val {
+				parts[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(parts, ",")
+		case nil:
+			out[key] = ""
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+}
+
+func (l *loader) lookup(env, key string) (string, bool) {
+	l.used[key] = true
+	if v, ok := os.LookupEnv(env); ok {
+		return v, true
+	}
+	v, ok := l.file[key]
+	return v, ok
+}
+
+func (l *loader) errorf(format string, args ...interface{}) {
+	l.errs = append(l.errs, fmt.Sprintf(format, args...))
+}
+
+func (l *loader) invalid(env, key, raw, want string) {
+	l.errorf("%s (%s): invalid value %q, want %s", env, key, raw, want)
+}
+
+func (l *loader) str(env, key, fallback string) string {
+	if v, ok := l.lookup(env, key); ok {
+		return v
+	}
+	return fallback
+}
+
+func (l *loader) integer(env, key string, fallback int) int {
+	v, ok := l.lookup(env, key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		l.invalid(env, key, v, "an integer")
+		return fallback
+	}
+	return n
+}
+
+func (l *loader) int64(env, key string, fallback int64) int64 {
+	v, ok := l.lookup(env, key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		l.invalid(env, key, v, "an integer")
+		return fallback
+	}
+	return n
+}
+
+func (l *loader) uint64(env, key string, fallback uint64) uint64 {
+	v, ok := l.lookup(env, key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		l.invalid(env, key, v, "a non-negative integer")
+		return fallback
+	}
+	return n
+}
+
+func (l *loader) float(env, key string, fallback float64) float64 {
+	v, ok := l.lookup(env, key)
+	if !ok {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil {
+		l.invalid(env, key, v, "a number")
+		return fallback
+	}
+	return f
+}
+
+func (l *loader) boolean(env, key string, fallback bool) bool {
+	v, ok := l.lookup(env, key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		l.invalid(env, key, v, "true or false")
+		return fallback
+	}
+	return b
+}
+
+// seconds reads a whole number of seconds (the unit used by the existing
+// *_SECONDS and DB_CONN_MAX_LIFE variables).
+func (l *loader) seconds(env, key string, fallback int) time.Duration {
+	return time.Duration(l.integer(env, key, fallback)) * time.Second
+}
+
+func (l *loader) bigInt(env, key, fallback string) *big.Int {
+	v := l.str(env, key, fallback)
+	n, ok := new(big.Int).SetString(strings.TrimSpace(v), 10)
+	if !ok || n.Sign() < 0 {
+		l.invalid(env, key, v, "a non-negative integer")
+		n, _ = new(big.Int).SetString(fallback, 10)
+	}
+	return n
+}
+
+func (l *loader) list(env, key, fallback string) []string {
+	var out []string
+	for _, item := range strings.Split(l.str(env, key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// checkUnknownKeys reports file keys no setting consumed, which are almost
+// always typos.
+func (l *loader) checkUnknownKeys() {
+	var unknown []string
+	for key := range l.file {
+		if !l.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		l.errorf("config file: unknown key %q", key)
+	}
+}
+
+// ValidationError lists every problem found while loading the config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
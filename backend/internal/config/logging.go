@@ -0,0 +1,51 @@
+package config
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type LoggingConfig struct {
+	// Format is "json" or "logfmt".
+	Format string `json:"format"`
+	Level  string `json:"level"`
+	// ComponentLevels overrides Level per component, e.g. watcher=debug.
+	ComponentLevels map[string]string `json:"component_levels"`
+	// SlowQueryThreshold makes GORM log slower queries at warn level.
+	SlowQueryThreshold time.Duration `json:"slow_query_threshold"`
+}
+
+func loadLoggingConfig(l *loader) *LoggingConfig {
+	componentLevels := map[string]string{}
+	for _, pair := range l.list("LOG_LEVELS", "logging.levels", "") {
+		name, level, ok := strings.Cut(pair, "=")
+		if !ok {
+			l.errorf("LOG_LEVELS (logging.levels): invalid entry %q, want component=level", pair)
+			continue
+		}
+		componentLevels[strings.TrimSpace(name)] = strings.TrimSpace(level)
+	}
+
+	return &LoggingConfig{
+		Format:             l.str("LOG_FORMAT", "logging.format", "logfmt"),
+		Level:              l.str("LOG_LEVEL", "logging.level", "info"),
+		ComponentLevels:    componentLevels,
+		SlowQueryThreshold: time.Duration(l.integer("LOG_SLOW_QUERY_MS", "logging.slow_query_ms", 200)) * time.Millisecond,
+	}
+}
+
+func (c *LoggingConfig) validate(l *loader) {
+	if c.Format != "json" && c.Format != "logfmt" {
+		l.errorf("LOG_FORMAT (logging.format): must be json or logfmt")
+	}
+	if _, err := logrus.ParseLevel(c.Level); err != nil {
+		l.errorf("LOG_LEVEL (logging.level): unknown level %q", c.Level)
+	}
+	for component, level := range c.ComponentLevels {
+		if _, err := logrus.ParseLevel(level); err != nil {
+			l.errorf("LOG_LEVELS (logging.levels): unknown level %q for %s", level, component)
+		}
+	}
+}
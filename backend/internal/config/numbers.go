@@ -0,0 +1,34 @@
+package config
+
+import "regexp"
+
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{0,20}$`)
+
+// NumbersConfig is the invoice number format of merchants that have no
+// sequence of their own yet. Each merchant's sequence can be changed through
+// the admin API afterwards.
+type NumbersConfig struct {
+	Prefix string `json:"prefix"`
+	// Padding is the minimum number of digits, zero-padded.
+	Padding int `json:"padding"`
+	// YearlyReset restarts numbering at 1 each calendar year (UTC) and puts
+	// the year in the number.
+	YearlyReset bool `json:"yearly_reset"`
+}
+
+func loadNumbersConfig(l *loader) *NumbersConfig {
+	return &NumbersConfig{
+		Prefix:      l.str("INVOICE_NUMBER_PREFIX", "numbers.prefix", "INV"),
+		Padding:     l.integer("INVOICE_NUMBER_PADDING", "numbers.padding", 6),
+		YearlyReset: l.boolean("INVOICE_NUMBER_YEARLY_RESET", "numbers.yearly_reset", true),
+	}
+}
+
+func (c *NumbersConfig) validate(l *loader) {
+	if c.Padding < 1 || c.Padding > 12 {
+		l.errorf("INVOICE_NUMBER_PADDING (numbers.padding) must be between 1 and 12")
+	}
+	if !prefixPattern.MatchString(c.Prefix) {
+		l.errorf("INVOICE_NUMBER_PREFIX (numbers.prefix) must be at most 20 letters, digits, '-' or '_'")
+	}
+}
@@ -1,15 +1,108 @@
 package config
 
-import "os"
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// InvoiceModeOnchain creates each invoice with an owner createInvoice
+	// transaction.
+	InvoiceModeOnchain = "onchain"
+	// InvoiceModeSigned signs invoices off-chain as EIP-712 typed data; the
+	// payer submits the signature with paySignedInvoice.
+	InvoiceModeSigned = "signed"
+	// InvoiceModeTransfer gives each invoice a unique amount to be sent as a
+	// plain transfer to PAYMENT_ADDRESS.
+	InvoiceModeTransfer = "transfer"
+	// InvoiceModeDeposit gives each invoice its own HD-derived deposit
+	// address (see DepositConfig).
+	InvoiceModeDeposit = "deposit"
+)
 
 type PaymentConfig struct {
-	Address           string
-	InvoiceExpiryMins int
+	Address           string `json:"address"`
+	InvoiceExpiryMins int    `json:"invoice_expiry_mins"`
+	// InvoiceMode is the default for requests that do not choose one.
+	InvoiceMode string `json:"invoice_mode"`
+
+	// TransferModeEnabled allows transfer invoices and makes the watcher
+	// scan blocks for transfers to Address.
+	TransferModeEnabled bool `json:"transfer_mode_enabled"`
+	// TransferAmountStepWei is the epsilon added to a transfer invoice's
+	// amount, up to TransferMaxOffsets times, to make it unique among
+	// pending invoices.
+	TransferAmountStepWei *big.Int `json:"transfer_amount_step_wei"`
+	TransferMaxOffsets    int      `json:"transfer_max_offsets"`
+	// TransferTraces also matches internal transfers (e.g. from contract
+	// wallets) using debug_traceBlockByNumber, if the node supports it.
+	TransferTraces bool `json:"transfer_traces"`
+
+	// UnderpaymentToleranceWei and UnderpaymentToleranceBps let partial and
+	// deposit invoices count as paid when short by at most the larger of
+	// the two, e.g. to absorb fees a payer's wallet or exchange deducted.
+	UnderpaymentToleranceWei *big.Int `json:"underpayment_tolerance_wei"`
+	UnderpaymentToleranceBps int64    `json:"underpayment_tolerance_bps"`
 }
 
-func LoadPaymentConfig() *PaymentConfig {
+func loadPaymentConfig(l *loader) *PaymentConfig {
 	return &PaymentConfig{
-		Address:           os.Getenv("PAYMENT_ADDRESS"),
-		InvoiceExpiryMins: 5,
+		Address:                  l.str("PAYMENT_ADDRESS", "payment.address", ""),
+		InvoiceExpiryMins:        l.integer("INVOICE_EXPIRY_MINS", "payment.invoice_expiry_mins", 5),
+		InvoiceMode:              l.str("INVOICE_MODE", "payment.invoice_mode", InvoiceModeOnchain),
+		TransferModeEnabled:      l.boolean("TRANSFER_MODE_ENABLED", "payment.transfer_mode_enabled", false),
+		TransferAmountStepWei:    l.bigInt("TRANSFER_AMOUNT_STEP_WEI", "payment.transfer_amount_step_wei", "1000000000000"),
+		TransferMaxOffsets:       l.integer("TRANSFER_MAX_OFFSETS", "payment.transfer_max_offsets", 1000),
+		TransferTraces:           l.boolean("TRANSFER_TRACES", "payment.transfer_traces", true),
+		UnderpaymentToleranceWei: l.bigInt("UNDERPAYMENT_TOLERANCE_WEI", "payment.underpayment_tolerance_wei", "0"),
+		UnderpaymentToleranceBps: l.int64("UNDERPAYMENT_TOLERANCE_BPS", "payment.underpayment_tolerance_bps", 0),
+	}
+}
+
+// UnderpaymentTolerance returns how far short of amountWei a payment total
+// may be and still settle the invoice.
+func (c *PaymentConfig) UnderpaymentTolerance(amountWei *big.Int) *big.Int {
+	tolerance := new(big.Int).Mul(amountWei, big.NewInt(c.UnderpaymentToleranceBps))
+	tolerance.Quo(tolerance, big.NewInt(10000))
+	if c.UnderpaymentToleranceWei.Cmp(tolerance) > 0 {
+		tolerance.Set(c.UnderpaymentToleranceWei)
+	}
+	return tolerance
+}
+
+func (c *PaymentConfig) validate(l *loader) {
+	if c.Address != "" {
+		if err := validateAddress(c.Address); err != "" {
+			l.errorf("PAYMENT_ADDRESS (payment.address): %s", err)
+		} else if common.HexToAddress(c.Address) == (common.Address{}) {
+			l.errorf("PAYMENT_ADDRESS (payment.address): must not be the zero address")
+		}
+	}
+	if c.InvoiceExpiryMins < 1 {
+		l.errorf("INVOICE_EXPIRY_MINS (payment.invoice_expiry_mins) must be at least 1")
+	}
+	switch c.InvoiceMode {
+	case InvoiceModeOnchain, InvoiceModeSigned, InvoiceModeDeposit:
+	case InvoiceModeTransfer:
+		if !c.TransferModeEnabled {
+			l.errorf("INVOICE_MODE (payment.invoice_mode): %s requires TRANSFER_MODE_ENABLED", InvoiceModeTransfer)
+		}
+	default:
+		l.errorf("INVOICE_MODE (payment.invoice_mode): must be %s, %s, %s or %s", InvoiceModeOnchain, InvoiceModeSigned, InvoiceModeTransfer, InvoiceModeDeposit)
+	}
+	if c.UnderpaymentToleranceBps < 0 || c.UnderpaymentToleranceBps >= 10000 {
+		l.errorf("UNDERPAYMENT_TOLERANCE_BPS (payment.underpayment_tolerance_bps) must be between 0 and 9999")
+	}
+	if c.TransferModeEnabled {
+		if c.Address == "" {
+			l.errorf("PAYMENT_ADDRESS (payment.address) is required when TRANSFER_MODE_ENABLED is set")
+		}
+		if c.TransferAmountStepWei.Sign() == 0 {
+			l.errorf("TRANSFER_AMOUNT_STEP_WEI (payment.transfer_amount_step_wei) must be positive")
+		}
+		if c.TransferMaxOffsets < 1 {
+			l.errorf("TRANSFER_MAX_OFFSETS (payment.transfer_max_offsets) must be at least 1")
+		}
 	}
 }
@@ -0,0 +1,69 @@
+package config
+
+import "net/url"
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the config that is safe to print or serve:
+// passwords, keys and tokens are masked, and the RPC URL is reduced to its
+// scheme and host because providers embed API keys in the path.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	db := *c.DB
+	db.Password = redactSecret(db.Password)
+	out.DB = &db
+
+	httpCfg := *c.HTTP
+	httpCfg.AdminToken = redactSecret(httpCfg.AdminToken)
+	out.HTTP = &httpCfg
+
+	eth := *c.Ethereum
+	eth.PrivateKey = redactSecret(eth.PrivateKey)
+	eth.RPCURL = redactURL(eth.RPCURL)
+	out.Ethereum = &eth
+
+	signer := *c.Signer
+	signer.KeystorePassphrase = redactSecret(signer.KeystorePassphrase)
+	signer.DepositXPrv = redactSecret(signer.DepositXPrv)
+	signer.RemoteURL = redactURL(signer.RemoteURL)
+	out.Signer = &signer
+
+	health := *c.Health
+	health.DebugToken = redactSecret(health.DebugToken)
+	out.Health = &health
+
+	// Price APIs take their key as a query parameter
+	fiat := *c.Fiat
+	fiat.PriceURL = redactURL(fiat.PriceURL)
+	out.Fiat = &fiat
+
+	reminder := *c.Reminder
+	reminder.WebhookURL = redactURL(reminder.WebhookURL)
+	reminder.WebhookSecret = redactSecret(reminder.WebhookSecret)
+	out.Reminder = &reminder
+
+	email := *c.Email
+	email.SMTPPassword = redactSecret(email.SMTPPassword)
+	out.Email = &email
+
+	return &out
+}
+
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return redactSecret(raw)
+	}
+	if u.Path == "" && u.RawQuery == "" && u.User == nil {
+		return raw
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+type RefundConfig struct {
+	// Confirmations is how many blocks a refund transaction needs on top of
+	// it before the refund counts as confirmed.
+	Confirmations uint64        `json:"confirmations"`
+	CheckInterval time.Duration `json:"check_interval"`
+}
+
+func loadRefundConfig(l *loader) *RefundConfig {
+	return &RefundConfig{
+		Confirmations: l.uint64("REFUND_CONFIRMATIONS", "refund.confirmations", 3),
+		CheckInterval: l.seconds("REFUND_CHECK_SECONDS", "refund.check_seconds", 15),
+	}
+}
+
+func (c *RefundConfig) validate(l *loader) {
+	if c.CheckInterval <= 0 {
+		l.errorf("REFUND_CHECK_SECONDS (refund.check_seconds) must be positive")
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"net/url"
+	"sort"
+	"time"
+)
+
+// ReminderConfig drives payment reminders before invoices expire, and the
+// optional re-issue of expired ones.
+type ReminderConfig struct {
+	// Offsets are how long before ExpiresAt a reminder is sent, longest
+	// first; empty disables reminders.
+	Offsets       []time.Duration `json:"offsets"`
+	CheckInterval time.Duration   `json:"check_interval"`
+	// Channels name the notification channels reminders go to: "log",
+	// "webhook" and "email".
+	Channels []string `json:"channels"`
+	// WebhookURL receives each notification as a JSON POST, signed with
+	// WebhookSecret when set.
+	WebhookURL    string `json:"webhook_url"`
+	WebhookSecret string `json:"webhook_secret"`
+	// Reissue replaces customers' invoices that expired unpaid with a new
+	// one, up to ReissueMax times in a row.
+	Reissue    bool `json:"reissue"`
+	ReissueMax int  `json:"reissue_max"`
+}
+
+func loadReminderConfig(l *loader) *ReminderConfig {
+	c := &ReminderConfig{
+		CheckInterval: l.seconds("REMINDER_CHECK_SECONDS", "reminder.check_seconds", 60),
+		Channels:      l.list("REMINDER_CHANNELS", "reminder.channels", "log"),
+		WebhookURL:    l.str("REMINDER_WEBHOOK_URL", "reminder.webhook_url", ""),
+		WebhookSecret: l.str("REMINDER_WEBHOOK_SECRET", "reminder.webhook_secret", ""),
+		Reissue:       l.boolean("REMINDER_REISSUE", "reminder.reissue", false),
+		ReissueMax:    l.integer("REMINDER_REISSUE_MAX", "reminder.reissue_max", 1),
+	}
+	for _, item := range l.list("REMINDER_OFFSETS", "reminder.offsets", "24h,1h") {
+		offset, err := time.ParseDuration(item)
+		if err != nil || offset <= 0 {
+			l.invalid("REMINDER_OFFSETS", "reminder.offsets", item, "positive durations, e.g. 24h,1h")
+			continue
+		}
+		c.Offsets = append(c.Offsets, offset)
+	}
+	sort.Slice(c.Offsets, func(i, j int) bool { return c.Offsets[i] > c.Offsets[j] })
+	return c
+}
+
+func (c *ReminderConfig) validate(l *loader, email *EmailConfig) {
+	if c.CheckInterval <= 0 {
+		l.errorf("REMINDER_CHECK_SECONDS (reminder.check_seconds) must be positive")
+	}
+	for i := 1; i < len(c.Offsets); i++ {
+		if c.Offsets[i] == c.Offsets[i-1] {
+			l.errorf("REMINDER_OFFSETS (reminder.offsets) lists %s twice", c.Offsets[i])
+		}
+	}
+	for _, channel := range c.Channels {
+		switch channel {
+		case "log":
+		case "webhook":
+			if c.WebhookURL == "" {
+				l.errorf("REMINDER_WEBHOOK_URL (reminder.webhook_url) is required for the webhook channel")
+			}
+		case "email":
+			if !email.Enabled() {
+				l.errorf("SMTP_HOST (email.smtp_host) is required for the email channel")
+			}
+		default:
+			l.errorf("REMINDER_CHANNELS (reminder.channels): unknown channel %q, want log, webhook or email", channel)
+		}
+	}
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			l.errorf("REMINDER_WEBHOOK_URL (reminder.webhook_url): want an http(s) URL")
+		}
+	}
+	if c.ReissueMax < 0 {
+		l.errorf("REMINDER_REISSUE_MAX (reminder.reissue_max) must not be negative")
+	}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"net/url"
+	"os"
+)
+
+const (
+	SignerBackendKey      = "key"
+	SignerBackendKeystore = "keystore"
+	SignerBackendRemote   = "remote"
+)
+
+// SignerConfig selects how transactions are signed. The raw key backend
+// reads DEPLOYER_PRIVATE_KEY and is meant for local development only.
+type SignerConfig struct {
+	Backend string `json:"backend"`
+
+	KeystorePath string `json:"keystore_path"`
+	// KeystorePassphrase may be given directly or read from
+	// KeystorePassphraseFile (e.g. a mounted secret).
+	KeystorePassphrase     string `json:"keystore_passphrase"`
+	KeystorePassphraseFile string `json:"keystore_passphrase_file"`
+
+	RemoteURL string `json:"remote_url"`
+	// RemoteMethod is eth_signTransaction for geth-style nodes; Clef serves
+	// the same call as account_signTransaction.
+	RemoteMethod string `json:"remote_method"`
+	// RemoteTypedDataMethod signs EIP-712 invoices: eth_signTypedData_v4,
+	// or account_signTypedData for Clef.
+	RemoteTypedDataMethod string `json:"remote_typed_data_method"`
+	// Address is the account the remote signer signs for. For the keystore
+	// backend it is optional and checked against the decrypted key.
+	Address string `json:"address"`
+
+	// DepositXPrv is the account-level extended private key matching
+	// DEPOSIT_XPUB, used only to sweep deposit addresses. It may be read
+	// from DepositXPrvFile instead. Processes that do not sweep (API-only
+	// replicas) can leave it unset.
+	DepositXPrv     string `json:"deposit_xprv"`
+	DepositXPrvFile string `json:"deposit_xprv_file"`
+}
+
+func loadSignerConfig(l *loader) *SignerConfig {
+	return &SignerConfig{
+		Backend:                l.str("SIGNER_BACKEND", "signer.backend", SignerBackendKey),
+		KeystorePath:           l.str("SIGNER_KEYSTORE_PATH", "signer.keystore_path", ""),
+		KeystorePassphrase:     l.str("SIGNER_KEYSTORE_PASSPHRASE", "signer.keystore_passphrase", ""),
+		KeystorePassphraseFile: l.str("SIGNER_KEYSTORE_PASSPHRASE_FILE", "signer.keystore_passphrase_file", ""),
+		RemoteURL:              l.str("SIGNER_REMOTE_URL", "signer.remote_url", ""),
+		RemoteMethod:           l.str("SIGNER_REMOTE_METHOD", "signer.remote_method", "eth_signTransaction"),
+		RemoteTypedDataMethod:  l.str("SIGNER_REMOTE_TYPED_DATA_METHOD", "signer.remote_typed_data_method", "eth_signTypedData_v4"),
+		Address:                l.str("SIGNER_ADDRESS", "signer.address", ""),
+		DepositXPrv:            l.str("SIGNER_DEPOSIT_XPRV", "signer.deposit_xprv", ""),
+		DepositXPrvFile:        l.str("SIGNER_DEPOSIT_XPRV_FILE", "signer.deposit_xprv_file", ""),
+	}
+}
+
+func (c *SignerConfig) validate(l *loader, eth *EthereumConfig, appEnv string) {
+	if c.Address != "" {
+		if err := validateAddress(c.Address); err != "" {
+			l.errorf("SIGNER_ADDRESS (signer.address): %s", err)
+		}
+	}
+
+	if c.DepositXPrv != "" && c.DepositXPrvFile != "" {
+		l.errorf("SIGNER_DEPOSIT_XPRV and SIGNER_DEPOSIT_XPRV_FILE are mutually exclusive")
+	}
+
+	switch c.Backend {
+	case SignerBackendKey:
+		if eth.PrivateKey == "" {
+			l.errorf("DEPLOYER_PRIVATE_KEY (ethereum.private_key) is required by the %q signer backend", SignerBackendKey)
+		}
+		if appEnv == "release" {
+			l.errorf("SIGNER_BACKEND (signer.backend): the raw private key signer is for development only; use %q or %q in release mode", SignerBackendKeystore, SignerBackendRemote)
+		}
+	case SignerBackendKeystore:
+		if c.KeystorePath == "" {
+			l.errorf("SIGNER_KEYSTORE_PATH (signer.keystore_path) is required by the %q signer backend", SignerBackendKeystore)
+		} else if _, err := os.Stat(c.KeystorePath); err != nil {
+			l.errorf("SIGNER_KEYSTORE_PATH (signer.keystore_path): %v", err)
+		}
+		if c.KeystorePassphrase != "" && c.KeystorePassphraseFile != "" {
+			l.errorf("SIGNER_KEYSTORE_PASSPHRASE and SIGNER_KEYSTORE_PASSPHRASE_FILE are mutually exclusive")
+		}
+	case SignerBackendRemote:
+		if c.RemoteURL == "" {
+			l.errorf("SIGNER_REMOTE_URL (signer.remote_url) is required by the %q signer backend", SignerBackendRemote)
+		} else if u, err := url.Parse(c.RemoteURL); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ws" && u.Scheme != "wss") {
+			l.errorf("SIGNER_REMOTE_URL (signer.remote_url): want an http(s) or ws(s) URL")
+		}
+		if c.Address == "" {
+			l.errorf("SIGNER_ADDRESS (signer.address) is required by the %q signer backend", SignerBackendRemote)
+		}
+	default:
+		l.errorf("SIGNER_BACKEND (signer.backend): must be %s, %s or %s", SignerBackendKey, SignerBackendKeystore, SignerBackendRemote)
+	}
+}
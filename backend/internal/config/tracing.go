@@ -0,0 +1,26 @@
+package config
+
+type TracingConfig struct {
+	// Exporter is "none", "otlp" (configured through the standard
+	// OTEL_EXPORTER_OTLP_* variables) or "stdout".
+	Exporter    string  `json:"exporter"`
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+func loadTracingConfig(l *loader) *TracingConfig {
+	return &TracingConfig{
+		Exporter:    l.str("TRACING_EXPORTER", "tracing.exporter", "none"),
+		ServiceName: l.str("OTEL_SERVICE_NAME", "tracing.service_name", "crypto-invoice-backend"),
+		SampleRatio: l.float("TRACING_SAMPLE_RATIO", "tracing.sample_ratio", 1),
+	}
+}
+
+func (c *TracingConfig) validate(l *loader) {
+	if c.Exporter != "none" && c.Exporter != "otlp" && c.Exporter != "stdout" {
+		l.errorf("TRACING_EXPORTER (tracing.exporter): must be none, otlp or stdout")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		l.errorf("TRACING_SAMPLE_RATIO (tracing.sample_ratio): must be between 0 and 1")
+	}
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+type WorkerConfig struct {
+	BackgroundJobs      bool          `json:"background_jobs"`
+	LeaderLockKey       int64         `json:"leader_lock_key"`
+	LeaderRetryInterval time.Duration `json:"leader_retry_interval"`
+	// MetricsPort is where cmd/worker serves /metrics; the API serves it on
+	// its own port.
+	MetricsPort string `json:"metrics_port"`
+}
+
+func loadWorkerConfig(l *loader) *WorkerConfig {
+	return &WorkerConfig{
+		BackgroundJobs:      l.boolean("BACKGROUND_JOBS", "worker.background_jobs", true),
+		LeaderLockKey:       l.int64("LEADER_LOCK_KEY", "worker.leader_lock_key", 727001),
+		LeaderRetryInterval: l.seconds("LEADER_RETRY_SECONDS", "worker.leader_retry_seconds", 5),
+		MetricsPort:         l.str("METRICS_PORT", "worker.metrics_port", "9090"),
+	}
+}
+
+func (c *WorkerConfig) validate(l *loader) {
+	if c.LeaderRetryInterval <= 0 {
+		l.errorf("LEADER_RETRY_SECONDS (worker.leader_retry_seconds) must be positive")
+	}
+	if port, err := strconv.Atoi(c.MetricsPort); err != nil || port < 1 || port > 65535 {
+		l.errorf("METRICS_PORT (worker.metrics_port): invalid port %q", c.MetricsPort)
+	}
+}
@@ -7,43 +7,106 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/sirupsen/logrus"
 	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
 	"github.com/user/crypto-invoice-generator/backend/internal/models"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"gorm.io/gorm/schema"
 )
 
-func InitDB(cfg *config.DBConfig) *gorm.DB {
+func InitDB(cfg *config.DBConfig, slowQueryThreshold time.Duration) *gorm.DB {
 	sqlDB := setupDB(cfg)
 
 	gormDB, err := gorm.Open(postgres.New(postgres.Config{
 		Conn: sqlDB,
 	}), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logging.NewGormLogger(slowQueryThreshold),
+		TranslateError: true,
 		NamingStrategy: schema.NamingStrategy{
 			SingularTable: true,
 		},
 	})
-	err = gormDB.AutoMigrate(
-		&models.Invoice{},
-		&models.AppState{},
-	)
 	if err != nil {
-		logrus.Fatalf("Failed to open GORM DB: %v", err)
+		logging.For("db").Fatalf("Failed to open GORM DB: %v", err)
+	}
+	if err := Migrate(gormDB); err != nil {
+		logging.For("db").Fatalf("Failed to migrate DB: %v", err)
 	}
 
 	if cfg.AppEnv == "debug" {
 		gormDB = gormDB.Debug()
-		logrus.Info("GORM debug mode enabled")
+		logging.For("db").Info("GORM debug mode enabled")
 	}
 	return gormDB
 }
 
+// Migrate creates and updates the schema.
+func Migrate(gormDB *gorm.DB) error {
+	err := gormDB.AutoMigrate(
+		&models.Invoice{},
+		&models.AppState{},
+		&models.TransferReview{},
+		&models.Payment{},
+		&models.Overpayment{},
+		&models.Refund{},
+		&models.LineItem{},
+		&models.Customer{},
+		&models.CustomerWallet{},
+		&models.InvoiceNumberSequence{},
+		&models.MerchantProfile{},
+		&models.PaymentLink{},
+		&models.SubscriptionPlan{},
+		&models.Subscription{},
+		&models.InvoiceNotification{},
+		&models.EmailMessage{},
+		&models.EmailPreference{},
+		&models.EmailTemplate{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate tables: %w", err)
+	}
+
+	// Transfer invoices are matched by exact amount, so the amount must be
+	// unique among pending invoices paid to the same address.
+	err = gormDB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_pending_transfer_amount
+		ON invoice (payment_address, amount_wei) WHERE mode = 'transfer' AND status = 'PENDING'`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create transfer amount index: %w", err)
+	}
+
+	// Invoice numbers are unique per merchant; sequences are keyed by the
+	// lower-case merchant address.
+	err = gormDB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_merchant_number
+		ON invoice (LOWER(merchant_address), invoice_number) WHERE invoice_number IS NOT NULL`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create invoice number index: %w", err)
+	}
+
+	// Deposit address indexes are allocated from a sequence so concurrent
+	// API replicas never derive the same address. BIP-32 non-hardened
+	// indexes stop at 2^31-1.
+	err = gormDB.Exec(`CREATE SEQUENCE IF NOT EXISTS deposit_index_seq MINVALUE 0 MAXVALUE 2147483647 START WITH 0`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create deposit index sequence: %w", err)
+	}
+
+	// Invoices used to keep their creation transaction in tx_hash until
+	// paid, so unpaid and expired ones still have it there. Paid (and later
+	// refunded) invoices, or any whose tx_hash is a recorded payment, had it
+	// replaced by the payment already.
+	err = gormDB.Exec(`UPDATE invoice SET creation_tx_hash = tx_hash, tx_hash = NULL
+		WHERE mode = 'onchain' AND status NOT IN ('PAID', 'PARTIALLY_REFUNDED', 'REFUNDED')
+			AND creation_tx_hash IS NULL AND tx_hash IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM payment WHERE payment.invoice_id = invoice.id AND payment.tx_hash = invoice.tx_hash)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to move creation transaction hashes: %w", err)
+	}
+	return nil
+}
+
 func setupDB(cfg *config.DBConfig) *sql.DB {
 	dataSourceName := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
@@ -57,7 +120,7 @@ func setupDB(cfg *config.DBConfig) *sql.DB {
 
 	db, err := sql.Open("pgx", dataSourceName)
 	if err != nil {
-		logrus.Fatalf("Failed to connect to DB: %v", err)
+		logging.For("db").Fatalf("Failed to connect to DB: %v", err)
 	}
 
 	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
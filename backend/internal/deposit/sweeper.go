@@ -0,0 +1,155 @@
+package deposit
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// sweepGas is the gas limit of a sweep, a plain transfer. Merchants must be
+// able to receive ETH without running code (an EOA or a cheap receive()).
+const sweepGas = 21000
+
+// Sweeper moves the balance of paid deposit addresses, and of expired ones
+// that received a partial payment, to their merchant, minus the transfer
+// fee. It goes by balance, so top-ups and late transfers to an address
+// swept before are swept again while the address is watched. It runs with
+// the other background jobs, under leader election.
+type Sweeper struct {
+	*lifecycle.Loop
+
+	repo        repository.InvoiceRepository
+	client      chain.Client
+	wallet      *signer.DepositWallet
+	chainID     *big.Int
+	watchWindow time.Duration
+}
+
+func NewSweeper(cfg *config.Config, repo repository.InvoiceRepository, client chain.Client, wallet *signer.DepositWallet) *Sweeper {
+	s := &Sweeper{
+		repo:        repo,
+		client:      client,
+		wallet:      wallet,
+		chainID:     big.NewInt(cfg.Ethereum.ChainID),
+		watchWindow: cfg.Deposit.WatchWindow,
+	}
+	s.Loop = lifecycle.NewLoop("deposit sweeper", cfg.Deposit.SweepInterval, s.sweepAll)
+	return s
+}
+
+func (s *Sweeper) sweepAll(ctx context.Context) {
+	ctx, span := telemetry.Start(ctx, "Sweeper.sweepAll")
+	defer span.End()
+	logger := logging.From(ctx, "deposit")
+
+	invoices, err := s.repo.FindSweepableDeposits(ctx, time.Now().Add(-s.watchWindow))
+	if err != nil {
+		logger.WithError(err).Error("Failed to load sweepable deposits")
+		return
+	}
+	if len(invoices) == 0 {
+		return
+	}
+	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read gas price")
+		return
+	}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		txHash, err := s.sweep(ctx, invoice, gasPrice)
+		entry := logger.WithFields(map[string]interface{}{
+			logging.FieldInvoiceID: invoice.ID.String(),
+			logging.FieldMerchant:  invoice.MerchantAddress,
+			"deposit_address":      *invoice.DepositAddress,
+		})
+		switch {
+		case err != nil:
+			metrics.DepositSweeps.WithLabelValues("failed").Inc()
+			entry.WithError(err).Error("Failed to sweep deposit address")
+		case txHash != "":
+			metrics.DepositSweeps.WithLabelValues("sent").Inc()
+			entry.WithField(logging.FieldTxHash, txHash).Info("Deposit address swept to merchant")
+		}
+	}
+}
+
+// sweep sends the deposit balance to the merchant. It returns an empty hash
+// without error when there is nothing to do yet.
+func (s *Sweeper) sweep(ctx context.Context, invoice *models.Invoice, gasPrice *big.Int) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "Sweeper.sweep", attribute.String("invoice.id", invoice.ID.String()))
+	defer func() { telemetry.End(span, err) }()
+
+	if invoice.DepositIndex == nil || invoice.DepositAddress == nil {
+		return "", fmt.Errorf("invoice has no deposit address")
+	}
+	// Sweeping to a missing or zero address would burn the funds
+	to := common.HexToAddress(invoice.MerchantAddress)
+	if !common.IsHexAddress(invoice.MerchantAddress) || to == (common.Address{}) {
+		return "", fmt.Errorf("invalid merchant address %q", invoice.MerchantAddress)
+	}
+	key, err := s.wallet.Signer(uint32(*invoice.DepositIndex))
+	if err != nil {
+		return "", err
+	}
+	from := key.Address()
+	if !strings.EqualFold(from.Hex(), *invoice.DepositAddress) {
+		return "", fmt.Errorf("derived key %s does not match deposit address; was DEPOSIT_XPUB changed?", from.Hex())
+	}
+
+	balance, err := s.client.BalanceAt(ctx, from, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get balance: %v", err)
+	}
+	fee := new(big.Int).Mul(gasPrice, big.NewInt(sweepGas))
+	if balance.Cmp(fee) <= 0 {
+		logging.From(ctx, "deposit").WithField("balance_wei", balance.String()).Debug("Deposit balance does not cover the sweep fee")
+		return "", nil
+	}
+
+	// A sweep sent earlier may still be pending, or its hash may have
+	// failed to save; never send a second one on top of it.
+	pending, err := s.client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return "", fmt.Errorf("failed to get nonce: %v", err)
+	}
+	confirmed, err := s.client.NonceAt(ctx, from, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get nonce: %v", err)
+	}
+	if pending != confirmed {
+		return "", nil
+	}
+
+	value := new(big.Int).Sub(balance, fee)
+	tx := types.NewTransaction(pending, to, value, sweepGas, gasPrice, nil)
+	signedTx, err := key.SignTx(ctx, tx, s.chainID)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign sweep: %v", err)
+	}
+	if err := s.client.SendTransaction(ctx, signedTx); err != nil {
+		return "", fmt.Errorf("failed to send sweep: %v", err)
+	}
+
+	txHash := signedTx.Hash().Hex()
+	if err := s.repo.UpdateSweepTx(ctx, invoice.ID.String(), txHash); err != nil {
+		return "", fmt.Errorf("sweep %s sent but not recorded: %v", txHash, err)
+	}
+	return txHash, nil
+}
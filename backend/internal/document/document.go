@@ -0,0 +1,342 @@
+// Package document renders invoice and receipt PDFs in the branding of the
+// invoice's merchant, and payment request QR codes.
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+const (
+	pageWidth = 210.0 // A4, in mm
+	margin    = 15.0
+	bodyWidth = pageWidth - 2*margin
+	// headerHeight is the height of the classic template's colored band.
+	headerHeight = 38.0
+	// qrSize is the printed size of the payment QR code; 40mm scans
+	// reliably from a screen or paper.
+	qrSize = 40.0
+)
+
+// defaultAccent is used when the merchant has no accent color.
+var defaultAccent = [3]int{0x25, 0x63, 0xEB}
+
+// Details are the parts shared by invoices and receipts.
+type Details struct {
+	Invoice *models.Invoice
+	// Merchant is the merchant's profile; a zero profile prints only the
+	// merchant address.
+	Merchant *models.MerchantProfile
+	ChainID  int64
+	// Fiat prices amounts in the display currency; nil if no rate is
+	// known, in which case only ETH amounts are shown.
+	Fiat *fiat.Quote
+	// Generated is printed in the footer.
+	Generated time.Time
+}
+
+// page wraps a PDF with the merchant's branding.
+type page struct {
+	pdf    *gofpdf.Fpdf
+	tr     func(string) string
+	d      *Details
+	accent [3]int
+}
+
+func newPage(d *Details, title string) *page {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetCreationDate(d.Generated)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Crypto Invoice Generator", true)
+	if d.Merchant.Name != "" {
+		pdf.SetAuthor(d.Merchant.Name, true)
+	}
+
+	p := &page{
+		pdf:    pdf,
+		tr:     pdf.UnicodeTranslatorFromDescriptor(""),
+		d:      d,
+		accent: parseColor(d.Merchant.AccentColor),
+	}
+	pdf.SetFooterFunc(p.footer)
+	pdf.AddPage()
+	return p
+}
+
+// header draws the template's header with title, e.g. "INVOICE", and the
+// document reference below it.
+func (p *page) header(title, reference string) {
+	pdf := p.pdf
+	classic := p.d.Merchant.Template != models.TemplateMinimal
+	if classic {
+		pdf.SetFillColor(p.accent[0], p.accent[1], p.accent[2])
+		pdf.Rect(0, 0, pageWidth, headerHeight, "F")
+		pdf.SetTextColor(255, 255, 255)
+	} else {
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	nameX := margin
+	if p.logo(margin, 9, 20) {
+		nameX = margin + 34
+	}
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetXY(nameX, 12)
+	pdf.CellFormat(90, 8, p.tr(p.merchantName()), "", 0, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.SetXY(pageWidth-margin-80, 10)
+	pdf.CellFormat(80, 10, p.tr(title), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(80, 6, p.tr(reference), "", 0, "R", false, 0, "")
+
+	if !classic {
+		pdf.SetDrawColor(p.accent[0], p.accent[1], p.accent[2])
+		pdf.SetLineWidth(0.8)
+		pdf.Line(margin, headerHeight-4, pageWidth-margin, headerHeight-4)
+	}
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetY(headerHeight + 8)
+}
+
+// logo draws the merchant logo at x, y with height h, reporting whether
+// there was a usable one.
+func (p *page) logo(x, y, h float64) bool {
+	var imageType string
+	switch http.DetectContentType(p.d.Merchant.Logo) {
+	case "image/png":
+		imageType = "PNG"
+	case "image/jpeg":
+		imageType = "JPG"
+	default:
+		return false
+	}
+	opts := gofpdf.ImageOptions{ImageType: imageType}
+	info := p.pdf.RegisterImageOptionsReader("logo", opts, bytes.NewReader(p.d.Merchant.Logo))
+	if p.pdf.Err() || info == nil {
+		// A broken logo must not break the document
+		p.pdf.ClearError()
+		return false
+	}
+	p.pdf.ImageOptions("logo", x, y, 0, h, false, opts, 0, "")
+	return true
+}
+
+// parties prints the merchant's details on the left and facts about the
+// document, as label/value pairs, on the right.
+func (p *page) parties(facts [][2]string) {
+	pdf := p.pdf
+	top := pdf.GetY()
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.CellFormat(90, 5, "FROM", "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(90, 5, p.tr(p.merchantName()), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	m := p.d.Merchant
+	var lines []string
+	if m.PostalAddress != "" {
+		lines = append(lines, strings.Split(m.PostalAddress, "\n")...)
+	}
+	for _, line := range []string{m.Email, m.Website} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if m.TaxID != "" {
+		lines = append(lines, "Tax ID: "+m.TaxID)
+	}
+	for _, line := range lines {
+		pdf.CellFormat(90, 4.5, p.tr(strings.TrimSpace(line)), "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont("Courier", "", 8)
+	pdf.CellFormat(90, 4.5, p.d.Invoice.MerchantAddress, "", 1, "L", false, 0, "")
+	left := pdf.GetY()
+
+	pdf.SetY(top)
+	for _, fact := range facts {
+		pdf.SetX(pageWidth - margin - 85)
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(30, 5, p.tr(fact[0]), "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(55, 5, p.tr(fact[1]), "", 1, "R", false, 0, "")
+	}
+	pdf.SetY(max(left, pdf.GetY()) + 6)
+}
+
+// sectionTitle starts a section with an accent-colored heading.
+func (p *page) sectionTitle(title string) {
+	pdf := p.pdf
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetTextColor(p.accent[0], p.accent[1], p.accent[2])
+	pdf.CellFormat(bodyWidth, 7, p.tr(title), "", 1, "L", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// totalRow prints a right-aligned label and ETH amount, with the fiat
+// amount in a third column when a rate is known.
+func (p *page) totalRow(label, wei string, bold bool) {
+	pdf := p.pdf
+	style := ""
+	if bold {
+		style = "B"
+	}
+	pdf.SetFont("Helvetica", style, 10)
+	pdf.SetX(pageWidth - margin - 120)
+	pdf.CellFormat(40, 6, p.tr(label), "", 0, "R", false, 0, "")
+	pdf.CellFormat(45, 6, FormatETH(wei)+" ETH", "", 0, "R", false, 0, "")
+	fiatAmount := ""
+	if p.d.Fiat != nil {
+		if n, ok := new(big.Int).SetString(wei, 10); ok {
+			fiatAmount = fiat.Format(p.d.Fiat.Convert(n)) + " " + p.d.Fiat.Currency
+		}
+	}
+	pdf.CellFormat(35, 6, fiatAmount, "", 1, "R", false, 0, "")
+}
+
+// fiatNote explains where the fiat amounts come from.
+func (p *page) fiatNote() {
+	q := p.d.Fiat
+	if q == nil {
+		return
+	}
+	note := fmt.Sprintf("Fiat amounts at 1 ETH = %s %s", fiat.Format(q.Rate), q.Currency)
+	if !q.AsOf.IsZero() {
+		note += " as of " + formatTime(q.AsOf)
+	}
+	p.pdf.SetFont("Helvetica", "I", 8)
+	p.pdf.SetTextColor(100, 100, 100)
+	p.pdf.CellFormat(bodyWidth, 5, p.tr(note+"; for reference only."), "", 1, "R", false, 0, "")
+	p.pdf.SetTextColor(0, 0, 0)
+}
+
+// field prints a label with a monospaced value below it, for hashes and
+// addresses.
+func (p *page) field(label, value string) {
+	pdf := p.pdf
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.CellFormat(bodyWidth, 4.5, p.tr(label), "", 1, "L", false, 0, "")
+	pdf.SetFont("Courier", "", 8)
+	pdf.MultiCell(bodyWidth, 4, value, "", "L", false)
+	pdf.Ln(1)
+}
+
+// qr draws a QR code of content at x, y.
+func (p *page) qr(content string, x, y float64) error {
+	png, err := QRPNG(content, 512)
+	if err != nil {
+		return err
+	}
+	opts := gofpdf.ImageOptions{ImageType: "PNG"}
+	p.pdf.RegisterImageOptionsReader("qr", opts, bytes.NewReader(png))
+	p.pdf.ImageOptions("qr", x, y, qrSize, qrSize, false, opts, 0, "")
+	return nil
+}
+
+func (p *page) footer() {
+	pdf := p.pdf
+	pdf.SetY(-16)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(margin, pdf.GetY(), pageWidth-margin, pdf.GetY())
+	pdf.Ln(1.5)
+	pdf.SetFont("Helvetica", "", 7.5)
+	pdf.SetTextColor(100, 100, 100)
+	if p.d.Merchant.FooterText != "" {
+		pdf.CellFormat(bodyWidth, 4, p.tr(p.d.Merchant.FooterText), "", 1, "C", false, 0, "")
+	}
+	pdf.CellFormat(bodyWidth, 4, fmt.Sprintf("Generated %s - page %d", formatTime(p.d.Generated), pdf.PageNo()), "", 0, "C", false, 0, "")
+}
+
+func (p *page) output() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := p.pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *page) merchantName() string {
+	if p.d.Merchant.Name != "" {
+		return p.d.Merchant.Name
+	}
+	return shortAddress(p.d.Invoice.MerchantAddress)
+}
+
+// reference is how documents name the invoice: its number, or its ID for
+// invoices created before numbering.
+func reference(invoice *models.Invoice) string {
+	if invoice.InvoiceNumber != nil {
+		return *invoice.InvoiceNumber
+	}
+	return invoice.ID.String()
+}
+
+func network(chainID int64) string {
+	switch chainID {
+	case 1:
+		return "Ethereum Mainnet"
+	case 11155111:
+		return "Sepolia"
+	case 31337:
+		return "Hardhat"
+	}
+	return "Chain " + strconv.FormatInt(chainID, 10)
+}
+
+// parseColor reads "#RRGGBB", falling back to defaultAccent.
+func parseColor(hex string) [3]int {
+	n, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if len(hex) != 7 || err != nil {
+		return defaultAccent
+	}
+	return [3]int{int(n >> 16 & 0xFF), int(n >> 8 & 0xFF), int(n & 0xFF)}
+}
+
+// FormatETH renders wei as an exact decimal ETH amount.
+func FormatETH(wei string) string {
+	n, ok := new(big.Int).SetString(wei, 10)
+	if !ok || n.Sign() < 0 {
+		return wei
+	}
+	digits := n.String()
+	if len(digits) <= 18 {
+		digits = strings.Repeat("0", 19-len(digits)) + digits
+	}
+	whole, frac := digits[:len(digits)-18], strings.TrimRight(digits[len(digits)-18:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+func shortAddress(address string) string {
+	if len(address) < 12 {
+		return address
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package document
+
+import (
+	"bytes"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// Invoice is the invoice document.
+type Invoice struct {
+	Details
+	// PaymentURI is the EIP-681 request paying the rest of the invoice,
+	// printed as a QR code; empty if the invoice cannot be paid from one.
+	PaymentURI string
+}
+
+// itemColumns are the widths of the line item table columns.
+var itemColumns = [5]float64{82, 18, 35, 15, 30}
+
+// RenderInvoice renders the invoice as a PDF.
+func RenderInvoice(doc *Invoice) ([]byte, error) {
+	invoice := doc.Invoice
+	p := newPage(&doc.Details, "Invoice "+reference(invoice))
+	p.header("INVOICE", reference(invoice))
+	p.parties([][2]string{
+		{"Invoice", reference(invoice)},
+		{"Issued", formatTime(invoice.CreatedAt)},
+		{"Due", formatTime(invoice.ExpiresAt)},
+		{"Status", strings.ReplaceAll(string(invoice.Status), "_", " ")},
+		{"Network", network(doc.ChainID)},
+	})
+
+	p.lineItems(invoice)
+	p.pdf.Ln(3)
+	if invoice.SubtotalWei != nil {
+		p.totalRow("Subtotal", *invoice.SubtotalWei, false)
+		optionalRow(p, "Less discount", invoice.DiscountWei)
+		optionalRow(p, "Tax", invoice.TaxWei)
+		optionalRow(p, "Shipping", invoice.ShippingWei)
+	}
+	p.totalRow("Total", invoice.AmountWei, true)
+	paid := invoice.PaidWei()
+	if paid.Sign() > 0 {
+		p.totalRow("Paid", paid.String(), false)
+		amount, _ := new(big.Int).SetString(invoice.AmountWei, 10)
+		if amount != nil && amount.Cmp(paid) > 0 {
+			p.totalRow("Amount due", new(big.Int).Sub(amount, paid).String(), true)
+		}
+	}
+	p.fiatNote()
+	p.pdf.Ln(6)
+
+	if err := p.payment(doc); err != nil {
+		return nil, err
+	}
+	if invoice.ContentHash != nil {
+		p.pdf.Ln(2)
+		p.field("Content hash", *invoice.ContentHash)
+	}
+	return p.output()
+}
+
+// lineItems prints the item table; invoices without items get one line
+// for their amount.
+func (p *page) lineItems(invoice *models.Invoice) {
+	pdf := p.pdf
+	headers := [5]string{"Description", "Qty", "Unit price (ETH)", "Tax", "Total (ETH)"}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(240, 240, 240)
+	for i, h := range headers {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(itemColumns[i], 7, h, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	if len(invoice.LineItems) == 0 {
+		p.itemRow("Payment", "1", FormatETH(invoice.AmountWei), "", FormatETH(invoice.AmountWei))
+		return
+	}
+	for _, item := range invoice.LineItems {
+		tax := ""
+		if item.TaxRateBps > 0 {
+			tax = strconv.FormatFloat(float64(item.TaxRateBps)/100, 'f', -1, 64) + "%"
+		}
+		p.itemRow(item.Description, item.Quantity, FormatETH(item.UnitPriceWei), tax, FormatETH(item.SubtotalWei))
+	}
+}
+
+// itemRow prints one table row, wrapping the description.
+func (p *page) itemRow(description, quantity, unitPrice, tax, total string) {
+	pdf := p.pdf
+	// SplitLines works on the translated single-byte text, unlike SplitText
+	lines := pdf.SplitLines([]byte(p.tr(description)), itemColumns[0]-2)
+	height := 6 * float64(max(len(lines), 1))
+	if pdf.GetY()+height > 270 {
+		pdf.AddPage()
+	}
+	x, y := pdf.GetX(), pdf.GetY()
+	pdf.MultiCell(itemColumns[0], 6, string(bytes.Join(lines, []byte("\n"))), "", "L", false)
+	pdf.SetXY(x+itemColumns[0], y)
+	for i, value := range []string{quantity, unitPrice, tax, total} {
+		pdf.CellFormat(itemColumns[i+1], 6, value, "", 0, "R", false, 0, "")
+	}
+	pdf.SetXY(x, y+height)
+	pdf.SetDrawColor(220, 220, 220)
+	pdf.SetLineWidth(0.2)
+	pdf.Line(margin, y+height, pageWidth-margin, y+height)
+}
+
+// payment prints how to pay: a QR code of the payment request, or why
+// there is none.
+func (p *page) payment(doc *Invoice) error {
+	pdf := p.pdf
+	invoice := doc.Invoice
+	if pdf.GetY()+qrSize+12 > 270 {
+		pdf.AddPage()
+	}
+	p.sectionTitle("Payment")
+
+	if doc.PaymentURI == "" {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(bodyWidth, 5, p.tr(paymentNote(invoice, doc.Generated)), "", "L", false)
+		return nil
+	}
+
+	top := pdf.GetY()
+	if err := p.qr(doc.PaymentURI, margin, top); err != nil {
+		return err
+	}
+	textX := margin + qrSize + 6
+	textWidth := bodyWidth - qrSize - 6
+	pdf.SetXY(textX, top)
+	pdf.SetFont("Helvetica", "", 9)
+	instructions := "Scan the code with an Ethereum wallet on " + network(doc.ChainID) + ", or open the payment link below."
+	if invoice.Mode == models.ModeTransfer {
+		instructions += " Send exactly the amount shown: it identifies your payment."
+	}
+	pdf.MultiCell(textWidth, 5, p.tr(instructions), "", "L", false)
+	pdf.Ln(2)
+	pdf.SetX(textX)
+	pdf.SetFont("Courier", "", 7.5)
+	pdf.MultiCell(textWidth, 3.8, doc.PaymentURI, "", "L", false)
+	pdf.SetY(max(pdf.GetY(), top+qrSize) + 4)
+	return nil
+}
+
+func paymentNote(invoice *models.Invoice, now time.Time) string {
+	switch invoice.Status {
+	case models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded:
+		return "This invoice has been paid. Download the receipt for the payment details."
+	}
+	if invoice.Status == models.StatusExpired || now.After(invoice.ExpiresAt) {
+		return "This invoice has expired and can no longer be paid."
+	}
+	if invoice.Mode == models.ModeOnchain && invoice.OnchainInvoiceID == "" {
+		return "This invoice is still being registered on-chain. Download it again in a few minutes for a payment code."
+	}
+	return "Pay this invoice from its payment page."
+}
+
+func optionalRow(p *page, label string, wei *string) {
+	if wei != nil && *wei != "0" {
+		p.totalRow(label, *wei, false)
+	}
+}
@@ -0,0 +1,55 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// quietZone is the blank border around a QR code, in modules, that
+// scanners need to find it.
+const quietZone = 4
+
+// QRPNG renders content as a size x size pixel PNG QR code.
+func QRPNG(content string, size int) ([]byte, error) {
+	png, err := qrcode.Encode(content, qrcode.Medium, size)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	return png, nil
+}
+
+// QRSVG renders content as a scalable SVG QR code, one module per user
+// unit. Dark modules are drawn as horizontal runs in a single path, which
+// keeps the file small.
+func QRSVG(content string) ([]byte, error) {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, fmt.Errorf("encode QR code: %w", err)
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+	size := len(bitmap) + 2*quietZone
+
+	var path strings.Builder
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+quietZone, y+quietZone, x-start, x-start)
+		}
+	}
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="#fff"/>`, size, size)
+	fmt.Fprintf(&svg, `<path d="%s" fill="#000"/>`, path.String())
+	svg.WriteString("</svg>\n")
+	return []byte(svg.String()), nil
+}
@@ -0,0 +1,80 @@
+package document
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// Receipt is the payment receipt of a settled invoice.
+type Receipt struct {
+	Details
+	// Payments are the payments that settled the invoice, oldest first. A
+	// payment's unknown block details are printed as "-".
+	Payments []models.Payment
+}
+
+// RenderReceipt renders the receipt as a PDF.
+func RenderReceipt(doc *Receipt) ([]byte, error) {
+	invoice := doc.Invoice
+	p := newPage(&doc.Details, "Receipt for invoice "+reference(invoice))
+	p.header("RECEIPT", reference(invoice))
+
+	paidAt := "-"
+	if n := len(doc.Payments); n > 0 && doc.Payments[n-1].BlockTime != nil {
+		paidAt = formatTime(*doc.Payments[n-1].BlockTime)
+	}
+	p.parties([][2]string{
+		{"Invoice", reference(invoice)},
+		{"Paid", paidAt},
+		{"Status", strings.ReplaceAll(string(invoice.Status), "_", " ")},
+		{"Network", network(doc.ChainID)},
+	})
+
+	p.sectionTitle("Payments")
+	for i, payment := range doc.Payments {
+		p.receiptPayment(i+1, &payment)
+	}
+	p.pdf.Ln(2)
+
+	p.totalRow("Invoice total", invoice.AmountWei, false)
+	p.totalRow("Total paid", invoice.PaidWei().String(), true)
+	if refunded, ok := new(big.Int).SetString(invoice.RefundedWei, 10); ok && refunded.Sign() > 0 {
+		p.totalRow("Refunded", refunded.String(), false)
+	}
+	p.fiatNote()
+	return p.output()
+}
+
+// receiptPayment prints the n-th payment with its transaction details.
+func (p *page) receiptPayment(n int, payment *models.Payment) {
+	pdf := p.pdf
+	if pdf.GetY()+40 > 270 {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Helvetica", "B", 10)
+	title := fmt.Sprintf("Payment %d: %s ETH", n, FormatETH(payment.AmountWei))
+	if p.d.Fiat != nil {
+		if amount, ok := new(big.Int).SetString(payment.AmountWei, 10); ok {
+			title += fmt.Sprintf(" (%s %s)", fiat.Format(p.d.Fiat.Convert(amount)), p.d.Fiat.Currency)
+		}
+	}
+	pdf.CellFormat(bodyWidth, 6, p.tr(title), "", 1, "L", false, 0, "")
+
+	block, confirmed := "-", "-"
+	if payment.BlockNumber > 0 {
+		block = strconv.FormatUint(payment.BlockNumber, 10)
+	}
+	if payment.BlockTime != nil {
+		confirmed = formatTime(*payment.BlockTime)
+	}
+	p.field("Transaction hash", orDash(payment.TxHash))
+	p.field("Block", block)
+	p.field("Payer", orDash(payment.PayerAddress))
+	p.field("Confirmed", confirmed)
+	pdf.Ln(2)
+}
@@ -0,0 +1,74 @@
+// Package eip681 builds EIP-681 payment request URIs, which wallets open
+// from links and QR codes to prefill a transaction.
+package eip681
+
+import (
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// URI is a payment request: either a plain transfer of Value to Target, or
+// a call of Function on the Target contract.
+type URI struct {
+	Target common.Address
+	// ChainID is omitted from the URI when zero; wallets then use the
+	// network they are on.
+	ChainID  int64
+	Function string
+	// Params are the function arguments, in order.
+	Params []Param
+	// Value is the wei sent along; nil sends none.
+	Value *big.Int
+}
+
+// Param is one function argument, keyed by its Solidity type, e.g.
+// {"uint256", "42"}.
+type Param struct {
+	Type  string
+	Value string
+}
+
+// Transfer requests value wei sent to the address to.
+func Transfer(to common.Address, chainID int64, value *big.Int) URI {
+	return URI{Target: to, ChainID: chainID, Value: value}
+}
+
+// Call requests a call of function on contract, sending value wei along.
+func Call(contract common.Address, chainID int64, function string, value *big.Int, params ...Param) URI {
+	return URI{Target: contract, ChainID: chainID, Function: function, Params: params, Value: value}
+}
+
+// String renders the URI, e.g.
+// "ethereum:0xAbc...@1/payInvoice?uint256=42&value=1000000000000000000".
+// Amounts are plain integers in base units, which every wallet parses, rather
+// than the scientific notation EIP-681 also allows.
+func (u URI) String() string {
+	var b strings.Builder
+	b.WriteString("ethereum:")
+	b.WriteString(u.Target.Hex())
+	if u.ChainID != 0 {
+		b.WriteString("@")
+		b.WriteString(strconv.FormatInt(u.ChainID, 10))
+	}
+	if u.Function != "" {
+		b.WriteString("/")
+		b.WriteString(u.Function)
+	}
+
+	var query []string
+	for _, p := range u.Params {
+		query = append(query, p.Type+"="+url.QueryEscape(p.Value))
+	}
+	if u.Value != nil {
+		query = append(query, "value="+u.Value.String())
+	}
+	if len(query) > 0 {
+		b.WriteString("?")
+		b.WriteString(strings.Join(query, "&"))
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package eip681
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestURIString(t *testing.T) {
+	// Lower case on purpose: the URI must carry the checksummed form
+	target := common.HexToAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	oneEther, _ := new(big.Int).SetString("1000000000000000000", 10)
+	large, _ := new(big.Int).SetString("123456789000000000000000000", 10)
+
+	tests := []struct {
+		name string
+		uri  URI
+		want string
+	}{
+		{
+			name: "transfer",
+			uri:  Transfer(target, 1, oneEther),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@1?value=1000000000000000000",
+		},
+		{
+			name: "transfer without chain",
+			uri:  Transfer(target, 0, big.NewInt(1)),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed?value=1",
+		},
+		{
+			name: "large value has no exponent",
+			uri:  Transfer(target, 11155111, large),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@11155111?value=123456789000000000000000000",
+		},
+		{
+			name: "call with argument and value",
+			uri:  Call(target, 1, "payInvoice", oneEther, Param{Type: "uint256", Value: "42"}),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@1/payInvoice?uint256=42&value=1000000000000000000",
+		},
+		{
+			name: "call keeps argument order",
+			uri: Call(target, 1, "f", nil,
+				Param{Type: "address", Value: target.Hex()},
+				Param{Type: "uint256", Value: "7"}),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@1/f?address=0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed&uint256=7",
+		},
+		{
+			name: "arguments are escaped",
+			uri:  Call(target, 1, "f", nil, Param{Type: "string", Value: "a b&c"}),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@1/f?string=a+b%26c",
+		},
+		{
+			name: "no value",
+			uri:  Transfer(target, 1, nil),
+			want: "ethereum:0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed@1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.uri.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
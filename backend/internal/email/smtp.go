@@ -0,0 +1,185 @@
+// Package email renders invoice emails from templates and sends them over
+// SMTP.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+)
+
+// sendTimeout bounds one SMTP conversation.
+const sendTimeout = 30 * time.Second
+
+// Message is one email to one recipient.
+type Message struct {
+	// ID makes the Message-ID, which stays the same across retries so
+	// that receivers can drop duplicates.
+	ID      string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+	// UnsubscribeURL, if set, is offered as one-click unsubscribe
+	// (RFC 8058).
+	UnsubscribeURL string
+}
+
+// Sender delivers messages.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender sends each message in its own SMTP session.
+type SMTPSender struct {
+	cfg  *config.EmailConfig
+	from *mail.Address
+}
+
+func NewSMTPSender(cfg *config.EmailConfig) (*SMTPSender, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_FROM: %w", err)
+	}
+	return &SMTPSender{cfg: cfg, from: from}, nil
+}
+
+func (s *SMTPSender) Send(ctx context.Context, msg Message) (err error) {
+	ctx, span := telemetry.Start(ctx, "email.SMTPSender.Send")
+	defer func() { telemetry.End(span, err) }()
+
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+	body, err := s.build(msg, to)
+	if err != nil {
+		return err
+	}
+
+	host := s.cfg.SMTPHost
+	addr := net.JoinHostPort(host, strconv.Itoa(s.cfg.SMTPPort))
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	var conn net.Conn
+	if s.cfg.SMTPTLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(sendTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if s.cfg.SMTPTLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not offer STARTTLS; set SMTP_TLS=none only for local servers")
+		}
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.SMTPUsername, s.cfg.SMTPPassword, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// build renders msg as a multipart/alternative MIME message.
+func (s *SMTPSender) build(msg Message, to *mail.Address) ([]byte, error) {
+	var buf bytes.Buffer
+	parts := multipart.NewWriter(&buf)
+
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+	header := []string{
+		"From: " + s.from.String(),
+		"To: " + to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", singleLine(msg.Subject)),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: <" + msg.ID + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	if msg.UnsubscribeURL != "" {
+		header = append(header,
+			"List-Unsubscribe: <"+singleLine(msg.UnsubscribeURL)+">",
+			"List-Unsubscribe-Post: List-Unsubscribe=One-Click")
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	// Plain text first: clients show the last part they understand
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// singleLine keeps header values from starting new headers.
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
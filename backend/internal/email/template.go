@@ -0,0 +1,177 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+//go:embed templates/*
+var templates embed.FS
+
+var errEmptySubject = errors.New("subject is required")
+
+var (
+	defaultHTML = mustRead("templates/default.html")
+	defaultText = mustRead("templates/default.txt")
+)
+
+// defaultSubjects are the subjects of emails without a merchant template.
+var defaultSubjects = map[models.EmailAudience]map[models.NotificationKind]string{
+	models.AudienceCustomer: {
+		models.NotifyCreated:  "Invoice {{.Reference}} from {{.Merchant}}",
+		models.NotifyPaid:     "Payment received for invoice {{.Reference}}",
+		models.NotifyExpired:  "Invoice {{.Reference}} has expired",
+		models.NotifyReminder: "Invoice {{.Reference}} is due in {{.ExpiresIn}}",
+		models.NotifyReissued: "New invoice {{.Reference}} from {{.Merchant}}",
+	},
+	models.AudienceMerchant: {
+		models.NotifyCreated: "Invoice {{.Reference}} created",
+		models.NotifyPaid:    "Invoice {{.Reference}} was paid",
+		models.NotifyExpired: "Invoice {{.Reference}} expired unpaid",
+	},
+}
+
+// Template is one email: Subject and Text are Go text templates, HTML an
+// html/template, all executed with Data.
+type Template struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Data is what templates can show about an invoice.
+type Data struct {
+	Kind     models.NotificationKind
+	Audience models.EmailAudience
+	// Merchant is the merchant's name, or address without a profile.
+	Merchant string
+	// Accent is a "#RRGGBB" color.
+	Accent string
+	// Reference is the invoice number, or ID without one.
+	Reference string
+	InvoiceID string
+	Status    string
+	// Amount is in ETH, exact, e.g. "0.105 ETH".
+	Amount    string
+	LineItems []LineItem
+	// ExpiresAt is formatted in UTC; ExpiresIn is set for reminders, e.g.
+	// "1 hour".
+	ExpiresAt string
+	ExpiresIn string
+	Customer  string
+	// Payer and TxHash are set once paid.
+	Payer  string
+	TxHash string
+	// Replaces is the reference of the expired invoice a re-issued one
+	// replaces.
+	Replaces       string
+	CheckoutURL    string
+	InvoicePDFURL  string
+	ReceiptURL     string
+	PreferencesURL string
+	Footer         string
+}
+
+type LineItem struct {
+	Description string
+	Quantity    string
+	// Total is in ETH, exact.
+	Total string
+}
+
+// Rendered is an email ready to send.
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Default returns the built-in email for kind and audience, and false if
+// there is none, e.g. reminders to merchants.
+func Default(kind models.NotificationKind, audience models.EmailAudience) (Template, bool) {
+	subject, ok := defaultSubjects[audience][kind]
+	if !ok {
+		return Template{}, false
+	}
+	return Template{Subject: subject, HTML: defaultHTML, Text: defaultText}, true
+}
+
+// Render executes t with data.
+func Render(t Template, data Data) (*Rendered, error) {
+	subject, err := executeText("subject", t.Subject, data)
+	if err != nil {
+		return nil, err
+	}
+	text, err := executeText("text", t.Text, data)
+	if err != nil {
+		return nil, err
+	}
+	page, err := htmltemplate.New("html").Parse(t.HTML)
+	if err != nil {
+		return nil, err
+	}
+	var html bytes.Buffer
+	if err := page.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	return &Rendered{Subject: singleLine(subject), HTML: html.String(), Text: text}, nil
+}
+
+// Validate checks that t parses and renders, so that a broken merchant
+// template is rejected when saved rather than when an email is due.
+func Validate(t Template) error {
+	if strings.TrimSpace(t.Subject) == "" {
+		return errEmptySubject
+	}
+	_, err := Render(t, SampleData())
+	return err
+}
+
+// SampleData is example data for previews and template checks.
+func SampleData() Data {
+	return Data{
+		Kind:           models.NotifyCreated,
+		Audience:       models.AudienceCustomer,
+		Merchant:       "Example Store",
+		Accent:         "#2563EB",
+		Reference:      "INV-2026-000123",
+		InvoiceID:      "00000000-0000-0000-0000-000000000000",
+		Status:         string(models.StatusPending),
+		Amount:         "0.105 ETH",
+		LineItems:      []LineItem{{Description: "Widget", Quantity: "2", Total: "0.1 ETH"}, {Description: "Shipping", Quantity: "1", Total: "0.005 ETH"}},
+		ExpiresAt:      "18 Oct 2026 21:00 UTC",
+		ExpiresIn:      "1 hour",
+		Customer:       "Ada Lovelace",
+		Replaces:       "INV-2026-000122",
+		CheckoutURL:    "https://pay.example.com/pay/00000000-0000-0000-0000-000000000000",
+		InvoicePDFURL:  "https://pay.example.com/api/invoices/00000000-0000-0000-0000-000000000000/pdf",
+		ReceiptURL:     "https://pay.example.com/api/invoices/00000000-0000-0000-0000-000000000000/receipt.pdf",
+		PreferencesURL: "https://pay.example.com/email/preferences/token",
+	}
+}
+
+func executeText(name, source string, data Data) (string, error) {
+	t, err := texttemplate.New(name).Parse(source)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func mustRead(name string) string {
+	b, err := templates.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
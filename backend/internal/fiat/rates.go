@@ -0,0 +1,145 @@
+package fiat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fetchTimeout bounds one price feed request, so a slow feed only delays
+// a document instead of hanging it.
+const fetchTimeout = 5 * time.Second
+
+// ErrNoRate is returned when neither the price feed nor the static rates
+// price the currency.
+var ErrNoRate = errors.New("no exchange rate for currency")
+
+// Quote is the price of one ETH in a fiat currency.
+type Quote struct {
+	Currency string
+	Rate     *big.Rat
+	// AsOf is when the price was fetched; zero for static rates.
+	AsOf time.Time
+}
+
+// Convert prices amountWei in the quote's currency.
+func (q *Quote) Convert(amountWei *big.Int) *big.Rat {
+	eth := new(big.Rat).SetFrac(amountWei, big.NewInt(1e18))
+	return eth.Mul(eth, q.Rate)
+}
+
+// Format renders r with two decimals and thousands separators, e.g.
+// "1,234.50".
+func Format(r *big.Rat) string {
+	s := r.FloatString(2)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String() + "." + frac
+}
+
+// Rates prices ETH in fiat currencies, from a CoinGecko-compatible feed when
+// one is configured, falling back to the configured static rates.
+type Rates struct {
+	cfg  *config.FiatConfig
+	http *http.Client
+
+	mu    sync.Mutex
+	cache map[string]Quote
+}
+
+func NewRates(cfg *config.FiatConfig) *Rates {
+	return &Rates{
+		cfg:   cfg,
+		http:  &http.Client{Timeout: fetchTimeout},
+		cache: map[string]Quote{},
+	}
+}
+
+// Quote returns the current price of one ETH in currency.
+func (r *Rates) Quote(ctx context.Context, currency string) (*Quote, error) {
+	currency = strings.ToUpper(currency)
+	if r.cfg.PriceURL != "" {
+		quote, err := r.live(ctx, currency)
+		if err == nil {
+			return quote, nil
+		}
+		logging.From(ctx, "fiat").WithError(err).WithField("currency", currency).Warn("Price feed failed, using static rate")
+	}
+	rate, ok := r.cfg.StaticRates[currency]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrNoRate, currency)
+	}
+	return &Quote{Currency: currency, Rate: rate}, nil
+}
+
+func (r *Rates) live(ctx context.Context, currency string) (_ *Quote, err error) {
+	r.mu.Lock()
+	cached, ok := r.cache[currency]
+	r.mu.Unlock()
+	if ok && time.Since(cached.AsOf) < r.cfg.CacheTTL {
+		return &cached, nil
+	}
+
+	ctx, span := telemetry.Start(ctx, "fiat.Rates.live", attribute.String("fiat.currency", currency))
+	defer func() { telemetry.End(span, err) }()
+
+	u, err := url.Parse(r.cfg.PriceURL)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	query.Set("ids", r.cfg.CoinID)
+	query.Set("vs_currencies", strings.ToLower(currency))
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("price feed returned %s", resp.Status)
+	}
+
+	// {"ethereum": {"usd": 3512.17}}; json.Number keeps the price exact
+	var body map[string]map[string]json.Number
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode price feed: %w", err)
+	}
+	price, ok := body[r.cfg.CoinID][strings.ToLower(currency)]
+	if !ok {
+		return nil, fmt.Errorf("%w %s in price feed", ErrNoRate, currency)
+	}
+	rate, ok := new(big.Rat).SetString(price.String())
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("price feed returned invalid price %q", price)
+	}
+
+	quote := Quote{Currency: currency, Rate: rate, AsOf: time.Now()}
+	r.mu.Lock()
+	r.cache[currency] = quote
+	r.mu.Unlock()
+	return &quote, nil
+}
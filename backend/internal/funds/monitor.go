@@ -0,0 +1,257 @@
+package funds
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+)
+
+const abiPath = "internal/abi/invoice.json"
+
+// gasSampleSize is the number of recent createInvoice receipts averaged to
+// estimate gas per invoice.
+const gasSampleSize = 20
+
+// ErrFundsLow is returned by Allow while the signer balance is below the
+// critical threshold.
+var ErrFundsLow = errors.New("signer wallet balance is below the critical threshold; invoice creation is paused")
+
+type Level int
+
+const (
+	LevelOK Level = iota
+	LevelWarning
+	LevelCritical
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelWarning:
+		return "warning"
+	case LevelCritical:
+		return "critical"
+	default:
+		return "ok"
+	}
+}
+
+// Status is the result of the latest balance check.
+type Status struct {
+	Level             Level     `json:"-"`
+	LevelName         string    `json:"level"`
+	BalanceWei        string    `json:"balance_wei"`
+	GasPerInvoice     uint64    `json:"gas_per_invoice"`
+	GasPriceWei       string    `json:"gas_price_wei"`
+	EstimatedCapacity uint64    `json:"estimated_invoice_capacity"`
+	CheckedAt         time.Time `json:"checked_at"`
+}
+
+// Monitor periodically checks the signer balance, estimates how many more
+// invoices it can pay gas for, and acts as a circuit breaker for invoice
+// creation while the balance is critical. It runs in every API process,
+// independent of leader election, since each one guards its own requests.
+type Monitor struct {
+	*lifecycle.Loop
+
+	cfg      *config.FundsConfig
+	client   chain.Client
+	signer   common.Address
+	contract common.Address
+	created  common.Hash
+
+	mu     sync.RWMutex
+	status Status
+	// gasByTx caches gas used per createInvoice transaction, so receipts
+	// are only fetched once.
+	gasByTx map[common.Hash]uint64
+}
+
+func NewMonitor(cfg *config.Config, client chain.Client, signer common.Address) *Monitor {
+	abiFile, err := os.Open(abiPath)
+	if err != nil {
+		panic("Failed to open ABI file: " + err.Error())
+	}
+	defer abiFile.Close()
+
+	parsed, err := abi.JSON(abiFile)
+	if err != nil {
+		panic("Failed to parse contract ABI: " + err.Error())
+	}
+
+	m := &Monitor{
+		cfg:      cfg.Funds,
+		client:   client,
+		signer:   signer,
+		contract: common.HexToAddress(cfg.Ethereum.ContractAddress),
+		created:  parsed.Events["InvoiceCreated"].ID,
+		status:   Status{LevelName: LevelOK.String()},
+		gasByTx:  map[common.Hash]uint64{},
+	}
+	m.Loop = lifecycle.NewLoop("funds monitor", cfg.Funds.CheckInterval, m.check)
+	return m
+}
+
+// Status returns the latest check result. Before the first successful check
+// the level is ok.
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Allow reports whether a new invoice transaction may be sent.
+func (m *Monitor) Allow() error {
+	if m.Status().Level == LevelCritical {
+		return ErrFundsLow
+	}
+	return nil
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	ctx, span := telemetry.Start(ctx, "FundsMonitor.check")
+	var err error
+	defer func() { telemetry.End(span, err) }()
+	logger := logging.From(ctx, "funds")
+
+	balance, err := m.client.BalanceAt(ctx, m.signer, nil)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read signer balance")
+		return
+	}
+	gasPrice, err := m.client.SuggestGasPrice(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read gas price")
+		return
+	}
+	gasPerInvoice := m.recentGasPerInvoice(ctx)
+
+	var capacity uint64
+	cost := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(gasPerInvoice))
+	if cost.Sign() > 0 {
+		if n := new(big.Int).Quo(balance, cost); n.IsUint64() {
+			capacity = n.Uint64()
+		}
+	}
+
+	level := LevelOK
+	switch {
+	case m.cfg.CriticalWei.Sign() > 0 && balance.Cmp(m.cfg.CriticalWei) < 0:
+		level = LevelCritical
+	case m.cfg.WarningWei.Sign() > 0 && balance.Cmp(m.cfg.WarningWei) < 0:
+		level = LevelWarning
+	}
+
+	m.mu.Lock()
+	previous := m.status.Level
+	m.status = Status{
+		Level:             level,
+		LevelName:         level.String(),
+		BalanceWei:        balance.String(),
+		GasPerInvoice:     gasPerInvoice,
+		GasPriceWei:       gasPrice.String(),
+		EstimatedCapacity: capacity,
+		CheckedAt:         time.Now(),
+	}
+	m.mu.Unlock()
+
+	metrics.SignerFundsLevel.Set(float64(level))
+	metrics.SignerInvoiceCapacity.Set(float64(capacity))
+	metrics.SignerGasPerInvoice.Set(float64(gasPerInvoice))
+
+	if level == previous {
+		return
+	}
+	entry := logger.WithFields(map[string]interface{}{
+		"signer":             m.signer.Hex(),
+		"balance_wei":        balance.String(),
+		"estimated_capacity": capacity,
+	})
+	switch level {
+	case LevelCritical:
+		entry.WithField("threshold_wei", m.cfg.CriticalWei.String()).
+			Error("Signer balance below critical threshold; rejecting new invoices until it is topped up")
+	case LevelWarning:
+		entry.WithField("threshold_wei", m.cfg.WarningWei.String()).
+			Warn("Signer balance below warning threshold")
+	default:
+		entry.Info("Signer balance back above thresholds")
+	}
+}
+
+// recentGasPerInvoice averages the gas used by the latest createInvoice
+// transactions in the lookback window, falling back to the configured
+// default when there are none or the lookup fails.
+func (m *Monitor) recentGasPerInvoice(ctx context.Context) uint64 {
+	logger := logging.From(ctx, "funds")
+
+	head, err := m.client.BlockNumber(ctx)
+	if err != nil {
+		logger.WithError(err).Debug("Failed to get block number for gas estimate")
+		return m.cfg.DefaultInvoiceGas
+	}
+	from := uint64(0)
+	if head > m.cfg.GasLookbackBlocks {
+		from = head - m.cfg.GasLookbackBlocks
+	}
+
+	logs, err := m.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(head),
+		Addresses: []common.Address{m.contract},
+		Topics:    [][]common.Hash{{m.created}},
+	})
+	if err != nil {
+		logger.WithError(err).Debug("Failed to fetch recent invoice logs for gas estimate")
+		return m.cfg.DefaultInvoiceGas
+	}
+
+	var txs []common.Hash
+	seen := map[common.Hash]bool{}
+	for i := len(logs) - 1; i >= 0 && len(txs) < gasSampleSize; i-- {
+		if h := logs[i].TxHash; !seen[h] {
+			seen[h] = true
+			txs = append(txs, h)
+		}
+	}
+
+	var total, count uint64
+	for _, h := range txs {
+		gas, ok := m.gasByTx[h]
+		if !ok {
+			receipt, err := m.client.TransactionReceipt(ctx, h)
+			if err != nil {
+				logger.WithError(err).WithField(logging.FieldTxHash, h.Hex()).Debug("Failed to fetch receipt for gas estimate")
+				continue
+			}
+			gas = receipt.GasUsed
+			m.gasByTx[h] = gas
+		}
+		total += gas
+		count++
+	}
+
+	// Keep the cache bounded to the current sample.
+	for h := range m.gasByTx {
+		if !seen[h] {
+			delete(m.gasByTx, h)
+		}
+	}
+
+	if count == 0 {
+		return m.cfg.DefaultInvoiceGas
+	}
+	return total / count
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type CheckoutHandler struct {
+	service service.CheckoutService
+}
+
+func NewCheckoutHandler(service service.CheckoutService) *CheckoutHandler {
+	return &CheckoutHandler{service: service}
+}
+
+// Page serves the hosted checkout.
+func (h *CheckoutHandler) Page(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, err := h.service.Page(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrInvoiceNotFound) {
+		c.String(http.StatusNotFound, "Invoice not found")
+		return
+	}
+	if err != nil {
+		logging.From(ctx, "handler").WithError(err).Error("Failed to load checkout")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+
+	servePage(c, http.StatusOK, func(w io.Writer, nonce string) error {
+		return checkout.Render(w, page, nonce)
+	})
+}
+
+// servePage renders a server-side page with a fresh nonce for its inline
+// style and script, under a Content-Security-Policy that allows nothing else
+// but this API and its own forms.
+func servePage(c *gin.Context, status int, render func(w io.Writer, nonce string) error) {
+	ctx := c.Request.Context()
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		logging.From(ctx, "handler").WithError(err).Error("Failed to generate page nonce")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+	encoded := base64.StdEncoding.EncodeToString(nonce[:])
+
+	var body bytes.Buffer
+	if err := render(&body, encoded); err != nil {
+		logging.From(ctx, "handler").WithError(err).Error("Failed to render page")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self' data:; style-src 'nonce-"+encoded+"'; "+
+		"script-src 'nonce-"+encoded+"'; connect-src 'self'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'")
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Data(status, "text/html; charset=utf-8", body.Bytes())
+}
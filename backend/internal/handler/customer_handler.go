@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type CustomerHandler struct {
+	service service.CustomerService
+}
+
+func NewCustomerHandler(service service.CustomerService) *CustomerHandler {
+	return &CustomerHandler{service: service}
+}
+
+type CustomerRequest struct {
+	Name              string   `json:"name" binding:"required"`
+	Email             string   `json:"email"`
+	BillingAddress    string   `json:"billing_address"`
+	TaxID             string   `json:"tax_id"`
+	PreferredCurrency string   `json:"preferred_currency"` // ISO 4217 code, e.g. "EUR"
+	Wallets           []string `json:"wallets"`            // Create only; use the wallet endpoints afterwards
+}
+
+type WalletRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+func (h *CustomerHandler) Create(c *gin.Context) {
+	var req CustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, err := h.service.Create(c.Request.Context(), customerParams(req))
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, customer)
+}
+
+// List returns a page of customers, or the owner of ?wallet=.
+func (h *CustomerHandler) List(c *gin.Context) {
+	if wallet := c.Query("wallet"); wallet != "" {
+		customer, err := h.service.FindByWallet(c.Request.Context(), wallet)
+		if err != nil {
+			customerError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, []*models.Customer{customer})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	customers, err := h.service.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customers)
+}
+
+func (h *CustomerHandler) Get(c *gin.Context) {
+	customer, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *CustomerHandler) Update(c *gin.Context) {
+	var req CustomerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, err := h.service.Update(c.Request.Context(), c.Param("id"), customerParams(req))
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *CustomerHandler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		customerError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CustomerHandler) AddWallet(c *gin.Context) {
+	var req WalletRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	customer, err := h.service.AddWallet(c.Request.Context(), c.Param("id"), req.Address)
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *CustomerHandler) RemoveWallet(c *gin.Context) {
+	customer, err := h.service.RemoveWallet(c.Request.Context(), c.Param("id"), c.Param("address"))
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, customer)
+}
+
+func (h *CustomerHandler) Invoices(c *gin.Context) {
+	invoices, err := h.service.Invoices(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		customerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+func customerParams(req CustomerRequest) service.CustomerParams {
+	return service.CustomerParams{
+		Name:              req.Name,
+		Email:             req.Email,
+		BillingAddress:    req.BillingAddress,
+		TaxID:             req.TaxID,
+		PreferredCurrency: req.PreferredCurrency,
+		Wallets:           req.Wallets,
+	}
+}
+
+func customerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCustomerNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidCustomer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_CUSTOMER"})
+	case errors.Is(err, repository.ErrWalletTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "WALLET_TAKEN"})
+	case errors.Is(err, repository.ErrCustomerSubscribed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "CUSTOMER_SUBSCRIBED"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Customer request failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
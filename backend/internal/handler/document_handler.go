@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type DocumentHandler struct {
+	service service.DocumentService
+}
+
+func NewDocumentHandler(service service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{service: service}
+}
+
+func (h *DocumentHandler) InvoicePDF(c *gin.Context) {
+	doc, err := h.service.InvoicePDF(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	sendDocument(c, doc)
+}
+
+func (h *DocumentHandler) ReceiptPDF(c *gin.Context) {
+	doc, err := h.service.ReceiptPDF(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	sendDocument(c, doc)
+}
+
+// QRPNG renders the payment URI as a PNG of ?size= pixels (default 256).
+func (h *DocumentHandler) QRPNG(c *gin.Context) {
+	size := 0
+	if raw := c.Query("size"); raw != "" {
+		var err error
+		if size, err = strconv.Atoi(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "size must be an integer"})
+			return
+		}
+	}
+	doc, err := h.service.PaymentQR(c.Request.Context(), c.Param("id"), service.QRFormatPNG, size)
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	sendDocument(c, doc)
+}
+
+func (h *DocumentHandler) QRSVG(c *gin.Context) {
+	doc, err := h.service.PaymentQR(c.Request.Context(), c.Param("id"), service.QRFormatSVG, 0)
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	sendDocument(c, doc)
+}
+
+// sendDocument serves the document inline, so browsers show it, under a
+// name that is used when it is saved.
+func sendDocument(c *gin.Context, doc *service.Document) {
+	c.Header("Content-Disposition", `inline; filename="`+doc.Filename+`"`)
+	c.Data(http.StatusOK, doc.ContentType, doc.Content)
+}
+
+func documentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvoiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+	case errors.Is(err, service.ErrNotPaid):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "INVOICE_NOT_PAID"})
+	case errors.Is(err, service.ErrNotPayable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "NOT_PAYABLE"})
+	case errors.Is(err, service.ErrInvalidQRSize):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Document rendering failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
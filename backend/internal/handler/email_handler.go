@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type EmailHandler struct {
+	service service.EmailService
+}
+
+func NewEmailHandler(service service.EmailService) *EmailHandler {
+	return &EmailHandler{service: service}
+}
+
+type EmailTemplateRequest struct {
+	Subject string `json:"subject"` // Go text/template
+	HTML    string `json:"html"`    // Go html/template
+	Text    string `json:"text"`    // Go text/template
+}
+
+type EmailPreferenceRequest struct {
+	Unsubscribed bool     `json:"unsubscribed"`
+	Disabled     []string `json:"disabled"` // Kinds not to send, e.g. "reminder"
+}
+
+// Outbox lists queued and sent emails, only those in ?status= if given.
+func (h *EmailHandler) Outbox(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	messages, err := h.service.Outbox(c.Request.Context(), models.EmailStatus(c.Query("status")), limit, offset)
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+func (h *EmailHandler) Retry(c *gin.Context) {
+	msg, err := h.service.Retry(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+func (h *EmailHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.service.ListTemplates(c.Request.Context(), c.Param("merchant"))
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate returns the merchant's template, or the default one to start
+// from if they have none.
+func (h *EmailHandler) GetTemplate(c *gin.Context) {
+	t, err := h.service.Template(c.Request.Context(), c.Param("merchant"),
+		models.NotificationKind(c.Param("kind")), models.EmailAudience(c.Param("audience")))
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func (h *EmailHandler) SaveTemplate(c *gin.Context) {
+	var req EmailTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	t, err := h.service.SaveTemplate(c.Request.Context(), c.Param("merchant"),
+		models.NotificationKind(c.Param("kind")), models.EmailAudience(c.Param("audience")),
+		service.EmailTemplateParams{Subject: req.Subject, HTML: req.HTML, Text: req.Text})
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func (h *EmailHandler) DeleteTemplate(c *gin.Context) {
+	err := h.service.DeleteTemplate(c.Request.Context(), c.Param("merchant"),
+		models.NotificationKind(c.Param("kind")), models.EmailAudience(c.Param("audience")))
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *EmailHandler) GetPreferences(c *gin.Context) {
+	pref, err := h.service.Preferences(c.Request.Context(), c.Param("email"))
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pref)
+}
+
+func (h *EmailHandler) SavePreferences(c *gin.Context) {
+	var req EmailPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := service.EmailPreferenceParams{Unsubscribed: req.Unsubscribed}
+	for _, kind := range req.Disabled {
+		params.Disabled = append(params.Disabled, models.NotificationKind(kind))
+	}
+	pref, err := h.service.SavePreferences(c.Request.Context(), c.Param("email"), params)
+	if err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pref)
+}
+
+// PreferencesPage shows the recipient's email preferences form.
+func (h *EmailHandler) PreferencesPage(c *gin.Context) {
+	page, err := h.service.PreferencesPage(c.Request.Context(), c.Param("token"))
+	h.servePreferences(c, page, err)
+}
+
+// SavePreferencesPage stores the submitted form. A List-Unsubscribe=One-Click
+// body, which mail clients send for one-click unsubscribe (RFC 8058),
+// unsubscribes from everything instead.
+func (h *EmailHandler) SavePreferencesPage(c *gin.Context) {
+	ctx := c.Request.Context()
+	token := c.Param("token")
+	if c.PostForm("List-Unsubscribe") == "One-Click" {
+		if err := h.service.Unsubscribe(ctx, token); err != nil {
+			h.servePreferences(c, nil, err)
+			return
+		}
+		c.String(http.StatusOK, "Unsubscribed")
+		return
+	}
+
+	// Unchecked boxes are not submitted, so every kind not checked is off
+	enabled := c.PostFormArray("kind")
+	params := service.EmailPreferenceParams{Unsubscribed: c.PostForm("unsubscribe") != ""}
+	for _, kind := range service.EmailKinds() {
+		if !slices.Contains(enabled, string(kind)) {
+			params.Disabled = append(params.Disabled, kind)
+		}
+	}
+	page, err := h.service.SavePreferencesPage(ctx, token, params)
+	h.servePreferences(c, page, err)
+}
+
+func (h *EmailHandler) servePreferences(c *gin.Context, page *checkout.PreferencesPage, err error) {
+	if errors.Is(err, service.ErrEmailPreferencesNotFound) {
+		c.String(http.StatusNotFound, "This link is not valid")
+		return
+	}
+	if err != nil {
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Failed to load email preferences")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+	servePage(c, http.StatusOK, func(w io.Writer, nonce string) error {
+		return checkout.RenderPreferences(w, page, nonce)
+	})
+}
+
+func emailError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrEmailNotFound), errors.Is(err, service.ErrEmailTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailSent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "EMAIL_SENT"})
+	case errors.Is(err, service.ErrInvalidEmailTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_EMAIL_TEMPLATE"})
+	case errors.Is(err, service.ErrInvalidEmailPreferences):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_EMAIL_PREFERENCES"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Email request failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,10 +1,13 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/funds"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
 	"github.com/user/crypto-invoice-generator/backend/internal/service"
 )
 
@@ -17,9 +20,27 @@ func NewInvoiceHandler(service service.InvoiceService) *InvoiceHandler {
 }
 
 type CreateInvoiceRequest struct {
-	MerchantAddress string  `json:"merchant_address"` // Optional, defaults to config
-	AmountETH       float64 `json:"amount_eth" binding:"required,gt=0"`
-	ExpiryMinutes   int     `json:"expiry_minutes" binding:"required,gt=0"`
+	MerchantAddress string            `json:"merchant_address"`                                               // Optional, defaults to config
+	AmountETH       float64           `json:"amount_eth" binding:"omitempty,gt=0"`                            // Required unless line_items are given
+	ExpiryMinutes   int               `json:"expiry_minutes" binding:"omitempty,gt=0"`                        // Optional, defaults to config
+	Mode            string            `json:"mode" binding:"omitempty,oneof=onchain signed transfer deposit"` // Optional, defaults to config
+	AllowPartial    bool              `json:"allow_partial"`                                                  // Optional, onchain and deposit only
+	LineItems       []LineItemRequest `json:"line_items" binding:"omitempty,max=100,dive"`                    // Optional, replaces amount_eth
+	DiscountETH     string            `json:"discount_eth"`                                                   // Optional, itemized invoices only
+	DiscountBps     int               `json:"discount_bps" binding:"omitempty,min=0,max=10000"`               // Optional, itemized invoices only
+	ShippingETH     string            `json:"shipping_eth"`                                                   // Optional, itemized invoices only
+	CustomerID      string            `json:"customer_id"`                                                    // Optional, bills the invoice to a customer
+	SuccessURL      string            `json:"success_url"`                                                    // Optional, hosted checkout redirect once paid
+	ExpiredURL      string            `json:"expired_url"`                                                    // Optional, hosted checkout redirect once expired
+}
+
+// LineItemRequest is one line of an itemized invoice. ETH amounts are
+// decimal strings, e.g. "0.015".
+type LineItemRequest struct {
+	Description  string `json:"description" binding:"required"`
+	Quantity     string `json:"quantity" binding:"required"`
+	UnitPriceETH string `json:"unit_price_eth" binding:"required"`
+	TaxRateBps   int    `json:"tax_rate_bps" binding:"omitempty,min=0,max=10000"`
 }
 
 func (h *InvoiceHandler) CreateInvoice(c *gin.Context) {
@@ -29,9 +50,62 @@ func (h *InvoiceHandler) CreateInvoice(c *gin.Context) {
 		return
 	}
 
-	invoice, err := h.service.CreateInvoice(req.MerchantAddress, req.AmountETH, req.ExpiryMinutes)
+	invoice, err := h.service.CreateInvoice(c.Request.Context(), service.CreateInvoiceParams{
+		MerchantAddress: req.MerchantAddress,
+		AmountETH:       req.AmountETH,
+		ExpiryMinutes:   req.ExpiryMinutes,
+		Mode:            models.InvoiceMode(req.Mode),
+		AllowPartial:    req.AllowPartial,
+		LineItems:       lineItemParams(req.LineItems),
+		DiscountETH:     req.DiscountETH,
+		DiscountBps:     req.DiscountBps,
+		ShippingETH:     req.ShippingETH,
+		CustomerID:      req.CustomerID,
+		SuccessURL:      req.SuccessURL,
+		ExpiredURL:      req.ExpiredURL,
+	})
+	if errors.Is(err, funds.ErrFundsLow) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error(), "code": "SIGNER_FUNDS_LOW"})
+		return
+	}
+	if errors.Is(err, service.ErrTransferModeDisabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "TRANSFER_MODE_DISABLED"})
+		return
+	}
+	if errors.Is(err, service.ErrPartialNotSupported) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "PARTIAL_NOT_SUPPORTED"})
+		return
+	}
+	if errors.Is(err, service.ErrDepositModeDisabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "DEPOSIT_MODE_DISABLED"})
+		return
+	}
+	if errors.Is(err, service.ErrAmountRequired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "AMOUNT_REQUIRED"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidLineItems) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_LINE_ITEMS"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidMerchant) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_MERCHANT_ADDRESS"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidRedirectURL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REDIRECT_URL"})
+		return
+	}
+	if errors.Is(err, service.ErrCustomerNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "UNKNOWN_CUSTOMER"})
+		return
+	}
+	if errors.Is(err, service.ErrNoUniqueAmount) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "NO_UNIQUE_AMOUNT"})
+		return
+	}
 	if err != nil {
-		fmt.Printf("FAILURE: CreateInvoice failed: %v\n", err)
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("CreateInvoice failed")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -41,7 +115,7 @@ func (h *InvoiceHandler) CreateInvoice(c *gin.Context) {
 
 func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
 	id := c.Param("id")
-	invoice, err := h.service.GetInvoice(id)
+	invoice, err := h.service.GetInvoice(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
@@ -49,3 +123,16 @@ func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
 
 	c.JSON(http.StatusOK, invoice)
 }
+
+func lineItemParams(items []LineItemRequest) []service.LineItemParams {
+	params := make([]service.LineItemParams, len(items))
+	for i, item := range items {
+		params[i] = service.LineItemParams{
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			UnitPriceETH: item.UnitPriceETH,
+			TaxRateBps:   item.TaxRateBps,
+		}
+	}
+	return params
+}
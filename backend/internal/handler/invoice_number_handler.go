@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type InvoiceNumberHandler struct {
+	service service.InvoiceNumberService
+}
+
+func NewInvoiceNumberHandler(service service.InvoiceNumberService) *InvoiceNumberHandler {
+	return &InvoiceNumberHandler{service: service}
+}
+
+type NumberSequenceRequest struct {
+	Prefix      string `json:"prefix"`
+	Padding     int    `json:"padding" binding:"required,min=1,max=12"`
+	YearlyReset bool   `json:"yearly_reset"`
+}
+
+// Search finds invoices by ?number= prefix, optionally for ?merchant=.
+func (h *InvoiceNumberHandler) Search(c *gin.Context) {
+	number := c.Query("number")
+	if number == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "number is required"})
+		return
+	}
+
+	invoices, err := h.service.Search(c.Request.Context(), c.Query("merchant"), number)
+	if err != nil {
+		numberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+func (h *InvoiceNumberHandler) GetSequence(c *gin.Context) {
+	seq, err := h.service.GetSequence(c.Request.Context(), c.Param("merchant"))
+	if err != nil {
+		numberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, seq)
+}
+
+func (h *InvoiceNumberHandler) SaveSequence(c *gin.Context) {
+	var req NumberSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seq, err := h.service.SaveSequence(c.Request.Context(), c.Param("merchant"), service.NumberSequenceParams{
+		Prefix:      req.Prefix,
+		Padding:     req.Padding,
+		YearlyReset: req.YearlyReset,
+	})
+	if err != nil {
+		numberError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, seq)
+}
+
+func numberError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidNumberSequence) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_NUMBER_SEQUENCE"})
+		return
+	}
+	logging.From(c.Request.Context(), "handler").WithError(err).Error("Invoice number request failed")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type MerchantHandler struct {
+	service service.MerchantService
+}
+
+func NewMerchantHandler(service service.MerchantService) *MerchantHandler {
+	return &MerchantHandler{service: service}
+}
+
+type MerchantProfileRequest struct {
+	Name          string `json:"name"`
+	PostalAddress string `json:"postal_address"`
+	Email         string `json:"email"`
+	Website       string `json:"website"`
+	TaxID         string `json:"tax_id"`
+	Logo          []byte `json:"logo"`         // Base64 PNG or JPEG, at most 256 KiB
+	AccentColor   string `json:"accent_color"` // "#RRGGBB"
+	Template      string `json:"template" binding:"omitempty,oneof=classic minimal"`
+	FooterText    string `json:"footer_text"`
+	Currency      string `json:"currency"`    // ISO 4217 code, e.g. "EUR"
+	SuccessURL    string `json:"success_url"` // Hosted checkout redirects
+	ExpiredURL    string `json:"expired_url"`
+}
+
+func (h *MerchantHandler) GetProfile(c *gin.Context) {
+	profile, err := h.service.GetProfile(c.Request.Context(), c.Param("merchant"))
+	if err != nil {
+		merchantError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *MerchantHandler) SaveProfile(c *gin.Context) {
+	var req MerchantProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.service.SaveProfile(c.Request.Context(), c.Param("merchant"), service.MerchantProfileParams{
+		Name:          req.Name,
+		PostalAddress: req.PostalAddress,
+		Email:         req.Email,
+		Website:       req.Website,
+		TaxID:         req.TaxID,
+		Logo:          req.Logo,
+		AccentColor:   req.AccentColor,
+		Template:      models.DocumentTemplate(req.Template),
+		FooterText:    req.FooterText,
+		Currency:      req.Currency,
+		SuccessURL:    req.SuccessURL,
+		ExpiredURL:    req.ExpiredURL,
+	})
+	if err != nil {
+		merchantError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func merchantError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_MERCHANT_PROFILE"})
+		return
+	}
+	logging.From(c.Request.Context(), "handler").WithError(err).Error("Merchant profile request failed")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type PaymentLinkHandler struct {
+	service service.PaymentLinkService
+}
+
+func NewPaymentLinkHandler(service service.PaymentLinkService) *PaymentLinkHandler {
+	return &PaymentLinkHandler{service: service}
+}
+
+type PaymentLinkRequest struct {
+	MerchantAddress string            `json:"merchant_address"` // Create only, defaults to config
+	Title           string            `json:"title"`
+	Description     string            `json:"description"`
+	Currency        string            `json:"currency"`   // "ETH" (default) or an ISO 4217 code
+	Amount          string            `json:"amount"`     // Decimal in currency; empty lets the payer choose
+	MinAmount       string            `json:"min_amount"` // Payer-chosen amounts only
+	MaxAmount       string            `json:"max_amount"`
+	Mode            string            `json:"mode" binding:"omitempty,oneof=onchain signed transfer deposit"`
+	ExpiryMinutes   int               `json:"expiry_minutes" binding:"omitempty,gt=0"` // Of each invoice
+	MaxUses         *int              `json:"max_uses"`
+	ExpiresAt       *time.Time        `json:"expires_at"` // Of the link
+	Disabled        bool              `json:"disabled"`
+	SuccessURL      string            `json:"success_url"`
+	ExpiredURL      string            `json:"expired_url"`
+	Metadata        map[string]string `json:"metadata"`
+}
+
+func (h *PaymentLinkHandler) Create(c *gin.Context) {
+	var req PaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.service.Create(c.Request.Context(), paymentLinkParams(req))
+	if err != nil {
+		paymentLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, link)
+}
+
+// List returns a page of links, only ?merchant='s if given.
+func (h *PaymentLinkHandler) List(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	links, err := h.service.List(c.Request.Context(), c.Query("merchant"), limit, offset)
+	if err != nil {
+		paymentLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+func (h *PaymentLinkHandler) Get(c *gin.Context) {
+	link, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		paymentLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+func (h *PaymentLinkHandler) Update(c *gin.Context) {
+	var req PaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.service.Update(c.Request.Context(), c.Param("id"), paymentLinkParams(req))
+	if err != nil {
+		paymentLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+func (h *PaymentLinkHandler) Stats(c *gin.Context) {
+	stats, err := h.service.Stats(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		paymentLinkError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// Show renders the link's page: its amount, or a form for the payer's
+// choice, with a button that posts to Open. It never creates anything, so
+// link previews and prefetching cannot use the link up.
+func (h *PaymentLinkHandler) Show(c *gin.Context) {
+	ctx := c.Request.Context()
+	page, err := h.service.Page(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrPaymentLinkNotFound) {
+		c.String(http.StatusNotFound, "Payment link not found")
+		return
+	}
+	if err != nil {
+		logging.From(ctx, "handler").WithError(err).Error("Failed to load payment link")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+	status := http.StatusOK
+	if page.Unavailable {
+		status = http.StatusGone
+	}
+	servePage(c, status, func(w io.Writer, nonce string) error {
+		return checkout.RenderLink(w, page, nonce)
+	})
+}
+
+// Open creates an invoice from the link and sends the payer to its hosted
+// checkout. It is the target of Show's form, which posts the amount for
+// links whose payer chooses it.
+func (h *PaymentLinkHandler) Open(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	amount := c.PostForm("amount")
+	invoice, err := h.service.Open(ctx, id, amount)
+	if err == nil {
+		c.Redirect(http.StatusSeeOther, "/pay/"+invoice.ID.String())
+		return
+	}
+	if errors.Is(err, service.ErrPaymentLinkNotFound) {
+		c.String(http.StatusNotFound, "Payment link not found")
+		return
+	}
+
+	page, pageErr := h.service.Page(ctx, id)
+	if pageErr != nil {
+		logging.From(ctx, "handler").WithError(pageErr).Error("Failed to load payment link")
+		c.String(http.StatusInternalServerError, "Something went wrong, please try again")
+		return
+	}
+	status := http.StatusOK
+	switch {
+	case errors.Is(err, service.ErrLinkAmountRequired):
+		status = http.StatusBadRequest
+		page.Error = err.Error()
+	case errors.Is(err, service.ErrInvalidLinkAmount):
+		status = http.StatusBadRequest
+		page.Amount = amount
+		page.Error = err.Error()
+	case errors.Is(err, service.ErrLinkUnavailable):
+		status = http.StatusGone
+		page.Unavailable = true
+		page.Error = err.Error()
+	default:
+		logging.From(ctx, "handler").WithError(err).WithField("payment_link_id", id).Error("Failed to open payment link")
+		status = http.StatusServiceUnavailable
+		page.Unavailable = true
+		page.Error = "The invoice could not be created right now. Please try again in a few minutes."
+	}
+	servePage(c, status, func(w io.Writer, nonce string) error {
+		return checkout.RenderLink(w, page, nonce)
+	})
+}
+
+func paymentLinkParams(req PaymentLinkRequest) service.PaymentLinkParams {
+	return service.PaymentLinkParams{
+		MerchantAddress: req.MerchantAddress,
+		Title:           req.Title,
+		Description:     req.Description,
+		Currency:        req.Currency,
+		Amount:          req.Amount,
+		MinAmount:       req.MinAmount,
+		MaxAmount:       req.MaxAmount,
+		Mode:            models.InvoiceMode(req.Mode),
+		ExpiryMinutes:   req.ExpiryMinutes,
+		MaxUses:         req.MaxUses,
+		ExpiresAt:       req.ExpiresAt,
+		Disabled:        req.Disabled,
+		SuccessURL:      req.SuccessURL,
+		ExpiredURL:      req.ExpiredURL,
+		Metadata:        req.Metadata,
+	}
+}
+
+func paymentLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPaymentLinkNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPaymentLink):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PAYMENT_LINK"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Payment link request failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const rateWindow = time.Minute
+
+// clientWindow counts one client's requests in the current window.
+type clientWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimit allows each client IP perMinute requests per fixed one-minute
+// window and answers the rest with 429. Counts are kept in memory, so each
+// API replica limits on its own. The client IP is only taken from
+// X-Forwarded-For behind TRUSTED_PROXIES.
+func RateLimit(perMinute int) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		clients   = map[string]*clientWindow{}
+		lastPrune time.Time
+	)
+	return func(c *gin.Context) {
+		now := time.Now()
+		ip := c.ClientIP()
+
+		mu.Lock()
+		if now.Sub(lastPrune) >= rateWindow {
+			for key, w := range clients {
+				if now.Sub(w.start) >= rateWindow {
+					delete(clients, key)
+				}
+			}
+			lastPrune = now
+		}
+		w := clients[ip]
+		if w == nil || now.Sub(w.start) >= rateWindow {
+			w = &clientWindow{start: now}
+			clients[ip] = w
+		}
+		w.count++
+		allowed := w.count <= perMinute
+		retryAfter := w.start.Add(rateWindow).Sub(now)
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+			c.String(http.StatusTooManyRequests, "Too many requests, please try again in a minute")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
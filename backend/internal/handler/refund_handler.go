@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type RefundHandler struct {
+	service service.RefundService
+}
+
+func NewRefundHandler(service service.RefundService) *RefundHandler {
+	return &RefundHandler{service: service}
+}
+
+type CreateRefundRequest struct {
+	AmountWei     string `json:"amount_wei"`                                       // Optional, defaults to the remaining amount
+	ToAddress     string `json:"to_address"`                                       // Optional, defaults to the payer
+	Method        string `json:"method" binding:"omitempty,oneof=signer merchant"` // Optional, defaults to signer
+	OverpaymentID string `json:"overpayment_id" binding:"omitempty,uuid"`          // Optional, refunds a recorded overpayment
+	Reason        string `json:"reason"`
+}
+
+type SubmitRefundTxRequest struct {
+	TxHash string `json:"tx_hash" binding:"required"`
+}
+
+func (h *RefundHandler) Create(c *gin.Context) {
+	var req CreateRefundRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	refund, err := h.service.Create(c.Request.Context(), c.Param("id"), service.CreateRefundParams{
+		AmountWei:     req.AmountWei,
+		ToAddress:     req.ToAddress,
+		Method:        models.RefundMethod(req.Method),
+		OverpaymentID: req.OverpaymentID,
+		Reason:        req.Reason,
+	})
+	if err != nil {
+		refundError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, refund)
+}
+
+func (h *RefundHandler) List(c *gin.Context) {
+	refunds, err := h.service.List(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		refundError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, refunds)
+}
+
+func (h *RefundHandler) SubmitTx(c *gin.Context) {
+	var req SubmitRefundTxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.service.SubmitTx(c.Request.Context(), c.Param("id"), c.Param("refund_id"), req.TxHash)
+	if err != nil {
+		refundError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, refund)
+}
+
+func refundError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRefundNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invoice or refund not found"})
+	case errors.Is(err, service.ErrInvalidRefund):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_REFUND"})
+	case errors.Is(err, service.ErrNoRefundAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "NO_REFUND_ADDRESS"})
+	case errors.Is(err, service.ErrNotRefundable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "INVOICE_NOT_REFUNDABLE"})
+	case errors.Is(err, service.ErrRefundExceedsPaid):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_EXCEEDS_PAID"})
+	case errors.Is(err, repository.ErrRefundStateChanged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REFUND_ALREADY_SENT"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Refund request failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type ReminderHandler struct {
+	service service.ReminderService
+}
+
+func NewReminderHandler(service service.ReminderService) *ReminderHandler {
+	return &ReminderHandler{service: service}
+}
+
+// Notifications lists the reminders and other notifications sent about an
+// invoice, per channel.
+func (h *ReminderHandler) Notifications(c *gin.Context) {
+	notifications, err := h.service.Notifications(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrInvoiceNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Failed to list invoice notifications")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, notifications)
+}
@@ -0,0 +1,226 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type SubscriptionHandler struct {
+	service service.SubscriptionService
+}
+
+func NewSubscriptionHandler(service service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: service}
+}
+
+type PlanRequest struct {
+	MerchantAddress string `json:"merchant_address"` // Create only, defaults to config
+	Name            string `json:"name" binding:"required"`
+	Description     string `json:"description"`
+	Currency        string `json:"currency"` // "ETH" (default) or an ISO 4217 code
+	Amount          string `json:"amount" binding:"required"`
+	Interval        string `json:"interval" binding:"required,oneof=day week month year"`
+	IntervalCount   int    `json:"interval_count"`
+	AnchorDay       int    `json:"anchor_day"`
+	TrialDays       int    `json:"trial_days"`
+	Mode            string `json:"mode" binding:"omitempty,oneof=onchain signed transfer deposit"`
+	ExpiryMinutes   int    `json:"expiry_minutes" binding:"omitempty,gt=0"` // Of each invoice
+	Archived        bool   `json:"archived"`
+}
+
+type SubscriptionRequest struct {
+	PlanID     string            `json:"plan_id" binding:"required"`
+	CustomerID string            `json:"customer_id" binding:"required"`
+	AnchorDay  int               `json:"anchor_day"`
+	StartAt    *time.Time        `json:"start_at"`
+	TrialDays  *int              `json:"trial_days"` // Overrides the plan's
+	Metadata   map[string]string `json:"metadata"`
+}
+
+type CancelSubscriptionRequest struct {
+	AtPeriodEnd bool `json:"at_period_end"`
+}
+
+func (h *SubscriptionHandler) CreatePlan(c *gin.Context) {
+	var req PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.CreatePlan(c.Request.Context(), planParams(req))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, plan)
+}
+
+// ListPlans returns a page of plans, only ?merchant='s if given.
+func (h *SubscriptionHandler) ListPlans(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	plans, err := h.service.ListPlans(c.Request.Context(), c.Query("merchant"), limit, offset)
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
+
+func (h *SubscriptionHandler) GetPlan(c *gin.Context) {
+	plan, err := h.service.GetPlan(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *SubscriptionHandler) UpdatePlan(c *gin.Context) {
+	var req PlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.service.UpdatePlan(c.Request.Context(), c.Param("id"), planParams(req))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *SubscriptionHandler) Create(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription, err := h.service.Create(c.Request.Context(), service.SubscriptionParams{
+		PlanID:     req.PlanID,
+		CustomerID: req.CustomerID,
+		AnchorDay:  req.AnchorDay,
+		StartAt:    req.StartAt,
+		TrialDays:  req.TrialDays,
+		Metadata:   req.Metadata,
+	})
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// List returns a page of subscriptions, filtered by ?customer_id= and
+// ?status= if given.
+func (h *SubscriptionHandler) List(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	status := models.SubscriptionStatus(c.Query("status"))
+	subscriptions, err := h.service.List(c.Request.Context(), c.Query("customer_id"), status, limit, offset)
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+func (h *SubscriptionHandler) Get(c *gin.Context) {
+	subscription, err := h.service.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *SubscriptionHandler) Invoices(c *gin.Context) {
+	invoices, err := h.service.Invoices(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, invoices)
+}
+
+func (h *SubscriptionHandler) Pause(c *gin.Context) {
+	subscription, err := h.service.Pause(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+func (h *SubscriptionHandler) Resume(c *gin.Context) {
+	subscription, err := h.service.Resume(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Cancel ends the subscription now, or with {"at_period_end": true} once
+// the current period ends.
+func (h *SubscriptionHandler) Cancel(c *gin.Context) {
+	var req CancelSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	subscription, err := h.service.Cancel(c.Request.Context(), c.Param("id"), req.AtPeriodEnd)
+	if err != nil {
+		subscriptionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+func planParams(req PlanRequest) service.PlanParams {
+	return service.PlanParams{
+		MerchantAddress: req.MerchantAddress,
+		Name:            req.Name,
+		Description:     req.Description,
+		Currency:        req.Currency,
+		Amount:          req.Amount,
+		Interval:        models.BillingInterval(req.Interval),
+		IntervalCount:   req.IntervalCount,
+		AnchorDay:       req.AnchorDay,
+		TrialDays:       req.TrialDays,
+		Mode:            models.InvoiceMode(req.Mode),
+		ExpiryMinutes:   req.ExpiryMinutes,
+		Archived:        req.Archived,
+	}
+}
+
+func subscriptionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPlanNotFound), errors.Is(err, service.ErrSubscriptionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_PLAN"})
+	case errors.Is(err, service.ErrInvalidSubscription):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "INVALID_SUBSCRIPTION"})
+	case errors.Is(err, service.ErrCustomerNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "code": "UNKNOWN_CUSTOMER"})
+	case errors.Is(err, service.ErrSubscriptionState):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "SUBSCRIPTION_STATE"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Subscription request failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
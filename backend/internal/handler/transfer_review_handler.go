@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+type TransferReviewHandler struct {
+	service service.TransferReviewService
+}
+
+func NewTransferReviewHandler(service service.TransferReviewService) *TransferReviewHandler {
+	return &TransferReviewHandler{service: service}
+}
+
+type ResolveReviewRequest struct {
+	InvoiceID string `json:"invoice_id" binding:"required"`
+	Note      string `json:"note"`
+}
+
+type DismissReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// RequireAdmin rejects requests without "Authorization: Bearer <token>". An
+// empty token disables the admin API entirely.
+func RequireAdmin(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "admin API is disabled"})
+			return
+		}
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (h *TransferReviewHandler) List(c *gin.Context) {
+	status := models.ReviewStatus(strings.ToUpper(c.Query("status")))
+	switch status {
+	case "", models.ReviewOpen, models.ReviewResolved, models.ReviewDismissed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be OPEN, RESOLVED or DISMISSED"})
+		return
+	}
+
+	reviews, err := h.service.List(c.Request.Context(), status)
+	if err != nil {
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("ListTransferReviews failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, reviews)
+}
+
+func (h *TransferReviewHandler) Resolve(c *gin.Context) {
+	var req ResolveReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, err := h.service.Resolve(c.Request.Context(), c.Param("id"), req.InvoiceID, req.Note)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func (h *TransferReviewHandler) Dismiss(c *gin.Context) {
+	var req DismissReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	review, err := h.service.Dismiss(c.Request.Context(), c.Param("id"), req.Note)
+	if err != nil {
+		reviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, review)
+}
+
+func reviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "review or invoice not found"})
+	case errors.Is(err, repository.ErrReviewClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "REVIEW_CLOSED"})
+	case errors.Is(err, repository.ErrInvoiceNotPayable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "INVOICE_ALREADY_PAID"})
+	default:
+		logging.From(c.Request.Context(), "handler").WithError(err).Error("Transfer review update failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,73 @@
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const recentErrorsSize = 50
+
+type RecordedError struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component,omitempty"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ErrorLog is a logrus hook keeping the most recent error-level entries for
+// the diagnostics endpoint.
+type ErrorLog struct {
+	mu      sync.Mutex
+	entries []RecordedError
+	next    int
+	full    bool
+}
+
+// RecentErrors is installed on the standard logrus logger at startup.
+var RecentErrors = &ErrorLog{entries: make([]RecordedError, recentErrorsSize)}
+
+func (l *ErrorLog) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+}
+
+func (l *ErrorLog) Fire(entry *logrus.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	recorded := RecordedError{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: entry.Message,
+	}
+	if component, ok := entry.Data["component"].(string); ok {
+		recorded.Component = component
+	}
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		recorded.Error = err.Error()
+	}
+	l.entries[l.next] = recorded
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	return nil
+}
+
+// Snapshot returns the recorded errors, newest first.
+func (l *ErrorLog) Snapshot() []RecordedError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := l.next
+	if l.full {
+		n = len(l.entries)
+	}
+	out := make([]RecordedError, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package health
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+const checkTimeout = 5 * time.Second
+
+var startedAt = time.Now()
+
+type CheckResult struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Checker answers liveness, readiness and diagnostics requests. Readiness
+// verifies every dependency the invoice flow needs, so an orchestrator only
+// routes traffic to instances that can actually create and track invoices.
+type Checker struct {
+	cfg    *config.Config
+	db     *gorm.DB
+	client chain.Client
+	signer common.Address
+}
+
+func NewChecker(cfg *config.Config, db *gorm.DB, client chain.Client, signer common.Address) *Checker {
+	return &Checker{cfg: cfg, db: db, client: client, signer: signer}
+}
+
+// Liveness reports that the process is running and serving requests.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness runs all dependency checks concurrently.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) (string, error){
+		"database":       c.checkDatabase,
+		"rpc":            c.checkRPC,
+		"chain_id":       c.checkChainID,
+		"contract":       c.checkContract,
+		"watcher_lag":    c.checkWatcherLag,
+		"signer_balance": c.checkSignerBalance,
+	}
+
+	results := make(map[string]CheckResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) (string, error)) {
+			defer wg.Done()
+			detail, err := check(ctx)
+			result := CheckResult{OK: err == nil, Detail: detail}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, result := range results {
+		if !result.OK {
+			status, code = "not ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "checks": results})
+}
+
+// DebugStatus summarizes the effective configuration, watcher cursor and
+// recent errors. It requires "Authorization: Bearer <DEBUG_TOKEN>".
+func (c *Checker) DebugStatus(w http.ResponseWriter, r *http.Request) {
+	token := c.cfg.Health.DebugToken
+	if token == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "debug endpoint disabled"})
+		return
+	}
+	provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	cursor := map[string]interface{}{}
+	if last, err := c.lastProcessedBlock(ctx); err != nil {
+		cursor["error"] = err.Error()
+	} else {
+		cursor["last_processed_block"] = last
+	}
+	if head, err := c.client.BlockNumber(ctx); err != nil {
+		cursor["head_error"] = err.Error()
+	} else {
+		cursor["head_block"] = head
+	}
+
+	status := map[string]interface{}{
+		"uptime_seconds": int64(time.Since(startedAt).Seconds()),
+		"config":         c.cfg.Redacted(),
+		"watcher":        cursor,
+		"recent_errors":  RecentErrors.Snapshot(),
+	}
+	status["signer_address"] = c.signer.Hex()
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (c *Checker) checkDatabase(ctx context.Context) (string, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return "", err
+	}
+	return "", sqlDB.PingContext(ctx)
+}
+
+func (c *Checker) checkRPC(ctx context.Context) (string, error) {
+	head, err := c.client.BlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("head block %d", head), nil
+}
+
+func (c *Checker) checkChainID(ctx context.Context) (string, error) {
+	chainID, err := c.client.ChainID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if chainID.Int64() != c.cfg.Ethereum.ChainID {
+		return "", fmt.Errorf("RPC chain ID %s does not match configured %d", chainID, c.cfg.Ethereum.ChainID)
+	}
+	return chainID.String(), nil
+}
+
+func (c *Checker) checkContract(ctx context.Context) (string, error) {
+	addr := common.HexToAddress(c.cfg.Ethereum.ContractAddress)
+	code, err := c.client.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(code) == 0 {
+		return "", fmt.Errorf("no contract code at %s", addr.Hex())
+	}
+	return fmt.Sprintf("%d bytes of code", len(code)), nil
+}
+
+func (c *Checker) checkWatcherLag(ctx context.Context) (string, error) {
+	last, err := c.lastProcessedBlock(ctx)
+	if err != nil {
+		return "", err
+	}
+	head, err := c.client.BlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	var lag uint64
+	if head > last {
+		lag = head - last
+	}
+	if lag > c.cfg.Health.MaxWatcherLag {
+		return "", fmt.Errorf("watcher is %d blocks behind (max %d)", lag, c.cfg.Health.MaxWatcherLag)
+	}
+	return fmt.Sprintf("%d blocks behind head", lag), nil
+}
+
+func (c *Checker) checkSignerBalance(ctx context.Context) (string, error) {
+	minBalance := c.cfg.Health.MinSignerBalanceWei
+	if minBalance.Sign() == 0 {
+		return "check disabled", nil
+	}
+	balance, err := c.client.BalanceAt(ctx, c.signer, nil)
+	if err != nil {
+		return "", err
+	}
+	if balance.Cmp(minBalance) < 0 {
+		return "", fmt.Errorf("signer balance %s wei below minimum %s wei", balance, minBalance)
+	}
+	return balance.String() + " wei", nil
+}
+
+// lastProcessedBlock reads the watcher cursor from the database, so it works
+// whether the watcher runs in this process or in cmd/worker.
+func (c *Checker) lastProcessedBlock(ctx context.Context) (uint64, error) {
+	var state models.AppState
+	if err := c.db.WithContext(ctx).First(&state).Error; err != nil {
+		return 0, fmt.Errorf("watcher cursor unavailable: %v", err)
+	}
+	return state.LastProcessedBlock, nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
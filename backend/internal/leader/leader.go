@@ -0,0 +1,126 @@
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+// Elector implements leader election on top of a Postgres session-level
+// advisory lock. The lock is held on a dedicated connection for as long as
+// this process is leader; if that connection dies, Postgres releases the lock
+// and a standby instance acquires it on its next attempt.
+type Elector struct {
+	db       *sql.DB
+	key      int64
+	interval time.Duration
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewElector(db *sql.DB, key int64, interval time.Duration) *Elector {
+	return &Elector{
+		db:       db,
+		key:      key,
+		interval: interval,
+	}
+}
+
+// Campaign blocks until the advisory lock is acquired or ctx is cancelled.
+// The returned channel is closed when the lock can no longer be guaranteed.
+func (e *Elector) Campaign(ctx context.Context) (<-chan struct{}, error) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := e.tryAcquire(ctx)
+		if err != nil {
+			logging.For("leader").WithError(err).Warn("Leader election attempt failed")
+		}
+		if acquired {
+			logging.For("leader").WithField("lock_key", e.key).Info("Acquired leader lock")
+			lost := make(chan struct{})
+			go e.holdLock(lost)
+			return lost, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resign releases the lock so that a standby can take over immediately
+// instead of waiting for the session to time out.
+func (e *Elector) Resign(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	_, err := e.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", e.key)
+	e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+func (e *Elector) tryAcquire(ctx context.Context) (bool, error) {
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve connection: %v", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("failed to query advisory lock: %v", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	e.mu.Lock()
+	e.conn = conn
+	e.mu.Unlock()
+	return true, nil
+}
+
+// holdLock checks the lock session every interval and closes lost as soon
+// as the session stops responding. It returns quietly after Resign.
+func (e *Elector) holdLock(lost chan struct{}) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		e.mu.Lock()
+		conn := e.conn
+		e.mu.Unlock()
+		if conn == nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), e.interval)
+		_, err := conn.ExecContext(ctx, "SELECT 1")
+		cancel()
+		if err != nil {
+			logging.For("leader").WithError(err).Error("Leader lock session lost")
+			e.mu.Lock()
+			if e.conn != nil {
+				e.conn.Close()
+				e.conn = nil
+			}
+			e.mu.Unlock()
+			close(lost)
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+// stopTimeout bounds how long stopping jobs may take after the lock was lost.
+const stopTimeout = 30 * time.Second
+
+// Supervisor runs a group of components only while this instance holds the
+// leader lock. When the lock is lost the group is stopped and the supervisor
+// campaigns again, so a process can regain leadership without restarting.
+type Supervisor struct {
+	elector  *Elector
+	newGroup func() *lifecycle.Group
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	active *lifecycle.Group
+}
+
+func NewSupervisor(elector *Elector, newGroup func() *lifecycle.Group) *Supervisor {
+	return &Supervisor{elector: elector, newGroup: newGroup}
+}
+
+func (s *Supervisor) Name() string {
+	return "leader supervisor"
+}
+
+func (s *Supervisor) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(runCtx)
+	return nil
+}
+
+// Stop stops the jobs (if this instance is leader) and then releases the
+// lock, so a standby only takes over once the jobs here have finished.
+func (s *Supervisor) Stop(ctx context.Context) error {
+	if s.done == nil {
+		return nil
+	}
+	s.cancel()
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if s.active != nil {
+		if err := s.active.Stop(ctx); err != nil {
+			return err
+		}
+		s.active = nil
+	}
+	return s.elector.Resign(ctx)
+}
+
+func (s *Supervisor) run(ctx context.Context) {
+	defer close(s.done)
+	logger := logging.For("leader")
+
+	for {
+		logger.Info("Waiting for leader lock before starting background jobs")
+		lost, err := s.elector.Campaign(ctx)
+		if err != nil {
+			return
+		}
+
+		group := s.newGroup()
+		if err := group.Start(ctx); err != nil {
+			logger.WithError(err).Error("Failed to start background jobs")
+			if err := s.elector.Resign(ctx); err != nil {
+				logger.WithError(err).Warn("Failed to release leader lock")
+			}
+			if !sleep(ctx, s.elector.interval) {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			// Stop takes over the running group
+			s.active = group
+			return
+		case <-lost:
+			logger.Warn("Leader lock lost; stopping background jobs")
+			stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+			if err := group.Stop(stopCtx); err != nil {
+				logger.WithError(err).Error("Failed to stop background jobs")
+			}
+			cancel()
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,70 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+// Component is a long-running background job. Start must return promptly and
+// run the work in its own goroutines; Stop must block until that work has
+// finished or ctx expires.
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// Group starts components in order and stops them in reverse order.
+type Group struct {
+	components []Component
+
+	mu      sync.Mutex
+	started []Component
+}
+
+func NewGroup(components ...Component) *Group {
+	return &Group{components: components}
+}
+
+// Start starts every component. If one fails, the components started before
+// it are stopped again and the error is returned.
+func (g *Group) Start(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, c := range g.components {
+		if err := c.Start(ctx); err != nil {
+			startErr := fmt.Errorf("failed to start %s: %v", c.Name(), err)
+			return errors.Join(startErr, g.stopStarted(ctx))
+		}
+		logging.For("lifecycle").Infof("Started %s", c.Name())
+		g.started = append(g.started, c)
+	}
+	return nil
+}
+
+// Stop stops all started components in reverse start order.
+func (g *Group) Stop(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.stopStarted(ctx)
+}
+
+func (g *Group) stopStarted(ctx context.Context) error {
+	var errs []error
+	for i := len(g.started) - 1; i >= 0; i-- {
+		c := g.started[i]
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %v", c.Name(), err))
+			continue
+		}
+		logging.For("lifecycle").Infof("Stopped %s", c.Name())
+	}
+	g.started = nil
+	return errors.Join(errs...)
+}
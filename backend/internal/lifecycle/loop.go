@@ -0,0 +1,71 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Loop runs fn immediately and then every interval until stopped. Components
+// that poll on a schedule embed a Loop to get Start/Stop for free. The ctx
+// passed to fn is cancelled by Stop; fn decides which of its work must run
+// to completion regardless.
+type Loop struct {
+	name     string
+	interval time.Duration
+	fn       func(ctx context.Context)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewLoop(name string, interval time.Duration, fn func(ctx context.Context)) *Loop {
+	return &Loop{name: name, interval: interval, fn: fn}
+}
+
+func (l *Loop) Name() string {
+	return l.name
+}
+
+func (l *Loop) Start(ctx context.Context) error {
+	if l.done != nil {
+		return errors.New("already started")
+	}
+
+	// The loop outlives the caller's start context; only Stop ends it.
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.cancel = cancel
+	l.done = make(chan struct{})
+
+	go func() {
+		defer close(l.done)
+
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-timer.C:
+			}
+			l.fn(runCtx)
+			timer.Reset(l.interval)
+		}
+	}()
+	return nil
+}
+
+func (l *Loop) Stop(ctx context.Context) error {
+	if l.done == nil {
+		return nil
+	}
+	l.cancel()
+
+	select {
+	case <-l.done:
+		l.done = nil
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
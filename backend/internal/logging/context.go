@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type fieldsKey struct{}
+
+// With returns a context whose loggers carry the given field in addition to
+// those already attached, e.g. the request ID or the invoice being handled.
+func With(ctx context.Context, key string, value interface{}) context.Context {
+	existing, _ := ctx.Value(fieldsKey{}).(logrus.Fields)
+	fields := make(logrus.Fields, len(existing)+1)
+	for k, v := range existing {
+		fields[k] = v
+	}
+	fields[key] = value
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// From returns the component logger carrying the fields attached to ctx,
+// plus the trace and span IDs when ctx carries a recorded span.
+func From(ctx context.Context, component string) *logrus.Entry {
+	entry := For(component)
+	if fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		entry = entry.WithFields(logrus.Fields{
+			"trace_id": sc.TraceID().String(),
+			"span_id":  sc.SpanID().String(),
+		})
+	}
+	return entry.WithContext(ctx)
+}
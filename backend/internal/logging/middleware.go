@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID assigns every request an ID (reusing a valid incoming
+// X-Request-ID), echoes it in the response and attaches it to the request
+// context so that all logs written while handling it carry request_id.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if _, err := uuid.Parse(id); err != nil {
+		id = uuid.NewString()
+	}
+	c.Header(RequestIDHeader, id)
+	c.Request = c.Request.WithContext(With(c.Request.Context(), FieldRequestID, id))
+	c.Next()
+}
+
+// AccessLog replaces gin's default logger with a structured access log.
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	entry := From(c.Request.Context(), "http").WithFields(map[string]interface{}{
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+		"route":      c.FullPath(),
+		"status":     c.Writer.Status(),
+		"latency_ms": time.Since(start).Milliseconds(),
+		"client_ip":  c.ClientIP(),
+	})
+	if len(c.Errors) > 0 {
+		entry = entry.WithField("errors", c.Errors.String())
+	}
+
+	switch status := c.Writer.Status(); {
+	case status >= 500:
+		entry.Error("request completed")
+	case status >= 400:
+		entry.Warn("request completed")
+	default:
+		entry.Info("request completed")
+	}
+}
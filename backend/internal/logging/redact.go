@@ -0,0 +1,36 @@
+package logging
+
+import (
+	"bytes"
+
+	"github.com/sirupsen/logrus"
+)
+
+var redactedValue = []byte("[REDACTED]")
+
+// redactingFormatter masks known secret values in the formatted entry, so
+// secrets are removed from messages and fields alike, including SQL and DSN
+// strings logged by libraries.
+type redactingFormatter struct {
+	inner   logrus.Formatter
+	secrets [][]byte
+}
+
+func newRedactingFormatter(inner logrus.Formatter, secrets []string) *redactingFormatter {
+	f := &redactingFormatter{inner: inner}
+	for _, s := range secrets {
+		f.secrets = append(f.secrets, []byte(s))
+	}
+	return f
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	out, err := f.inner.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range f.secrets {
+		out = bytes.ReplaceAll(out, secret, redactedValue)
+	}
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+// AccountReader is the part of the chain client needed to inspect an account.
+type AccountReader interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+}
+
+const accountScrapeTimeout = 5 * time.Second
+
+var (
+	signerBalanceDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "signer", "balance_wei"),
+		"Balance of the wallet that signs invoice transactions.",
+		[]string{"address"}, nil,
+	)
+	signerPendingDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "signer", "pending_transactions"),
+		"Transactions sent by the signer wallet that are not yet mined.",
+		[]string{"address"}, nil,
+	)
+)
+
+// accountCollector queries the signer account at scrape time, so the values
+// are always current and no background polling is needed.
+type accountCollector struct {
+	client  AccountReader
+	address common.Address
+}
+
+// RegisterSignerAccount exposes balance and pending transaction count of the
+// signer wallet.
+func RegisterSignerAccount(client AccountReader, address common.Address) {
+	Registry.MustRegister(&accountCollector{client: client, address: address})
+}
+
+func (c *accountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- signerBalanceDesc
+	ch <- signerPendingDesc
+}
+
+func (c *accountCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), accountScrapeTimeout)
+	defer cancel()
+
+	addr := c.address.Hex()
+	logger := logging.For("metrics")
+
+	balance, err := c.client.BalanceAt(ctx, c.address, nil)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read signer balance")
+	} else {
+		value, _ := new(big.Float).SetInt(balance).Float64()
+		ch <- prometheus.MustNewConstMetric(signerBalanceDesc, prometheus.GaugeValue, value, addr)
+	}
+
+	pending, err := c.client.PendingNonceAt(ctx, c.address)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read signer pending nonce")
+		return
+	}
+	mined, err := c.client.NonceAt(ctx, c.address, nil)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read signer nonce")
+		return
+	}
+	var inFlight uint64
+	if pending > mined {
+		inFlight = pending - mined
+	}
+	ch <- prometheus.MustNewConstMetric(signerPendingDesc, prometheus.GaugeValue, float64(inFlight), addr)
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "invoice"
+
+// Registry holds every collector exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	InvoicesCreated = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invoices_created_total",
+		Help:      "Invoices created, by merchant and chain.",
+	}, []string{"merchant", "chain_id"})
+
+	InvoicesPaid = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invoices_paid_total",
+		Help:      "Invoices marked paid by the watcher, by merchant and chain.",
+	}, []string{"merchant", "chain_id"})
+
+	InvoicesExpired = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invoices_expired_total",
+		Help:      "Invoices expired by the expiry checker, by merchant and chain.",
+	}, []string{"merchant", "chain_id"})
+
+	TransferReviews = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "watcher",
+		Name:      "transfer_reviews_total",
+		Help:      "Transfers to the payment address queued for manual review, by reason.",
+	}, []string{"reason"})
+
+	WatcherHeadLag = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "watcher",
+		Name:      "head_lag_blocks",
+		Help:      "Chain head minus the last block processed by the watcher.",
+	})
+
+	WatcherLastProcessedBlock = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "watcher",
+		Name:      "last_processed_block",
+		Help:      "Last block whose logs were processed by the watcher.",
+	})
+
+	WatcherBatchDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "watcher",
+		Name:      "log_batch_duration_seconds",
+		Help:      "Time to fetch and apply one batch of contract logs.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+	})
+
+	SignerFundsLevel = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "signer",
+		Name:      "funds_level",
+		Help:      "Signer balance level: 0 ok, 1 below warning threshold, 2 below critical threshold.",
+	})
+
+	SignerInvoiceCapacity = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "signer",
+		Name:      "estimated_invoice_capacity",
+		Help:      "Invoices the signer balance can still pay gas for at the current gas price.",
+	})
+
+	SignerGasPerInvoice = factory.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "signer",
+		Name:      "gas_per_invoice",
+		Help:      "Average gas used by recent createInvoice transactions.",
+	})
+
+	InvoicesRejected = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "invoices_rejected_total",
+		Help:      "Invoice creation requests rejected before sending a transaction, by reason.",
+	}, []string{"reason"})
+
+	DepositSweeps = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "deposit",
+		Name:      "sweeps_total",
+		Help:      "Deposit address sweep transactions, by result (sent or failed).",
+	}, []string{"result"})
+
+	Refunds = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refunds_total",
+		Help:      "Refund state changes, by new status (REQUESTED, SENT, CONFIRMED or FAILED).",
+	}, []string{"status"})
+
+	RPCDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "call_duration_seconds",
+		Help:      "Ethereum JSON-RPC call latency by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	RPCErrors = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc",
+		Name:      "errors_total",
+		Help:      "Failed Ethereum JSON-RPC calls by method.",
+	}, []string{"method"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDBStats exposes connection pool statistics of the given database.
+func RegisterDBStats(db *sql.DB, dbName string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
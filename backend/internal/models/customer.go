@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletSource records how a wallet became known for a customer.
+type WalletSource string
+
+const (
+	// WalletManual: added through the customer API.
+	WalletManual WalletSource = "manual"
+	// WalletPayment: the wallet paid one of the customer's invoices.
+	WalletPayment WalletSource = "payment"
+)
+
+// Customer is who an invoice is billed to.
+type Customer struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name           string    `gorm:"not null" json:"name"`
+	Email          string    `gorm:"index" json:"email,omitempty"`
+	BillingAddress string    `json:"billing_address,omitempty"`
+	TaxID          string    `json:"tax_id,omitempty"`
+	// PreferredCurrency is an ISO 4217 code for display, e.g. "EUR".
+	PreferredCurrency string           `gorm:"type:varchar(10)" json:"preferred_currency,omitempty"`
+	Wallets           []CustomerWallet `json:"wallets,omitempty"`
+	CreatedAt         time.Time        `json:"created_at"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+}
+
+// CustomerWallet is an address known to belong to a customer. An address
+// belongs to at most one customer and is stored checksummed.
+type CustomerWallet struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CustomerID uuid.UUID    `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Address    string       `gorm:"type:varchar(42);not null;uniqueIndex" json:"address"`
+	Source     WalletSource `gorm:"type:varchar(10);not null;default:'manual'" json:"source"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
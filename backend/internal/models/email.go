@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailAudience is who an email is written for.
+type EmailAudience string
+
+const (
+	AudienceCustomer EmailAudience = "customer"
+	AudienceMerchant EmailAudience = "merchant"
+)
+
+type EmailStatus string
+
+const (
+	// EmailPending: queued, sent at NextAttemptAt.
+	EmailPending EmailStatus = "PENDING"
+	EmailSent    EmailStatus = "SENT"
+	// EmailFailed: every attempt failed; LastError says why.
+	EmailFailed EmailStatus = "FAILED"
+)
+
+// EmailMessage is a rendered email in the outbox. It is stored before it
+// is sent, so queued emails survive restarts and are retried.
+type EmailMessage struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID *uuid.UUID       `gorm:"type:uuid;index" json:"invoice_id,omitempty"`
+	Kind      NotificationKind `gorm:"type:varchar(20);not null" json:"kind"`
+	Audience  EmailAudience    `gorm:"type:varchar(10);not null" json:"audience"`
+	To        string           `gorm:"not null" json:"to"`
+	Subject   string           `gorm:"not null" json:"subject"`
+	HTML      string           `json:"html"`
+	Text      string           `json:"text"`
+	// UnsubscribeURL is sent as the List-Unsubscribe header.
+	UnsubscribeURL string      `json:"unsubscribe_url,omitempty"`
+	Status         EmailStatus `gorm:"type:varchar(10);not null;default:'PENDING';index" json:"status"`
+	Attempts       int         `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time   `gorm:"not null;index" json:"next_attempt_at"`
+	LastError      string      `json:"last_error,omitempty"`
+	SentAt         *time.Time  `json:"sent_at,omitempty"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// EmailPreference is what one address wants to receive. Its secret Token
+// identifies it in unsubscribe links, so recipients need no account.
+type EmailPreference struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	// Email is lower-case.
+	Email string `gorm:"not null;uniqueIndex" json:"email"`
+	Token string `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	// Unsubscribed stops every email to the address.
+	Unsubscribed bool `gorm:"not null;default:false" json:"unsubscribed"`
+	// Disabled are the kinds the address opted out of.
+	Disabled  []NotificationKind `gorm:"serializer:json" json:"disabled"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// Wants reports whether the address accepts emails of kind.
+func (p *EmailPreference) Wants(kind NotificationKind) bool {
+	if p.Unsubscribed {
+		return false
+	}
+	for _, disabled := range p.Disabled {
+		if disabled == kind {
+			return false
+		}
+	}
+	return true
+}
+
+// EmailTemplate replaces the default email of one kind for a merchant's
+// customers or the merchant. Subject and Text are Go text templates, HTML
+// an html/template, all executed with the same invoice data.
+type EmailTemplate struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	// MerchantAddress is lower-case, like MerchantProfile's.
+	MerchantAddress string           `gorm:"type:varchar(42);not null;uniqueIndex:idx_email_template" json:"merchant_address"`
+	Kind            NotificationKind `gorm:"type:varchar(20);not null;uniqueIndex:idx_email_template" json:"kind"`
+	Audience        EmailAudience    `gorm:"type:varchar(10);not null;uniqueIndex:idx_email_template" json:"audience"`
+	Subject         string           `gorm:"not null" json:"subject"`
+	HTML            string           `gorm:"not null" json:"html"`
+	Text            string           `gorm:"not null" json:"text"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,26 +11,106 @@ type InvoiceStatus string
 
 const (
 	StatusPending InvoiceStatus = "PENDING"
-	StatusPaid    InvoiceStatus = "PAID"
-	StatusExpired InvoiceStatus = "EXPIRED"
+	// StatusPartiallyPaid: a partial invoice received less than its amount
+	// (minus tolerance) so far.
+	StatusPartiallyPaid InvoiceStatus = "PARTIALLY_PAID"
+	StatusPaid          InvoiceStatus = "PAID"
+	StatusExpired       InvoiceStatus = "EXPIRED"
+	// StatusPartiallyRefunded: confirmed refunds left the merchant with less
+	// than the invoice amount, but not nothing.
+	StatusPartiallyRefunded InvoiceStatus = "PARTIALLY_REFUNDED"
+	// StatusRefunded: everything paid has been refunded.
+	StatusRefunded InvoiceStatus = "REFUNDED"
+)
+
+// Settled reports whether an invoice in this status was paid in full or
+// refunded; it takes no further payments toward its amount.
+func (s InvoiceStatus) Settled() bool {
+	switch s {
+	case StatusPaid, StatusPartiallyRefunded, StatusRefunded:
+		return true
+	}
+	return false
+}
+
+// InvoiceMode is how an invoice is made payable on-chain.
+type InvoiceMode string
+
+const (
+	// ModeOnchain invoices are created by an owner createInvoice
+	// transaction and paid with payInvoice(onchain ID).
+	ModeOnchain InvoiceMode = "onchain"
+	// ModeSigned invoices are EIP-712 signed by the owner off-chain and
+	// paid with paySignedInvoice; they are identified by InvoiceHash.
+	ModeSigned InvoiceMode = "signed"
+	// ModeTransfer invoices are paid by a plain transfer of their exact,
+	// unique amount to PaymentAddress.
+	ModeTransfer InvoiceMode = "transfer"
+	// ModeDeposit invoices are paid by transfers to their own HD-derived
+	// DepositAddress, which is later swept to the merchant.
+	ModeDeposit InvoiceMode = "deposit"
 )
 
 type Invoice struct {
 	ID               uuid.UUID     `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Mode             InvoiceMode   `gorm:"type:varchar(10);not null;default:'onchain'" json:"mode"`
+	InvoiceNumber    *string       `gorm:"type:varchar(64)" json:"invoice_number,omitempty"`
 	OnchainInvoiceID string        `gorm:"index" json:"onchain_invoice_id,omitempty"` // uint256 as string, populated later by watcher
 	MerchantAddress  string        `gorm:"not null" json:"merchant_address"`
+	CustomerID       *uuid.UUID    `gorm:"type:uuid;index" json:"customer_id,omitempty"`
+	PaymentLinkID    *uuid.UUID    `gorm:"type:uuid;index" json:"payment_link_id,omitempty"`
+	SubscriptionID   *uuid.UUID    `gorm:"type:uuid;index" json:"subscription_id,omitempty"`
+	ReissueOfID      *uuid.UUID    `gorm:"type:uuid;uniqueIndex" json:"reissue_of_id,omitempty"` // Expired invoice this one replaces
+	ReissueCount     int           `gorm:"not null;default:0" json:"reissue_count,omitempty"`
+	PriceCurrency    *string       `gorm:"type:varchar(10)" json:"price_currency,omitempty"` // Fiat price the amount was converted from
+	PriceAmount      *string       `json:"price_amount,omitempty"`
 	AmountWei        string        `gorm:"not null" json:"amount_wei"` // big.Int as string
 	AmountETH        string        `gorm:"-" json:"amount_eth"`        // Computed field for display
 	Status           InvoiceStatus `gorm:"type:varchar(20);default:'PENDING'" json:"status"`
 	ExpiresAt        time.Time     `gorm:"not null" json:"expires_at"`
-	PaymentAddress   string        `gorm:"-" json:"-"` // Deprecated, kept ignored or removed
+	PaymentAddress   string        `gorm:"type:varchar(42)" json:"payment_address,omitempty"` // Transfer invoices only
 	ContractAddress  string        `gorm:"-" json:"contract_address"`
-	TxHash           *string       `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`
+	PaymentURI       string        `gorm:"-" json:"payment_uri,omitempty"`                           // EIP-681 request while the invoice can be paid
+	CreationTxHash   *string       `gorm:"type:varchar(66);index" json:"creation_tx_hash,omitempty"` // createInvoice transaction, on-chain invoices only
+	TxHash           *string       `gorm:"type:varchar(66)" json:"tx_hash,omitempty"`                // Payment that settled the invoice
 	PayerAddress     *string       `gorm:"type:varchar(42)" json:"payer_address,omitempty"`
+	InvoiceHash      *string       `gorm:"type:varchar(66);uniqueIndex" json:"invoice_hash,omitempty"` // EIP-712 hash, signed invoices only
+	Salt             *string       `gorm:"type:varchar(66)" json:"salt,omitempty"`
+	Signature        *string       `gorm:"type:varchar(132)" json:"signature,omitempty"`
+	DepositAddress   *string       `gorm:"type:varchar(42);uniqueIndex" json:"deposit_address,omitempty"` // Deposit invoices only
+	DepositIndex     *int64        `gorm:"uniqueIndex" json:"-"`                                          // BIP-32 child index of DepositAddress
+	SweepTxHash      *string       `gorm:"type:varchar(66)" json:"sweep_tx_hash,omitempty"`               // Latest sweep of DepositAddress
+	AllowPartial     bool          `gorm:"not null;default:false" json:"allow_partial"`
+	AmountPaidWei    string        `gorm:"not null;default:'0'" json:"amount_paid_wei"` // Sum of Payments
+	RefundedWei      string        `gorm:"not null;default:'0'" json:"refunded_wei"`    // Sum of confirmed Refunds
+	SubtotalWei      *string       `json:"subtotal_wei,omitempty"`                      // Itemized invoices only, like the fields below
+	DiscountWei      *string       `json:"discount_wei,omitempty"`
+	TaxWei           *string       `json:"tax_wei,omitempty"`
+	ShippingWei      *string       `json:"shipping_wei,omitempty"`
+	ContentHash      *string       `gorm:"type:varchar(66)" json:"content_hash,omitempty"` // Hash of the itemized content, signed or committed on-chain
+	SuccessURL       *string       `json:"success_url,omitempty"`                          // Hosted checkout redirects, overriding the merchant's
+	ExpiredURL       *string       `json:"expired_url,omitempty"`
+	LineItems        []LineItem    `json:"line_items,omitempty"`
+	Payments         []Payment     `json:"payments,omitempty"`
+	Overpayments     []Overpayment `json:"overpayments,omitempty"`
+	Refunds          []Refund      `json:"refunds,omitempty"`
 	CreatedAt        time.Time     `json:"created_at"`
 	UpdatedAt        time.Time     `json:"updated_at"`
 }
 
+// PaidWei is the total received for the invoice. Invoices settled before
+// payments were recorded count as paid in full.
+func (i *Invoice) PaidWei() *big.Int {
+	paid, ok := new(big.Int).SetString(i.AmountPaidWei, 10)
+	if !ok {
+		paid = new(big.Int)
+	}
+	if paid.Sign() == 0 && i.Status == StatusPaid {
+		paid.SetString(i.AmountWei, 10)
+	}
+	return paid
+}
+
 type AppState struct {
 	ID                 uint   `gorm:"primaryKey" json:"id"`
 	LastProcessedBlock uint64 `json:"last_processed_block"`
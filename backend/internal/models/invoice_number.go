@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// InvoiceNumberSequence numbers one merchant's invoices without gaps. The
+// number is allocated in the transaction that stores the invoice, so a
+// failed insert never uses one up.
+type InvoiceNumberSequence struct {
+	// MerchantAddress is lower-case, so differently cased addresses share a
+	// sequence.
+	MerchantAddress string `gorm:"type:varchar(42);primary_key" json:"merchant_address"`
+	Prefix          string `gorm:"type:varchar(20);not null;default:''" json:"prefix"`
+	Padding         int    `gorm:"not null" json:"padding"`
+	YearlyReset     bool   `gorm:"not null" json:"yearly_reset"`
+	// Year is the year LastNumber was allocated in; with YearlyReset the
+	// first invoice of a new year starts over at 1.
+	Year       int       `gorm:"not null" json:"year"`
+	LastNumber int64     `gorm:"not null;default:0" json:"last_number"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Next takes the number after LastNumber in year, starting over at 1 in a
+// new year with YearlyReset, and returns it formatted.
+func (s *InvoiceNumberSequence) Next(year int) string {
+	if s.YearlyReset && s.Year != year {
+		s.LastNumber = 0
+	}
+	s.Year = year
+	s.LastNumber++
+	return s.Format(s.Year, s.LastNumber)
+}
+
+// Format renders number n, e.g. "ACME-2026-000123" or, without a yearly
+// reset, "ACME-000123".
+func (s *InvoiceNumberSequence) Format(year int, n int64) string {
+	number := fmt.Sprintf("%0*d", s.Padding, n)
+	if s.YearlyReset {
+		number = fmt.Sprintf("%d-%s", year, number)
+	}
+	if s.Prefix != "" {
+		number = s.Prefix + "-" + number
+	}
+	return number
+}
@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+func TestInvoiceNumberSequenceFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		seq  InvoiceNumberSequence
+		n    int64
+		want string
+	}{
+		{"prefix and year", InvoiceNumberSequence{Prefix: "ACME", Padding: 6, YearlyReset: true}, 123, "ACME-2026-000123"},
+		{"prefix only", InvoiceNumberSequence{Prefix: "ACME", Padding: 6}, 123, "ACME-000123"},
+		{"year only", InvoiceNumberSequence{Padding: 4, YearlyReset: true}, 7, "2026-0007"},
+		{"no padding", InvoiceNumberSequence{}, 42, "42"},
+		{"number wider than padding", InvoiceNumberSequence{Padding: 2}, 12345, "12345"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.seq.Format(2026, tt.n); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceNumberSequenceNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		seq   InvoiceNumberSequence
+		years []int
+		want  []string
+	}{
+		{
+			name:  "numbers without gaps",
+			seq:   InvoiceNumberSequence{Prefix: "INV", Padding: 3},
+			years: []int{2026, 2026, 2026},
+			want:  []string{"INV-001", "INV-002", "INV-003"},
+		},
+		{
+			name:  "continues from the stored counter",
+			seq:   InvoiceNumberSequence{Padding: 3, Year: 2026, LastNumber: 41},
+			years: []int{2026},
+			want:  []string{"042"},
+		},
+		{
+			name:  "yearly reset starts over",
+			seq:   InvoiceNumberSequence{Padding: 3, YearlyReset: true, Year: 2025, LastNumber: 99},
+			years: []int{2025, 2026, 2026},
+			want:  []string{"2025-100", "2026-001", "2026-002"},
+		},
+		{
+			name:  "no reset keeps counting across years",
+			seq:   InvoiceNumberSequence{Padding: 3, Year: 2025, LastNumber: 99},
+			years: []int{2026},
+			want:  []string{"100"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := tt.seq
+			for i, year := range tt.years {
+				if got := seq.Next(year); got != tt.want[i] {
+					t.Errorf("Next(%d) #%d = %q, want %q", year, i+1, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "github.com/google/uuid"
+
+// LineItem is one billed line of an itemized invoice. The wei amounts are
+// computed by the service when the invoice is created.
+type LineItem struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID   uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	Position    int       `gorm:"not null" json:"position"`
+	Description string    `gorm:"not null" json:"description"`
+	// Quantity is a decimal string, e.g. "1.5".
+	Quantity     string `gorm:"not null" json:"quantity"`
+	UnitPriceWei string `gorm:"not null" json:"unit_price_wei"`
+	TaxRateBps   int    `gorm:"not null;default:0" json:"tax_rate_bps"`
+	// SubtotalWei is quantity times unit price, DiscountWei this line's
+	// share of the invoice discount, and TaxWei the tax on what remains.
+	SubtotalWei string `gorm:"not null" json:"subtotal_wei"`
+	DiscountWei string `gorm:"not null;default:'0'" json:"discount_wei"`
+	TaxWei      string `gorm:"not null;default:'0'" json:"tax_wei"`
+	TotalWei    string `gorm:"not null" json:"total_wei"`
+}
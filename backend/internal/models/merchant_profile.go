@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// DocumentTemplate is the layout of a merchant's invoice and receipt PDFs.
+type DocumentTemplate string
+
+const (
+	// TemplateClassic has a colored header band with the logo.
+	TemplateClassic DocumentTemplate = "classic"
+	// TemplateMinimal is black on white with an accent-colored rule.
+	TemplateMinimal DocumentTemplate = "minimal"
+)
+
+// MerchantProfile holds the details and branding shown on a merchant's
+// documents and hosted checkout. Merchants without one get plain pages
+// showing only their address.
+type MerchantProfile struct {
+	// MerchantAddress is lower-case, like InvoiceNumberSequence's.
+	MerchantAddress string `gorm:"type:varchar(42);primary_key" json:"merchant_address"`
+	Name            string `json:"name,omitempty"`
+	// PostalAddress may span several lines.
+	PostalAddress string `json:"postal_address,omitempty"`
+	Email         string `json:"email,omitempty"`
+	Website       string `json:"website,omitempty"`
+	TaxID         string `json:"tax_id,omitempty"`
+	// Logo is a PNG or JPEG image, base64 in JSON.
+	Logo        []byte           `json:"logo,omitempty"`
+	AccentColor string           `gorm:"type:varchar(7)" json:"accent_color,omitempty"` // "#RRGGBB"
+	Template    DocumentTemplate `gorm:"type:varchar(20);not null;default:'classic'" json:"template"`
+	FooterText  string           `json:"footer_text,omitempty"`
+	// Currency is the ISO 4217 code amounts are also shown in, unless the
+	// customer prefers another.
+	Currency string `gorm:"type:varchar(10)" json:"currency,omitempty"`
+	// SuccessURL and ExpiredURL are where the hosted checkout sends the
+	// payer once an invoice is paid or has expired, unless the invoice has
+	// its own.
+	SuccessURL string    `json:"success_url,omitempty"`
+	ExpiredURL string    `json:"expired_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
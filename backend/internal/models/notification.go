@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationKind is what an invoice notification is about.
+type NotificationKind string
+
+const (
+	NotifyCreated NotificationKind = "created"
+	NotifyPaid    NotificationKind = "paid"
+	NotifyExpired NotificationKind = "expired"
+	// NotifyReminder: the invoice expires in OffsetSeconds and is not paid.
+	NotifyReminder NotificationKind = "reminder"
+	// NotifyReissued: the invoice replaces one that expired unpaid.
+	NotifyReissued NotificationKind = "reissued"
+)
+
+type NotificationStatus string
+
+const (
+	// NotificationSending: claimed by the scheduler; a crash leaves it here
+	// rather than sending twice.
+	NotificationSending NotificationStatus = "SENDING"
+	NotificationSent    NotificationStatus = "SENT"
+	NotificationFailed  NotificationStatus = "FAILED"
+)
+
+// InvoiceNotification records one notification about an invoice on one
+// channel. The unique index makes each reminder go out at most once.
+type InvoiceNotification struct {
+	ID        uuid.UUID        `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_invoice_notification" json:"invoice_id"`
+	Kind      NotificationKind `gorm:"type:varchar(20);not null;uniqueIndex:idx_invoice_notification" json:"kind"`
+	// OffsetSeconds is how long before expiry a reminder was due; zero for
+	// other kinds.
+	OffsetSeconds int64              `gorm:"not null;default:0;uniqueIndex:idx_invoice_notification" json:"offset_seconds"`
+	Channel       string             `gorm:"type:varchar(20);not null;uniqueIndex:idx_invoice_notification" json:"channel"`
+	Status        NotificationStatus `gorm:"type:varchar(10);not null" json:"status"`
+	Error         string             `json:"error,omitempty"`
+	SentAt        *time.Time         `json:"sent_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
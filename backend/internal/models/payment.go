@@ -0,0 +1,75 @@
+package models
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NativeToken is the Token of payments in the chain's native currency.
+const NativeToken = "ETH"
+
+// transferLogIndex is the synthetic log index of a transaction's own value
+// transfer. Native transfers emit no log, so their payments are keyed by an
+// index above any real one.
+const transferLogIndex = 1 << 31
+
+// TransferLogIndex is the synthetic log index of a native transfer, by its
+// trace path: empty for the transaction's own value, else the call index
+// path of an internal transfer, whose hash is added to the index.
+func TransferLogIndex(tracePath string) uint {
+	if tracePath == "" {
+		return transferLogIndex
+	}
+	h := fnv.New32a()
+	h.Write([]byte(tracePath))
+	return transferLogIndex + 1 + uint(h.Sum32()>>2)
+}
+
+// Payment is one payment towards an invoice: a contract payment event, a
+// native transfer to the payment or deposit address, or deposit balance no
+// transfer accounts for. Partial and deposit invoices may have several.
+type Payment struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID uuid.UUID `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	// ChainID, TxHash and LogIndex identify the event, so replaying a block
+	// range never records a payment twice. Native transfers, which emit no
+	// log, use a synthetic LogIndex of 2^31 and above.
+	ChainID     int64      `gorm:"not null;uniqueIndex:idx_payment_chain_event" json:"chain_id"`
+	TxHash      string     `gorm:"type:varchar(66);not null;uniqueIndex:idx_payment_chain_event" json:"tx_hash"`
+	LogIndex    uint       `gorm:"not null;uniqueIndex:idx_payment_chain_event" json:"log_index"`
+	BlockNumber uint64     `gorm:"not null" json:"block_number"`
+	BlockHash   string     `gorm:"type:varchar(66)" json:"block_hash,omitempty"`
+	BlockTime   *time.Time `json:"block_time,omitempty"`
+	// Token is NativeToken or the address of the ERC-20 paid with.
+	Token        string `gorm:"type:varchar(42);not null;default:'ETH'" json:"token"`
+	PayerAddress string `gorm:"type:varchar(42);not null" json:"payer_address"`
+	AmountWei    string `gorm:"not null" json:"amount_wei"`
+	// GasPaidWei is the fee of the payment transaction, paid by its sender.
+	GasPaidWei string    `json:"gas_paid_wei,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type OverpaymentStatus string
+
+const (
+	// OverpaymentOpen: the excess is owed back to the payer.
+	OverpaymentOpen OverpaymentStatus = "OPEN"
+	// OverpaymentRefunded: the excess has been returned.
+	OverpaymentRefunded OverpaymentStatus = "REFUNDED"
+)
+
+// Overpayment records the part of a payment beyond the invoice amount, or a
+// whole payment made after the invoice was already settled. It is eligible
+// for a refund to PayerAddress.
+type Overpayment struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID    uuid.UUID         `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	PaymentID    uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex" json:"payment_id"`
+	PayerAddress string            `gorm:"type:varchar(42);not null" json:"payer_address"`
+	AmountWei    string            `gorm:"not null" json:"amount_wei"`
+	Status       OverpaymentStatus `gorm:"type:varchar(20);not null;default:'OPEN';index" json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentLink is a reusable URL that creates a new invoice each time it is
+// opened, e.g. for donations or a product listing.
+type PaymentLink struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	MerchantAddress string    `gorm:"type:varchar(42);not null;index" json:"merchant_address"`
+	Title           string    `json:"title,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	// Currency is "ETH" or an ISO 4217 code; fiat amounts are converted at
+	// the rate when an invoice is created.
+	Currency string `gorm:"type:varchar(10);not null" json:"currency"`
+	// Amount is a decimal in Currency. Without one the payer chooses,
+	// within MinAmount and MaxAmount if set.
+	Amount    string      `json:"amount,omitempty"`
+	MinAmount string      `json:"min_amount,omitempty"`
+	MaxAmount string      `json:"max_amount,omitempty"`
+	Mode      InvoiceMode `gorm:"type:varchar(10)" json:"mode,omitempty"` // Empty for the configured default
+	// ExpiryMinutes is how long each invoice stays payable; zero for the
+	// configured default.
+	ExpiryMinutes int `gorm:"not null;default:0" json:"expiry_minutes,omitempty"`
+	// MaxUses caps the invoices the link creates; nil for no limit.
+	MaxUses *int `json:"max_uses,omitempty"`
+	Uses    int  `gorm:"not null;default:0" json:"uses"`
+	// ExpiresAt is when the link stops working; nil for never.
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Disabled   bool       `gorm:"not null;default:false" json:"disabled"`
+	SuccessURL string     `json:"success_url,omitempty"` // Passed on to the invoices, like ExpiredURL
+	ExpiredURL string     `json:"expired_url,omitempty"`
+	// Metadata is the merchant's own data, e.g. a product SKU.
+	Metadata  map[string]string `gorm:"serializer:json" json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// PaymentLinkStats summarizes the invoices a payment link created.
+type PaymentLinkStats struct {
+	Uses    int `json:"uses"`
+	Pending int `json:"pending"`
+	Paid    int `json:"paid"` // Including partially or fully refunded
+	Expired int `json:"expired"`
+	// PaidWei is the total received, before refunds.
+	PaidWei    string     `json:"paid_wei"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
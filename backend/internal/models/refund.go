@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefundMethod is how a refund transaction gets signed.
+type RefundMethod string
+
+const (
+	// RefundBySigner: the backend signer sends the refund from its wallet.
+	RefundBySigner RefundMethod = "signer"
+	// RefundByMerchant: the backend returns a payment request for the
+	// merchant to sign; the merchant then reports the transaction hash.
+	RefundByMerchant RefundMethod = "merchant"
+)
+
+type RefundStatus string
+
+const (
+	// RefundRequested: recorded, no transaction known yet.
+	RefundRequested RefundStatus = "REQUESTED"
+	// RefundSent: a transaction was sent and is awaiting confirmation.
+	RefundSent RefundStatus = "SENT"
+	// RefundConfirmed: the transaction succeeded and is confirmed.
+	RefundConfirmed RefundStatus = "CONFIRMED"
+	// RefundFailed: sending failed or the transaction reverted; the amount
+	// is refundable again.
+	RefundFailed RefundStatus = "FAILED"
+)
+
+// Refund returns all or part of an invoice's payments to the payer, or to
+// an override address.
+type Refund struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	InvoiceID uuid.UUID    `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	AmountWei string       `gorm:"not null" json:"amount_wei"`
+	ToAddress string       `gorm:"type:varchar(42);not null" json:"to_address"`
+	Method    RefundMethod `gorm:"type:varchar(10);not null" json:"method"`
+	Status    RefundStatus `gorm:"type:varchar(20);not null;default:'REQUESTED';index" json:"status"`
+	// OverpaymentID is set when the refund returns a recorded overpayment.
+	OverpaymentID *uuid.UUID `gorm:"type:uuid" json:"overpayment_id,omitempty"`
+	TxHash        *string    `gorm:"type:varchar(66);uniqueIndex" json:"tx_hash,omitempty"`
+	// PaymentRequest is an EIP-681 URI for merchant-signed refunds.
+	PaymentRequest string     `json:"payment_request,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	ConfirmedAt    *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
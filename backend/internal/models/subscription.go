@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BillingInterval is the unit of a plan's billing period.
+type BillingInterval string
+
+const (
+	IntervalDay   BillingInterval = "day"
+	IntervalWeek  BillingInterval = "week"
+	IntervalMonth BillingInterval = "month"
+	IntervalYear  BillingInterval = "year"
+)
+
+// SubscriptionPlan is what a subscription is billed each period.
+type SubscriptionPlan struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	MerchantAddress string    `gorm:"type:varchar(42);not null;index" json:"merchant_address"`
+	Name            string    `gorm:"not null" json:"name"`
+	Description     string    `json:"description,omitempty"`
+	// Currency is "ETH" or an ISO 4217 code; fiat amounts are converted at
+	// the rate when each invoice is issued.
+	Currency string `gorm:"type:varchar(10);not null" json:"currency"`
+	Amount   string `gorm:"not null" json:"amount"` // Decimal in Currency
+	// Interval and IntervalCount make the period, e.g. every 3 months.
+	Interval      BillingInterval `gorm:"type:varchar(10);not null" json:"interval"`
+	IntervalCount int             `gorm:"not null;default:1" json:"interval_count"`
+	// AnchorDay is the day of the month (1-31, clamped to short months)
+	// that monthly and yearly plans bill on; zero bills on the day each
+	// subscription starts.
+	AnchorDay int         `gorm:"not null;default:0" json:"anchor_day,omitempty"`
+	TrialDays int         `gorm:"not null;default:0" json:"trial_days,omitempty"`
+	Mode      InvoiceMode `gorm:"type:varchar(10)" json:"mode,omitempty"` // Empty for the configured default
+	// ExpiryMinutes is how long each invoice stays payable; zero for the
+	// configured default.
+	ExpiryMinutes int `gorm:"not null;default:0" json:"expiry_minutes,omitempty"`
+	// Archived plans keep billing their subscriptions but take no new ones.
+	Archived  bool      `gorm:"not null;default:false" json:"archived"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SubscriptionStatus is where a subscription is in its lifecycle.
+type SubscriptionStatus string
+
+const (
+	// SubscriptionTrialing subscriptions are not billed until TrialEndsAt.
+	SubscriptionTrialing SubscriptionStatus = "trialing"
+	SubscriptionActive   SubscriptionStatus = "active"
+	// SubscriptionPastDue subscriptions had their latest invoice expire
+	// unpaid; the period is invoiced again at NextRetryAt.
+	SubscriptionPastDue SubscriptionStatus = "past_due"
+	// SubscriptionUnpaid subscriptions ran out of retries and are not
+	// billed again until resumed.
+	SubscriptionUnpaid   SubscriptionStatus = "unpaid"
+	SubscriptionPaused   SubscriptionStatus = "paused"
+	SubscriptionCanceled SubscriptionStatus = "canceled"
+)
+
+// Subscription bills a customer for a plan every period.
+type Subscription struct {
+	ID         uuid.UUID          `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PlanID     uuid.UUID          `gorm:"type:uuid;not null;index" json:"plan_id"`
+	Plan       *SubscriptionPlan  `json:"plan,omitempty"`
+	CustomerID uuid.UUID          `gorm:"type:uuid;not null;index" json:"customer_id"`
+	Status     SubscriptionStatus `gorm:"type:varchar(10);not null;index" json:"status"`
+	// AnchorDay is the plan's, or the start day without one.
+	AnchorDay          int        `gorm:"not null;default:0" json:"anchor_day,omitempty"`
+	TrialEndsAt        *time.Time `json:"trial_ends_at,omitempty"`
+	CurrentPeriodStart *time.Time `json:"current_period_start,omitempty"`
+	CurrentPeriodEnd   *time.Time `json:"current_period_end,omitempty"`
+	// NextInvoiceAt is when the next period starts and is invoiced.
+	NextInvoiceAt   time.Time  `gorm:"not null;index" json:"next_invoice_at"`
+	LatestInvoiceID *uuid.UUID `gorm:"type:uuid" json:"latest_invoice_id,omitempty"`
+	LatestInvoice   *Invoice   `json:"latest_invoice,omitempty"`
+	// RetryCount is how often the current period was invoiced again after
+	// an invoice expired unpaid.
+	RetryCount        int               `gorm:"not null;default:0" json:"retry_count"`
+	NextRetryAt       *time.Time        `json:"next_retry_at,omitempty"`
+	CancelAtPeriodEnd bool              `gorm:"not null;default:false" json:"cancel_at_period_end"`
+	PausedAt          *time.Time        `json:"paused_at,omitempty"`
+	CanceledAt        *time.Time        `json:"canceled_at,omitempty"`
+	Metadata          map[string]string `gorm:"serializer:json" json:"metadata,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	UpdatedAt         time.Time         `json:"updated_at"`
+}
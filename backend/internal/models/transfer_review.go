@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReviewReason string
+
+const (
+	// ReviewUnmatched: no invoice has the transferred amount.
+	ReviewUnmatched ReviewReason = "UNMATCHED"
+	// ReviewLate: the amount matches an invoice that already expired.
+	ReviewLate ReviewReason = "LATE"
+	// ReviewDuplicate: the amount matches an invoice already paid by
+	// another transfer.
+	ReviewDuplicate ReviewReason = "DUPLICATE"
+	// ReviewAmbiguous: the amount matches several non-pending invoices.
+	ReviewAmbiguous ReviewReason = "AMBIGUOUS"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewOpen      ReviewStatus = "OPEN"
+	ReviewResolved  ReviewStatus = "RESOLVED"
+	ReviewDismissed ReviewStatus = "DISMISSED"
+)
+
+// TransferReview is a transfer to a payment address that the watcher could
+// not attribute to exactly one pending invoice. An operator either resolves
+// it against an invoice, which marks that invoice paid, or dismisses it.
+type TransferReview struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	// TxHash and TracePath identify the transfer; TracePath is empty for a
+	// top-level transaction and the call index path (e.g. "0.1") for an
+	// internal transfer.
+	TxHash      string       `gorm:"type:varchar(66);not null;uniqueIndex:idx_transfer_review_transfer" json:"tx_hash"`
+	TracePath   string       `gorm:"type:varchar(64);not null;default:'';uniqueIndex:idx_transfer_review_transfer" json:"trace_path,omitempty"`
+	BlockNumber uint64       `gorm:"not null" json:"block_number"`
+	FromAddress string       `gorm:"type:varchar(42);not null" json:"from_address"`
+	ToAddress   string       `gorm:"type:varchar(42);not null" json:"to_address"`
+	AmountWei   string       `gorm:"not null" json:"amount_wei"`
+	Reason      ReviewReason `gorm:"type:varchar(20);not null" json:"reason"`
+	Status      ReviewStatus `gorm:"type:varchar(20);not null;default:'OPEN';index" json:"status"`
+	// InvoiceID is the candidate invoice (for LATE and DUPLICATE) or the
+	// invoice the transfer was resolved against.
+	InvoiceID  *uuid.UUID `gorm:"type:uuid" json:"invoice_id,omitempty"`
+	Note       string     `json:"note,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
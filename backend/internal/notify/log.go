@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+)
+
+// LogChannel writes events to the log, for development and for forwarding
+// by a log pipeline.
+type LogChannel struct{}
+
+func NewLogChannel() *LogChannel {
+	return &LogChannel{}
+}
+
+func (LogChannel) Name() string {
+	return "log"
+}
+
+func (LogChannel) Send(ctx context.Context, event Event) error {
+	entry := logging.From(ctx, "notify").WithFields(logrus.Fields{
+		"kind":                 event.Kind,
+		logging.FieldInvoiceID: event.Invoice.ID.String(),
+		"expires_at":           event.Invoice.ExpiresAt,
+		"checkout_path":        event.CheckoutPath(),
+	})
+	if event.Customer != nil {
+		entry = entry.WithField("customer_id", event.Customer.ID.String())
+	}
+	if event.Replaces != nil {
+		entry = entry.WithField("replaces_invoice_id", event.Replaces.ID.String())
+	}
+	entry.Info("Invoice notification")
+	return nil
+}
@@ -0,0 +1,52 @@
+// Package notify delivers invoice notifications, such as payment reminders,
+// over pluggable channels.
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// Event is one notification about an invoice.
+type Event struct {
+	Kind    models.NotificationKind
+	Invoice *models.Invoice
+	// Customer is nil for invoices without one.
+	Customer *models.Customer
+	// ExpiresIn is how long before expiry a reminder was due.
+	ExpiresIn time.Duration
+	// Replaces is the expired invoice a re-issued one replaces.
+	Replaces *models.Invoice
+}
+
+// CheckoutPath is the hosted checkout of the event's invoice, relative to
+// the API's public URL.
+func (e Event) CheckoutPath() string {
+	return "/pay/" + e.Invoice.ID.String()
+}
+
+// Channel sends events somewhere. Send is called once per event; a failed
+// send is recorded, not retried.
+type Channel interface {
+	// Name identifies the channel in the notification records, e.g. "log".
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// NewChannels builds the channels named in REMINDER_CHANNELS, except
+// "email": the email service is that channel, and the server adds it.
+func NewChannels(cfg *config.ReminderConfig) []Channel {
+	var channels []Channel
+	for _, name := range cfg.Channels {
+		switch name {
+		case "log":
+			channels = append(channels, NewLogChannel())
+		case "webhook":
+			channels = append(channels, NewWebhookChannel(cfg.WebhookURL, cfg.WebhookSecret))
+		}
+	}
+	return channels
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+)
+
+// webhookTimeout bounds one delivery, so a slow receiver cannot hold up the
+// scheduler.
+const webhookTimeout = 10 * time.Second
+
+// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body,
+// keyed with REMINDER_WEBHOOK_SECRET.
+const SignatureHeader = "X-Invoice-Signature"
+
+// WebhookChannel POSTs events as JSON to a URL. Any 2xx answer counts as
+// delivered.
+type WebhookChannel struct {
+	url    string
+	secret []byte
+	http   *http.Client
+}
+
+func NewWebhookChannel(url, secret string) *WebhookChannel {
+	return &WebhookChannel{url: url, secret: []byte(secret), http: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (w *WebhookChannel) Name() string {
+	return "webhook"
+}
+
+// webhookPayload is the JSON body of a webhook delivery.
+type webhookPayload struct {
+	Kind              models.NotificationKind `json:"kind"`
+	Invoice           *models.Invoice         `json:"invoice"`
+	Customer          *models.Customer        `json:"customer,omitempty"`
+	ExpiresInSeconds  int64                   `json:"expires_in_seconds,omitempty"`
+	ReplacesInvoiceID string                  `json:"replaces_invoice_id,omitempty"`
+	CheckoutPath      string                  `json:"checkout_path"`
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, event Event) (err error) {
+	ctx, span := telemetry.Start(ctx, "notify.WebhookChannel.Send")
+	defer func() { telemetry.End(span, err) }()
+
+	payload := webhookPayload{
+		Kind:             event.Kind,
+		Invoice:          event.Invoice,
+		Customer:         event.Customer,
+		ExpiresInSeconds: int64(event.ExpiresIn / time.Second),
+		CheckoutPath:     event.CheckoutPath(),
+	}
+	if event.Replaces != nil {
+		payload.ReplacesInvoiceID = event.Replaces.ID.String()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
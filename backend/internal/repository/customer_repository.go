@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrWalletTaken is returned when adding a wallet that already belongs to
+// another customer.
+var ErrWalletTaken = errors.New("wallet belongs to another customer")
+
+// ErrCustomerSubscribed is returned when deleting a customer that still has
+// subscriptions which are not canceled; billing them would fail forever.
+var ErrCustomerSubscribed = errors.New("customer has subscriptions that are not canceled")
+
+type CustomerRepository interface {
+	Create(ctx context.Context, customer *models.Customer) error
+	// FindByID loads a customer with its wallets.
+	FindByID(ctx context.Context, id string) (*models.Customer, error)
+	FindByWallet(ctx context.Context, address string) (*models.Customer, error)
+	List(ctx context.Context, limit int, offset int) ([]models.Customer, error)
+	// Update saves the profile fields; wallets are managed separately.
+	Update(ctx context.Context, customer *models.Customer) error
+	// Delete removes the customer and its wallets and unlinks its invoices.
+	// Customers with subscriptions that are not canceled are kept.
+	Delete(ctx context.Context, id string) error
+	// AddWallet links an address to the customer. Adding an address the
+	// customer already has is a no-op.
+	AddWallet(ctx context.Context, customerID uuid.UUID, address string, source models.WalletSource) error
+	RemoveWallet(ctx context.Context, customerID string, address string) error
+	// ListInvoices returns the customer's invoices, newest first.
+	ListInvoices(ctx context.Context, customerID string) ([]models.Invoice, error)
+}
+
+type customerRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerRepository(db *gorm.DB) CustomerRepository {
+	return &customerRepository{db: db}
+}
+
+func (r *customerRepository) Create(ctx context.Context, customer *models.Customer) error {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.Create")
+	// Associations are upserted by default, which would silently skip a
+	// wallet owned by another customer
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Wallets").Create(customer).Error; err != nil {
+			return err
+		}
+		for i := range customer.Wallets {
+			customer.Wallets[i].CustomerID = customer.ID
+			if err := tx.Create(&customer.Wallets[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		err = ErrWalletTaken
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *customerRepository) FindByID(ctx context.Context, id string) (*models.Customer, error) {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.FindByID")
+	var customer models.Customer
+	err := r.db.WithContext(ctx).
+		Preload("Wallets", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).First(&customer).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *customerRepository) FindByWallet(ctx context.Context, address string) (*models.Customer, error) {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.FindByWallet")
+	var wallet models.CustomerWallet
+	err := r.db.WithContext(ctx).Where("address = ?", address).First(&wallet).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, wallet.CustomerID.String())
+}
+
+func (r *customerRepository) List(ctx context.Context, limit int, offset int) ([]models.Customer, error) {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.List")
+	var customers []models.Customer
+	err := r.db.WithContext(ctx).Preload("Wallets").
+		Order("created_at ASC").Limit(limit).Offset(offset).
+		Find(&customers).Error
+	telemetry.End(span, err)
+	return customers, err
+}
+
+func (r *customerRepository) Update(ctx context.Context, customer *models.Customer) error {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.Update")
+	res := r.db.WithContext(ctx).Model(customer).Select(
+		"name", "email", "billing_address", "tax_id", "preferred_currency",
+	).Updates(customer)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *customerRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.Delete")
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var customer models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&customer).Error; err != nil {
+			return err
+		}
+		var subscribed int64
+		err := tx.Model(&models.Subscription{}).
+			Where("customer_id = ? AND status <> ?", id, models.SubscriptionCanceled).
+			Count(&subscribed).Error
+		if err != nil {
+			return err
+		}
+		if subscribed > 0 {
+			return ErrCustomerSubscribed
+		}
+
+		if err := tx.Model(&models.Invoice{}).Where("customer_id = ?", id).Update("customer_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("customer_id = ?", id).Delete(&models.CustomerWallet{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("id = ?", id).Delete(&models.Customer{})
+		if res.Error == nil && res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return res.Error
+	})
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *customerRepository) AddWallet(ctx context.Context, customerID uuid.UUID, address string, source models.WalletSource) (err error) {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.AddWallet")
+	defer func() { telemetry.End(span, err) }()
+
+	wallet := &models.CustomerWallet{CustomerID: customerID, Address: address, Source: source}
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "address"}}, DoNothing: true}).Create(wallet)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+
+	var existing models.CustomerWallet
+	if err := r.db.WithContext(ctx).Where("address = ?", address).First(&existing).Error; err != nil {
+		return err
+	}
+	if existing.CustomerID != customerID {
+		return ErrWalletTaken
+	}
+	return nil
+}
+
+func (r *customerRepository) RemoveWallet(ctx context.Context, customerID string, address string) error {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.RemoveWallet")
+	res := r.db.WithContext(ctx).Where("customer_id = ? AND address = ?", customerID, address).Delete(&models.CustomerWallet{})
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *customerRepository) ListInvoices(ctx context.Context, customerID string) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "CustomerRepository.ListInvoices")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).Where("customer_id = ?", customerID).Order("created_at DESC").Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestDeleteSubscribedCustomer(t *testing.T) {
+	tx := testDB(t)
+	repo := NewCustomerRepository(tx)
+	ctx := context.Background()
+
+	customer := &models.Customer{Name: "Ada"}
+	if err := repo.Create(ctx, customer); err != nil {
+		t.Fatal(err)
+	}
+	plan := &models.SubscriptionPlan{
+		MerchantAddress: "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+		Name:            "Monthly",
+		Currency:        "ETH",
+		Amount:          "0.1",
+		Interval:        models.IntervalMonth,
+		IntervalCount:   1,
+	}
+	if err := tx.Create(plan).Error; err != nil {
+		t.Fatal(err)
+	}
+	subscription := &models.Subscription{
+		PlanID:        plan.ID,
+		CustomerID:    customer.ID,
+		Status:        models.SubscriptionActive,
+		NextInvoiceAt: time.Now().Add(time.Hour),
+	}
+	if err := tx.Create(subscription).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Delete(ctx, customer.ID.String()); !errors.Is(err, ErrCustomerSubscribed) {
+		t.Fatalf("Delete() with an active subscription = %v, want ErrCustomerSubscribed", err)
+	}
+	if _, err := repo.FindByID(ctx, customer.ID.String()); err != nil {
+		t.Fatalf("customer gone after refused delete: %v", err)
+	}
+
+	if err := tx.Model(subscription).Update("status", models.SubscriptionCanceled).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, customer.ID.String()); err != nil {
+		t.Fatalf("Delete() with a canceled subscription: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, customer.ID.String()); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("FindByID() after delete = %v, want ErrRecordNotFound", err)
+	}
+}
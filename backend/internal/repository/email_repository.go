@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailChannel is the channel name email notifications are recorded
+// under.
+const EmailChannel = "email"
+
+type EmailRepository interface {
+	// Enqueue adds a message to the outbox.
+	Enqueue(ctx context.Context, msg *models.EmailMessage) error
+	// FindDue returns up to limit pending messages due at now, oldest
+	// first.
+	FindDue(ctx context.Context, now time.Time, limit int) ([]models.EmailMessage, error)
+	FindMessage(ctx context.Context, id string) (*models.EmailMessage, error)
+	// SaveAttempt saves the delivery state of a message.
+	SaveAttempt(ctx context.Context, msg *models.EmailMessage) error
+	// ListOutbox returns messages newest first, filtered by status when
+	// not empty.
+	ListOutbox(ctx context.Context, status models.EmailStatus, limit int, offset int) ([]models.EmailMessage, error)
+	// FindEventDue returns the invoices, with their line items, that had
+	// the event after since and were not emailed about it yet: created,
+	// paid or expired.
+	FindEventDue(ctx context.Context, kind models.NotificationKind, since time.Time) ([]models.Invoice, error)
+
+	// Preference returns the address's preferences, creating them with a
+	// fresh token on first use.
+	Preference(ctx context.Context, email string) (*models.EmailPreference, error)
+	FindPreferenceByToken(ctx context.Context, token string) (*models.EmailPreference, error)
+	SavePreference(ctx context.Context, pref *models.EmailPreference) error
+
+	FindTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) (*models.EmailTemplate, error)
+	// ListTemplates returns the merchant's templates by kind and audience.
+	ListTemplates(ctx context.Context, merchant string) ([]models.EmailTemplate, error)
+	// SaveTemplate creates or replaces the merchant's template.
+	SaveTemplate(ctx context.Context, t *models.EmailTemplate) error
+	DeleteTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) error
+}
+
+type emailRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &emailRepository{db: db}
+}
+
+func (r *emailRepository) Enqueue(ctx context.Context, msg *models.EmailMessage) error {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.Enqueue")
+	err := r.db.WithContext(ctx).Create(msg).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *emailRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]models.EmailMessage, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.FindDue")
+	var messages []models.EmailMessage
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", models.EmailPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	telemetry.End(span, err)
+	return messages, err
+}
+
+func (r *emailRepository) FindMessage(ctx context.Context, id string) (*models.EmailMessage, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.FindMessage")
+	var msg models.EmailMessage
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&msg).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (r *emailRepository) SaveAttempt(ctx context.Context, msg *models.EmailMessage) error {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.SaveAttempt")
+	res := r.db.WithContext(ctx).Model(msg).
+		Select("status", "attempts", "next_attempt_at", "last_error", "sent_at", "updated_at").
+		Updates(msg)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *emailRepository) ListOutbox(ctx context.Context, status models.EmailStatus, limit int, offset int) ([]models.EmailMessage, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.ListOutbox")
+	query := r.db.WithContext(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var messages []models.EmailMessage
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&messages).Error
+	telemetry.End(span, err)
+	return messages, err
+}
+
+func (r *emailRepository) FindEventDue(ctx context.Context, kind models.NotificationKind, since time.Time) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.FindEventDue")
+	query := r.db.WithContext(ctx).Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") })
+	switch kind {
+	case models.NotifyCreated:
+		query = query.Where("created_at > ?", since)
+	case models.NotifyPaid:
+		// Refunds move a paid invoice on; it was still paid first
+		query = query.Where("status IN ? AND updated_at > ?",
+			[]models.InvoiceStatus{models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded}, since)
+	case models.NotifyExpired:
+		query = query.Where("status = ? AND updated_at > ?", models.StatusExpired, since)
+	default:
+		telemetry.End(span, nil)
+		return nil, nil
+	}
+	var invoices []models.Invoice
+	err := query.
+		Where(`NOT EXISTS (SELECT 1 FROM invoice_notification n
+			WHERE n.invoice_id = invoice.id AND n.kind = ? AND n.channel = ?)`, kind, EmailChannel).
+		Order("created_at ASC").
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+func (r *emailRepository) Preference(ctx context.Context, email string) (*models.EmailPreference, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.Preference")
+	email = strings.ToLower(email)
+	var token [32]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		telemetry.End(span, err)
+		return nil, err
+	}
+	pref := models.EmailPreference{Email: email, Token: hex.EncodeToString(token[:])}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&pref).Error
+	if err == nil {
+		pref = models.EmailPreference{}
+		err = r.db.WithContext(ctx).Where("email = ?", email).First(&pref).Error
+	}
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+func (r *emailRepository) FindPreferenceByToken(ctx context.Context, token string) (*models.EmailPreference, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.FindPreferenceByToken")
+	var pref models.EmailPreference
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&pref).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &pref, nil
+}
+
+func (r *emailRepository) SavePreference(ctx context.Context, pref *models.EmailPreference) error {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.SavePreference")
+	res := r.db.WithContext(ctx).Model(pref).Select("unsubscribed", "disabled", "updated_at").Updates(pref)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *emailRepository) FindTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) (*models.EmailTemplate, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.FindTemplate")
+	var t models.EmailTemplate
+	err := r.db.WithContext(ctx).
+		Where("merchant_address = ? AND kind = ? AND audience = ?", strings.ToLower(merchant), kind, audience).
+		First(&t).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *emailRepository) ListTemplates(ctx context.Context, merchant string) ([]models.EmailTemplate, error) {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.ListTemplates")
+	var templates []models.EmailTemplate
+	err := r.db.WithContext(ctx).Where("merchant_address = ?", strings.ToLower(merchant)).
+		Order("kind ASC, audience ASC").Find(&templates).Error
+	telemetry.End(span, err)
+	return templates, err
+}
+
+func (r *emailRepository) SaveTemplate(ctx context.Context, t *models.EmailTemplate) error {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.SaveTemplate")
+	t.MerchantAddress = strings.ToLower(t.MerchantAddress)
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "merchant_address"}, {Name: "kind"}, {Name: "audience"}},
+			DoUpdates: clause.AssignmentColumns([]string{"subject", "html", "text", "updated_at"}),
+		},
+		clause.Returning{},
+	).Create(t).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *emailRepository) DeleteTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) error {
+	ctx, span := telemetry.Start(ctx, "EmailRepository.DeleteTemplate")
+	res := r.db.WithContext(ctx).
+		Where("merchant_address = ? AND kind = ? AND audience = ?", strings.ToLower(merchant), kind, audience).
+		Delete(&models.EmailTemplate{})
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
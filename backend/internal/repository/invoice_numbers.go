@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxNumberSearchResults caps SearchByNumber.
+const maxNumberSearchResults = 100
+
+// allocateNumber takes the next number of the invoice's merchant sequence
+// inside tx. The sequence row stays locked until tx ends, so concurrent
+// invoices of one merchant are numbered one after the other, and a rolled
+// back insert leaves no gap.
+func allocateNumber(tx *gorm.DB, invoice *models.Invoice, template *models.InvoiceNumberSequence) error {
+	merchant := strings.ToLower(invoice.MerchantAddress)
+	year := time.Now().UTC().Year()
+
+	seq := *template
+	seq.MerchantAddress = merchant
+	seq.Year = year
+	seq.LastNumber = 0
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seq).Error; err != nil {
+		return err
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("merchant_address = ?", merchant).First(&seq).Error; err != nil {
+		return err
+	}
+
+	number := seq.Next(year)
+	err := tx.Model(&seq).Updates(map[string]interface{}{"year": seq.Year, "last_number": seq.LastNumber}).Error
+	if err != nil {
+		return err
+	}
+	invoice.InvoiceNumber = &number
+	return nil
+}
+
+func (r *invoiceRepository) FindNumberSequence(ctx context.Context, merchant string) (*models.InvoiceNumberSequence, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindNumberSequence")
+	var seq models.InvoiceNumberSequence
+	err := r.db.WithContext(ctx).Where("merchant_address = ?", strings.ToLower(merchant)).First(&seq).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &seq, nil
+}
+
+// SaveNumberSequence creates the merchant's sequence or changes its
+// format. The counter is kept, so numbering continues where it was.
+func (r *invoiceRepository) SaveNumberSequence(ctx context.Context, seq *models.InvoiceNumberSequence) error {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.SaveNumberSequence")
+	seq.MerchantAddress = strings.ToLower(seq.MerchantAddress)
+	if seq.Year == 0 {
+		seq.Year = time.Now().UTC().Year()
+	}
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns:   []clause.Column{{Name: "merchant_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"prefix", "padding", "yearly_reset", "updated_at"}),
+		},
+		clause.Returning{},
+	).Create(seq).Error
+	telemetry.End(span, err)
+	return err
+}
+
+// SearchByNumber finds invoices whose number starts with number,
+// optionally only those of one merchant.
+func (r *invoiceRepository) SearchByNumber(ctx context.Context, merchant string, number string) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.SearchByNumber")
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(number)
+	query := r.db.WithContext(ctx).Where("invoice_number LIKE ?", escaped+"%")
+	if merchant != "" {
+		query = query.Where("LOWER(merchant_address) = ?", strings.ToLower(merchant))
+	}
+	var invoices []models.Invoice
+	err := query.Order("invoice_number ASC").Limit(maxNumberSearchResults).Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
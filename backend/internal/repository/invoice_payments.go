@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentOutcome is the invoice state after RecordPayment.
+type PaymentOutcome struct {
+	// Duplicate is set when the payment event was already recorded; nothing
+	// else changed.
+	Duplicate     bool
+	Status        models.InvoiceStatus
+	AmountPaidWei string
+	// Settled is set when this payment moved the invoice to PAID.
+	Settled     bool
+	Overpayment *models.Overpayment
+}
+
+// RecordPayment adds a payment to an invoice and updates its paid total and
+// status atomically. The invoice is PAID once the total reaches its amount
+// minus tolerance, else PARTIALLY_PAID, or still EXPIRED for a payment made
+// in time but seen after expiry. Any excess over the amount, or the
+// whole payment if the invoice was already settled (PAID or refunded), is
+// stored as an Overpayment.
+func (r *invoiceRepository) RecordPayment(ctx context.Context, invoiceID string, payment *models.Payment, tolerance *big.Int) (_ *PaymentOutcome, err error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.RecordPayment")
+	defer func() { telemetry.End(span, err) }()
+
+	var outcome *PaymentOutcome
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invoice models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", invoiceID).First(&invoice).Error; err != nil {
+			return err
+		}
+		var err error
+		outcome, err = applyPayment(tx, &invoice, payment, tolerance)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
+
+// applyPayment is RecordPayment within tx, for an invoice locked by the
+// caller.
+func applyPayment(tx *gorm.DB, invoice *models.Invoice, payment *models.Payment, tolerance *big.Int) (*PaymentOutcome, error) {
+	outcome := &PaymentOutcome{}
+	payment.InvoiceID = invoice.ID
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(payment)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		outcome.Duplicate = true
+		outcome.Status = invoice.Status
+		outcome.AmountPaidWei = invoice.AmountPaidWei
+		return outcome, nil
+	}
+
+	amount, ok1 := new(big.Int).SetString(invoice.AmountWei, 10)
+	previous, ok2 := new(big.Int).SetString(invoice.AmountPaidWei, 10)
+	paid, ok3 := new(big.Int).SetString(payment.AmountWei, 10)
+	if !ok1 || !ok2 || !ok3 {
+		return nil, errors.New("invalid wei amount on invoice or payment")
+	}
+	total := new(big.Int).Add(previous, paid)
+
+	var excess *big.Int
+	outcome.Status, outcome.Settled, excess = settle(invoice.Status, amount, total, paid, tolerance)
+	outcome.AmountPaidWei = total.String()
+
+	if excess.Sign() > 0 {
+		outcome.Overpayment = &models.Overpayment{
+			InvoiceID:    invoice.ID,
+			PaymentID:    payment.ID,
+			PayerAddress: payment.PayerAddress,
+			AmountWei:    excess.String(),
+			Status:       models.OverpaymentOpen,
+		}
+		if err := tx.Create(outcome.Overpayment).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{
+		"status":          outcome.Status,
+		"amount_paid_wei": outcome.AmountPaidWei,
+	}
+	if !invoice.Status.Settled() {
+		updates["tx_hash"] = payment.TxHash
+		updates["payer_address"] = payment.PayerAddress
+	}
+	if err := tx.Model(invoice).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return outcome, nil
+}
+
+// settle decides an invoice's status after a payment of paid wei brought its
+// paid total to total, and how much of the payment is excess. A settled
+// invoice keeps its status and the whole payment is excess: the paid total
+// of a refunded invoice still counts the refunded wei, so it must not be
+// paid again.
+func settle(status models.InvoiceStatus, amount, total, paid, tolerance *big.Int) (_ models.InvoiceStatus, settled bool, excess *big.Int) {
+	switch {
+	case status.Settled():
+		return status, false, new(big.Int).Set(paid)
+	case total.Cmp(new(big.Int).Sub(amount, tolerance)) >= 0:
+		excess = new(big.Int)
+		if total.Cmp(amount) > 0 {
+			excess.Sub(total, amount)
+		}
+		return models.StatusPaid, true, excess
+	case status == models.StatusExpired:
+		return models.StatusExpired, false, new(big.Int)
+	default:
+		return models.StatusPartiallyPaid, false, new(big.Int)
+	}
+}
+
+// FindByIDWithPayments loads an invoice with its line items, payments,
+// overpayments and refunds.
+func (r *invoiceRepository) FindByIDWithPayments(ctx context.Context, id string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByIDWithPayments")
+	var invoice models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("block_number ASC, log_index ASC") }).
+		Preload("Overpayments").
+		Preload("Refunds", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).
+		Where("id = ?", id).First(&invoice).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
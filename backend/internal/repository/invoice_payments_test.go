@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+func TestSettle(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        models.InvoiceStatus
+		total, paid   int64
+		tolerance     int64
+		want          models.InvoiceStatus
+		wantSettled   bool
+		wantExcessWei int64
+	}{
+		{"partial", models.StatusPending, 400, 400, 0, models.StatusPartiallyPaid, false, 0},
+		{"completes", models.StatusPartiallyPaid, 1000, 600, 0, models.StatusPaid, true, 0},
+		{"within tolerance", models.StatusPartiallyPaid, 990, 590, 10, models.StatusPaid, true, 0},
+		{"overpays", models.StatusPending, 1200, 1200, 0, models.StatusPaid, true, 200},
+		{"late but in time", models.StatusExpired, 1000, 1000, 0, models.StatusPaid, true, 0},
+		{"expired stays expired", models.StatusExpired, 500, 100, 0, models.StatusExpired, false, 0},
+		{"paid again", models.StatusPaid, 1500, 500, 0, models.StatusPaid, false, 500},
+		{"partially refunded", models.StatusPartiallyRefunded, 1500, 500, 0, models.StatusPartiallyRefunded, false, 500},
+		{"refunded", models.StatusRefunded, 1500, 500, 0, models.StatusRefunded, false, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, settled, excess := settle(tt.status, big.NewInt(1000), big.NewInt(tt.total), big.NewInt(tt.paid), big.NewInt(tt.tolerance))
+			if status != tt.want || settled != tt.wantSettled || excess.Int64() != tt.wantExcessWei {
+				t.Errorf("settle() = %s, %v, %s; want %s, %v, %d", status, settled, excess, tt.want, tt.wantSettled, tt.wantExcessWei)
+			}
+		})
+	}
+}
+
+func TestRecordPaymentOnRefundedInvoice(t *testing.T) {
+	for _, status := range []models.InvoiceStatus{models.StatusPartiallyRefunded, models.StatusRefunded} {
+		t.Run(string(status), func(t *testing.T) {
+			tx := testDB(t)
+			repo := NewInvoiceRepository(tx)
+			ctx := context.Background()
+
+			settledBy := "0x1111111111111111111111111111111111111111111111111111111111111111"
+			payer := "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+			invoice := &models.Invoice{
+				Mode:            models.ModeDeposit,
+				MerchantAddress: "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+				AmountWei:       "1000",
+				AmountPaidWei:   "1000",
+				RefundedWei:     "400",
+				Status:          status,
+				ExpiresAt:       time.Now().Add(time.Hour),
+				TxHash:          &settledBy,
+				PayerAddress:    &payer,
+			}
+			if err := tx.Create(invoice).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			outcome, err := repo.RecordPayment(ctx, invoice.ID.String(), &models.Payment{
+				ChainID:      1,
+				TxHash:       "0x2222222222222222222222222222222222222222222222222222222222222222",
+				LogIndex:     models.TransferLogIndex(""),
+				BlockNumber:  100,
+				PayerAddress: "0x90F79bf6EB2c4f870365E785982E1f101E93b906",
+				AmountWei:    "1000",
+			}, new(big.Int))
+			if err != nil {
+				t.Fatalf("RecordPayment: %v", err)
+			}
+			if outcome.Status != status || outcome.Settled || outcome.AmountPaidWei != "2000" {
+				t.Errorf("outcome = %+v, want status %s, not settled, 2000 paid", outcome, status)
+			}
+			if outcome.Overpayment == nil || outcome.Overpayment.AmountWei != "1000" {
+				t.Errorf("overpayment = %+v, want the whole payment", outcome.Overpayment)
+			}
+
+			var stored models.Invoice
+			if err := tx.First(&stored, "id = ?", invoice.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != status || *stored.TxHash != settledBy || *stored.PayerAddress != payer {
+				t.Errorf("stored invoice = %s %s %s, want %s with its settling payment", stored.Status, *stored.TxHash, *stored.PayerAddress, status)
+			}
+		})
+	}
+}
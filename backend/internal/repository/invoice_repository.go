@@ -1,21 +1,40 @@
 package repository
 
 import (
+	"context"
+	"math/big"
 	"time"
 
 	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceRepository interface {
-	Create(invoice *models.Invoice) error
-	FindByID(id string) (*models.Invoice, error)
-	FindByOnchainID(onchainID string) (*models.Invoice, error)
-	FindByTxHash(txHash string) (*models.Invoice, error)
-	UpdateStatus(id string, status models.InvoiceStatus, txHash string, payer string) error
-	UpdateOnchainID(id string, onchainID string) error
-	FindPending() ([]models.Invoice, error)
-	UpdateExpired(now time.Time) error
+	// Create stores the invoice with the next number of its merchant's
+	// sequence. numbering is the format of the merchant's first sequence.
+	Create(ctx context.Context, invoice *models.Invoice, numbering *models.InvoiceNumberSequence) error
+	FindByID(ctx context.Context, id string) (*models.Invoice, error)
+	FindByOnchainID(ctx context.Context, onchainID string) (*models.Invoice, error)
+	FindByCreationTxHash(ctx context.Context, txHash string) (*models.Invoice, error)
+	FindByInvoiceHash(ctx context.Context, invoiceHash string) (*models.Invoice, error)
+	UpdateOnchainID(ctx context.Context, id string, onchainID string) error
+	FindPending(ctx context.Context) ([]models.Invoice, error)
+	UpdateExpired(ctx context.Context, now time.Time) ([]models.Invoice, error)
+	FindPendingTransferAmounts(ctx context.Context, paymentAddress string, amounts []string) ([]string, error)
+	FindTransferByAmount(ctx context.Context, paymentAddress string, amountWei string) ([]models.Invoice, error)
+	NextDepositIndex(ctx context.Context) (uint32, error)
+	FindByDepositAddress(ctx context.Context, address string) (*models.Invoice, error)
+	FindDepositsSince(ctx context.Context, since time.Time) ([]models.Invoice, error)
+	FindSweepableDeposits(ctx context.Context, since time.Time) ([]models.Invoice, error)
+	UpdateSweepTx(ctx context.Context, id string, txHash string) error
+	RecordPayment(ctx context.Context, invoiceID string, payment *models.Payment, tolerance *big.Int) (*PaymentOutcome, error)
+	FindByIDWithPayments(ctx context.Context, id string) (*models.Invoice, error)
+	FindNumberSequence(ctx context.Context, merchant string) (*models.InvoiceNumberSequence, error)
+	SaveNumberSequence(ctx context.Context, seq *models.InvoiceNumberSequence) error
+	SearchByNumber(ctx context.Context, merchant string, number string) ([]models.Invoice, error)
 }
 
 type invoiceRepository struct {
@@ -26,57 +45,152 @@ func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
 	return &invoiceRepository{db: db}
 }
 
-func (r *invoiceRepository) Create(invoice *models.Invoice) error {
-	return r.db.Create(invoice).Error
+func (r *invoiceRepository) Create(ctx context.Context, invoice *models.Invoice, numbering *models.InvoiceNumberSequence) error {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.Create")
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := allocateNumber(tx, invoice, numbering); err != nil {
+			return err
+		}
+		return tx.Create(invoice).Error
+	})
+	if err != nil {
+		invoice.InvoiceNumber = nil
+	}
+	telemetry.End(span, err)
+	return err
 }
 
-func (r *invoiceRepository) FindByID(id string) (*models.Invoice, error) {
-	var invoice models.Invoice
-	if err := r.db.Where("id = ?", id).First(&invoice).Error; err != nil {
-		return nil, err
-	}
-	return &invoice, nil
+func (r *invoiceRepository) FindByID(ctx context.Context, id string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByID")
+	return r.findOne(ctx, span, "id = ?", id)
 }
 
-func (r *invoiceRepository) FindByOnchainID(onchainID string) (*models.Invoice, error) {
-	var invoice models.Invoice
-	if err := r.db.Where("onchain_invoice_id = ?", onchainID).First(&invoice).Error; err != nil {
-		return nil, err
-	}
-	return &invoice, nil
+func (r *invoiceRepository) FindByOnchainID(ctx context.Context, onchainID string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByOnchainID")
+	return r.findOne(ctx, span, "onchain_invoice_id = ?", onchainID)
 }
 
-func (r *invoiceRepository) FindByTxHash(txHash string) (*models.Invoice, error) {
-	var invoice models.Invoice
-	if err := r.db.Where("tx_hash = ?", txHash).First(&invoice).Error; err != nil {
-		return nil, err
-	}
-	return &invoice, nil
+func (r *invoiceRepository) FindByCreationTxHash(ctx context.Context, txHash string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByCreationTxHash")
+	return r.findOne(ctx, span, "creation_tx_hash = ?", txHash)
 }
 
-func (r *invoiceRepository) UpdateStatus(id string, status models.InvoiceStatus, txHash string, payer string) error {
-	updates := map[string]interface{}{"status": status}
-	if txHash != "" {
-		updates["tx_hash"] = txHash
-	}
-	if payer != "" {
-		updates["payer_address"] = payer
-	}
-	return r.db.Model(&models.Invoice{}).Where("id = ?", id).Updates(updates).Error
+func (r *invoiceRepository) FindByInvoiceHash(ctx context.Context, invoiceHash string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByInvoiceHash")
+	return r.findOne(ctx, span, "invoice_hash = ?", invoiceHash)
 }
 
-func (r *invoiceRepository) UpdateOnchainID(id string, onchainID string) error {
-	return r.db.Model(&models.Invoice{}).Where("id = ?", id).Update("onchain_invoice_id", onchainID).Error
+func (r *invoiceRepository) UpdateOnchainID(ctx context.Context, id string, onchainID string) error {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.UpdateOnchainID")
+	err := r.db.WithContext(ctx).Model(&models.Invoice{}).Where("id = ?", id).Update("onchain_invoice_id", onchainID).Error
+	telemetry.End(span, err)
+	return err
 }
 
-func (r *invoiceRepository) FindPending() ([]models.Invoice, error) {
+func (r *invoiceRepository) FindPending(ctx context.Context) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindPending")
 	var invoices []models.Invoice
-	err := r.db.Where("status = ?", models.StatusPending).Find(&invoices).Error
+	err := r.db.WithContext(ctx).Where("status = ?", models.StatusPending).Find(&invoices).Error
+	telemetry.End(span, err)
 	return invoices, err
 }
 
-func (r *invoiceRepository) UpdateExpired(now time.Time) error {
-	return r.db.Model(&models.Invoice{}).
-		Where("status = ? AND expires_at < ?", models.StatusPending, now).
+// UpdateExpired marks overdue pending and partially paid invoices as expired
+// and returns them. Payments of partially paid ones stay refundable.
+func (r *invoiceRepository) UpdateExpired(ctx context.Context, now time.Time) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.UpdateExpired")
+	var expired []models.Invoice
+	err := r.db.WithContext(ctx).Model(&expired).
+		Clauses(clause.Returning{}).
+		Where("status IN ? AND expires_at < ?", []models.InvoiceStatus{models.StatusPending, models.StatusPartiallyPaid}, now).
 		Update("status", models.StatusExpired).Error
+	telemetry.End(span, err)
+	return expired, err
+}
+
+// FindPendingTransferAmounts returns which of the candidate amounts are
+// already taken by pending transfer invoices to paymentAddress.
+func (r *invoiceRepository) FindPendingTransferAmounts(ctx context.Context, paymentAddress string, amounts []string) ([]string, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindPendingTransferAmounts")
+	var taken []string
+	err := r.db.WithContext(ctx).Model(&models.Invoice{}).
+		Where("mode = ? AND status = ? AND payment_address = ? AND amount_wei IN ?", models.ModeTransfer, models.StatusPending, paymentAddress, amounts).
+		Pluck("amount_wei", &taken).Error
+	telemetry.End(span, err)
+	return taken, err
+}
+
+// FindTransferByAmount returns transfer invoices to paymentAddress with the
+// exact amount, in any status, newest first.
+func (r *invoiceRepository) FindTransferByAmount(ctx context.Context, paymentAddress string, amountWei string) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindTransferByAmount")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("mode = ? AND payment_address = ? AND amount_wei = ?", models.ModeTransfer, paymentAddress, amountWei).
+		Order("created_at DESC").
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+// NextDepositIndex allocates a BIP-32 child index for a deposit address.
+// Indexes are never reused, even if the invoice insert later fails.
+func (r *invoiceRepository) NextDepositIndex(ctx context.Context) (uint32, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.NextDepositIndex")
+	var index int64
+	err := r.db.WithContext(ctx).Raw("SELECT nextval('deposit_index_seq')").Scan(&index).Error
+	telemetry.End(span, err)
+	return uint32(index), err
+}
+
+func (r *invoiceRepository) FindByDepositAddress(ctx context.Context, address string) (*models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindByDepositAddress")
+	return r.findOne(ctx, span, "deposit_address = ?", address)
+}
+
+// FindDepositsSince returns deposit invoices created after since, in any
+// status; their addresses are the ones the watcher still scans.
+func (r *invoiceRepository) FindDepositsSince(ctx context.Context, since time.Time) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindDepositsSince")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("mode = ? AND created_at >= ?", models.ModeDeposit, since).
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+// FindSweepableDeposits returns deposit invoices created after since whose
+// funds belong to the merchant: paid ones, refunded ones (refunds come from
+// the signer) and expired ones that received part of their amount. They are
+// returned whether swept before or not, as their address may have received
+// more since.
+func (r *invoiceRepository) FindSweepableDeposits(ctx context.Context, since time.Time) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.FindSweepableDeposits")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("mode = ? AND created_at >= ?", models.ModeDeposit, since).
+		Where("status IN ? OR (status = ? AND amount_paid_wei <> '0')",
+			[]models.InvoiceStatus{models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded}, models.StatusExpired).
+		Order("created_at ASC").
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+func (r *invoiceRepository) UpdateSweepTx(ctx context.Context, id string, txHash string) error {
+	ctx, span := telemetry.Start(ctx, "InvoiceRepository.UpdateSweepTx")
+	err := r.db.WithContext(ctx).Model(&models.Invoice{}).Where("id = ?", id).Update("sweep_tx_hash", txHash).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *invoiceRepository) findOne(ctx context.Context, span trace.Span, query string, arg interface{}) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.WithContext(ctx).Where(query, arg).First(&invoice).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
 }
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MerchantRepository interface {
+	FindProfile(ctx context.Context, merchant string) (*models.MerchantProfile, error)
+	// SaveProfile creates or replaces the merchant's profile.
+	SaveProfile(ctx context.Context, profile *models.MerchantProfile) error
+}
+
+type merchantRepository struct {
+	db *gorm.DB
+}
+
+func NewMerchantRepository(db *gorm.DB) MerchantRepository {
+	return &merchantRepository{db: db}
+}
+
+func (r *merchantRepository) FindProfile(ctx context.Context, merchant string) (*models.MerchantProfile, error) {
+	ctx, span := telemetry.Start(ctx, "MerchantRepository.FindProfile")
+	var profile models.MerchantProfile
+	err := r.db.WithContext(ctx).Where("merchant_address = ?", strings.ToLower(merchant)).First(&profile).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *merchantRepository) SaveProfile(ctx context.Context, profile *models.MerchantProfile) error {
+	ctx, span := telemetry.Start(ctx, "MerchantRepository.SaveProfile")
+	profile.MerchantAddress = strings.ToLower(profile.MerchantAddress)
+	err := r.db.WithContext(ctx).Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "merchant_address"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"name", "postal_address", "email", "website", "tax_id", "logo",
+				"accent_color", "template", "footer_text", "currency", "success_url",
+				"expired_url", "updated_at",
+			}),
+		},
+		clause.Returning{},
+	).Create(profile).Error
+	telemetry.End(span, err)
+	return err
+}
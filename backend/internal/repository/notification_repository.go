@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository interface {
+	// FindReminderDue returns the unpaid invoices due a reminder offset
+	// before expiry and not sent one yet. Only invoices expiring within
+	// offset but not within next, the next shorter offset, qualify, so a
+	// late pass sends only the most urgent reminder; so do only invoices
+	// created before the reminder time.
+	FindReminderDue(ctx context.Context, now time.Time, offset time.Duration, next time.Duration) ([]models.Invoice, error)
+	// FindReissuable returns customers' invoices that expired unpaid after
+	// since, with their line items, that have not been re-issued and are
+	// fewer than max re-issues in a row. Subscription invoices are left to
+	// subscription dunning, and partially paid ones to a refund: a new
+	// invoice would bill the full amount again.
+	FindReissuable(ctx context.Context, since time.Time, max int) ([]models.Invoice, error)
+	// Claim records a notification about to be sent. It returns false if
+	// the same notification was claimed before.
+	Claim(ctx context.Context, notification *models.InvoiceNotification) (bool, error)
+	// Finish saves the outcome of a claimed notification.
+	Finish(ctx context.Context, notification *models.InvoiceNotification) error
+	ListByInvoice(ctx context.Context, invoiceID string) ([]models.InvoiceNotification, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) FindReminderDue(ctx context.Context, now time.Time, offset time.Duration, next time.Duration) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "NotificationRepository.FindReminderDue")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Where("status IN ?", []models.InvoiceStatus{models.StatusPending, models.StatusPartiallyPaid}).
+		Where("expires_at > ? AND expires_at <= ?", now.Add(next), now.Add(offset)).
+		Where("EXTRACT(EPOCH FROM expires_at - created_at) >= ?", offset.Seconds()).
+		Where(`NOT EXISTS (SELECT 1 FROM invoice_notification n
+			WHERE n.invoice_id = invoice.id AND n.kind = ? AND n.offset_seconds = ?)`,
+			models.NotifyReminder, int64(offset/time.Second)).
+		Order("expires_at ASC").
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+func (r *notificationRepository) FindReissuable(ctx context.Context, since time.Time, max int) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "NotificationRepository.FindReissuable")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).
+		Preload("LineItems", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Where("status = ? AND expires_at > ? AND amount_paid_wei = '0'", models.StatusExpired, since).
+		Where("customer_id IS NOT NULL AND subscription_id IS NULL AND reissue_count < ?", max).
+		Where("NOT EXISTS (SELECT 1 FROM invoice r WHERE r.reissue_of_id = invoice.id)").
+		Order("expires_at ASC").
+		Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
+
+func (r *notificationRepository) Claim(ctx context.Context, notification *models.InvoiceNotification) (bool, error) {
+	ctx, span := telemetry.Start(ctx, "NotificationRepository.Claim")
+	res := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(notification)
+	telemetry.End(span, res.Error)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *notificationRepository) Finish(ctx context.Context, notification *models.InvoiceNotification) error {
+	ctx, span := telemetry.Start(ctx, "NotificationRepository.Finish")
+	res := r.db.WithContext(ctx).Model(notification).Select("status", "error", "sent_at").Updates(notification)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *notificationRepository) ListByInvoice(ctx context.Context, invoiceID string) ([]models.InvoiceNotification, error) {
+	ctx, span := telemetry.Start(ctx, "NotificationRepository.ListByInvoice")
+	var notifications []models.InvoiceNotification
+	err := r.db.WithContext(ctx).Where("invoice_id = ?", invoiceID).Order("created_at ASC").Find(&notifications).Error
+	telemetry.End(span, err)
+	return notifications, err
+}
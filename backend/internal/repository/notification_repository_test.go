@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+func TestFindReissuableSkipsPartiallyPaid(t *testing.T) {
+	tx := testDB(t)
+	ctx := context.Background()
+
+	customer := &models.Customer{Name: "Ada"}
+	if err := tx.Create(customer).Error; err != nil {
+		t.Fatal(err)
+	}
+	expired := func(paidWei string) *models.Invoice {
+		invoice := &models.Invoice{
+			MerchantAddress: "0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+			CustomerID:      &customer.ID,
+			AmountWei:       "1000",
+			AmountPaidWei:   paidWei,
+			Status:          models.StatusExpired,
+			ExpiresAt:       time.Now().Add(-time.Minute),
+		}
+		if err := tx.Create(invoice).Error; err != nil {
+			t.Fatal(err)
+		}
+		return invoice
+	}
+	unpaid := expired("0")
+	expired("900")
+
+	invoices, err := NewNotificationRepository(tx).FindReissuable(ctx, time.Now().Add(-time.Hour), 1)
+	if err != nil {
+		t.Fatalf("FindReissuable: %v", err)
+	}
+	var ids []uuid.UUID
+	for _, invoice := range invoices {
+		if *invoice.CustomerID == customer.ID {
+			ids = append(ids, invoice.ID)
+		}
+	}
+	if len(ids) != 1 || ids[0] != unpaid.ID {
+		t.Errorf("FindReissuable() = %v, want only the unpaid invoice %s", ids, unpaid.ID)
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+)
+
+// ErrLinkUsedUp is returned when claiming a use of a payment link that has
+// reached its MaxUses.
+var ErrLinkUsedUp = errors.New("payment link has reached its maximum uses")
+
+type PaymentLinkRepository interface {
+	Create(ctx context.Context, link *models.PaymentLink) error
+	FindByID(ctx context.Context, id string) (*models.PaymentLink, error)
+	// List returns links newest first, only the merchant's unless merchant
+	// is empty.
+	List(ctx context.Context, merchant string, limit int, offset int) ([]models.PaymentLink, error)
+	// Update saves the link's settings; Uses is left alone.
+	Update(ctx context.Context, link *models.PaymentLink) error
+	// ClaimUse counts one use of the link, atomically checking MaxUses so
+	// concurrent opens cannot exceed it.
+	ClaimUse(ctx context.Context, id string) error
+	// ReleaseUse gives back a use whose invoice could not be created.
+	ReleaseUse(ctx context.Context, id string) error
+	Stats(ctx context.Context, id string) (*models.PaymentLinkStats, error)
+}
+
+type paymentLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentLinkRepository(db *gorm.DB) PaymentLinkRepository {
+	return &paymentLinkRepository{db: db}
+}
+
+func (r *paymentLinkRepository) Create(ctx context.Context, link *models.PaymentLink) error {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.Create")
+	err := r.db.WithContext(ctx).Create(link).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *paymentLinkRepository) FindByID(ctx context.Context, id string) (*models.PaymentLink, error) {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.FindByID")
+	var link models.PaymentLink
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&link).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *paymentLinkRepository) List(ctx context.Context, merchant string, limit int, offset int) ([]models.PaymentLink, error) {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.List")
+	query := r.db.WithContext(ctx)
+	if merchant != "" {
+		query = query.Where("LOWER(merchant_address) = LOWER(?)", merchant)
+	}
+	var links []models.PaymentLink
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&links).Error
+	telemetry.End(span, err)
+	return links, err
+}
+
+func (r *paymentLinkRepository) Update(ctx context.Context, link *models.PaymentLink) error {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.Update")
+	res := r.db.WithContext(ctx).Model(link).Select(
+		"title", "description", "currency", "amount", "min_amount", "max_amount", "mode",
+		"expiry_minutes", "max_uses", "expires_at", "disabled", "success_url", "expired_url", "metadata",
+	).Updates(link)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *paymentLinkRepository) ClaimUse(ctx context.Context, id string) error {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.ClaimUse")
+	res := r.db.WithContext(ctx).Model(&models.PaymentLink{}).
+		Where("id = ? AND (max_uses IS NULL OR uses < max_uses)", id).
+		Update("uses", gorm.Expr("uses + 1"))
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = ErrLinkUsedUp
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *paymentLinkRepository) ReleaseUse(ctx context.Context, id string) error {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.ReleaseUse")
+	err := r.db.WithContext(ctx).Model(&models.PaymentLink{}).
+		Where("id = ? AND uses > 0", id).
+		Update("uses", gorm.Expr("uses - 1")).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *paymentLinkRepository) Stats(ctx context.Context, id string) (_ *models.PaymentLinkStats, err error) {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkRepository.Stats")
+	defer func() { telemetry.End(span, err) }()
+
+	link, err := r.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Invoices settled before payments were recorded count as paid in
+	// full, like Invoice.PaidWei
+	var rows []struct {
+		Status     models.InvoiceStatus
+		Count      int
+		PaidWei    string
+		LastUsedAt time.Time
+	}
+	err = r.db.WithContext(ctx).Model(&models.Invoice{}).
+		Select(`status, COUNT(*) AS count,
+			COALESCE(SUM((CASE WHEN amount_paid_wei = '0' AND status = ? THEN amount_wei ELSE amount_paid_wei END)::numeric), 0)::text AS paid_wei,
+			MAX(created_at) AS last_used_at`, models.StatusPaid).
+		Where("payment_link_id = ?", id).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.PaymentLinkStats{Uses: link.Uses}
+	paid := new(big.Int)
+	for _, row := range rows {
+		switch row.Status {
+		case models.StatusPending, models.StatusPartiallyPaid:
+			stats.Pending += row.Count
+		case models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded:
+			stats.Paid += row.Count
+		case models.StatusExpired:
+			stats.Expired += row.Count
+		}
+		if amount, ok := new(big.Int).SetString(row.PaidWei, 10); ok {
+			paid.Add(paid, amount)
+		}
+		if stats.LastUsedAt == nil || row.LastUsedAt.After(*stats.LastUsedAt) {
+			lastUsed := row.LastUsedAt
+			stats.LastUsedAt = &lastUsed
+		}
+	}
+	stats.PaidWei = paid.String()
+	return stats, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrRefundStateChanged is returned when a refund is no longer in the state
+// an update expects, e.g. a transaction was already reported for it.
+var ErrRefundStateChanged = errors.New("refund is not in the expected state")
+
+type RefundRepository interface {
+	// Create calls build with the locked invoice, its overpayments and
+	// refunds that have not failed, and the total of those refunds, and
+	// stores the refund build returns.
+	// Holding the lock keeps concurrent requests from refunding more than
+	// was paid.
+	Create(ctx context.Context, invoiceID string, build func(invoice *models.Invoice, committed *big.Int) (*models.Refund, error)) (*models.Refund, error)
+	FindByID(ctx context.Context, id string) (*models.Refund, error)
+	ListByInvoice(ctx context.Context, invoiceID string) ([]models.Refund, error)
+	FindSent(ctx context.Context) ([]models.Refund, error)
+	// MarkSent records the refund transaction of a REQUESTED refund.
+	MarkSent(ctx context.Context, id string, txHash string) error
+	MarkFailed(ctx context.Context, id string, reason string) error
+	// Confirm marks a SENT refund confirmed and updates the invoice's
+	// refunded total and status, and the refunded overpayment if any.
+	Confirm(ctx context.Context, id string) (*models.Invoice, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db: db}
+}
+
+func (r *refundRepository) Create(ctx context.Context, invoiceID string, build func(invoice *models.Invoice, committed *big.Int) (*models.Refund, error)) (_ *models.Refund, err error) {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.Create")
+	defer func() { telemetry.End(span, err) }()
+
+	var refund *models.Refund
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invoice models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", invoiceID).First(&invoice).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&invoice.Overpayments).Error; err != nil {
+			return err
+		}
+		err := tx.Where("invoice_id = ? AND status <> ?", invoice.ID, models.RefundFailed).Find(&invoice.Refunds).Error
+		if err != nil {
+			return err
+		}
+		committed := new(big.Int)
+		for _, existing := range invoice.Refunds {
+			if n, ok := new(big.Int).SetString(existing.AmountWei, 10); ok {
+				committed.Add(committed, n)
+			}
+		}
+
+		refund, err = build(&invoice, committed)
+		if err != nil {
+			return err
+		}
+		refund.InvoiceID = invoice.ID
+		return tx.Create(refund).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (r *refundRepository) FindByID(ctx context.Context, id string) (*models.Refund, error) {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.FindByID")
+	var refund models.Refund
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&refund).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) ListByInvoice(ctx context.Context, invoiceID string) ([]models.Refund, error) {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.ListByInvoice")
+	var refunds []models.Refund
+	err := r.db.WithContext(ctx).Where("invoice_id = ?", invoiceID).Order("created_at ASC").Find(&refunds).Error
+	telemetry.End(span, err)
+	return refunds, err
+}
+
+func (r *refundRepository) FindSent(ctx context.Context) ([]models.Refund, error) {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.FindSent")
+	var refunds []models.Refund
+	err := r.db.WithContext(ctx).Where("status = ?", models.RefundSent).Order("created_at ASC").Find(&refunds).Error
+	telemetry.End(span, err)
+	return refunds, err
+}
+
+func (r *refundRepository) MarkSent(ctx context.Context, id string, txHash string) error {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.MarkSent")
+	res := r.db.WithContext(ctx).Model(&models.Refund{}).
+		Where("id = ? AND status = ?", id, models.RefundRequested).
+		Updates(map[string]interface{}{"status": models.RefundSent, "tx_hash": txHash})
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = ErrRefundStateChanged
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *refundRepository) MarkFailed(ctx context.Context, id string, reason string) error {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.MarkFailed")
+	err := r.db.WithContext(ctx).Model(&models.Refund{}).
+		Where("id = ? AND status IN ?", id, []models.RefundStatus{models.RefundRequested, models.RefundSent}).
+		Updates(map[string]interface{}{"status": models.RefundFailed, "error": reason}).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *refundRepository) Confirm(ctx context.Context, id string) (_ *models.Invoice, err error) {
+	ctx, span := telemetry.Start(ctx, "RefundRepository.Confirm")
+	defer func() { telemetry.End(span, err) }()
+
+	var invoice models.Invoice
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var refund models.Refund
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&refund).Error; err != nil {
+			return err
+		}
+		if refund.Status != models.RefundSent {
+			return ErrRefundStateChanged
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", refund.InvoiceID).First(&invoice).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err := tx.Model(&refund).Updates(map[string]interface{}{"status": models.RefundConfirmed, "confirmed_at": now}).Error
+		if err != nil {
+			return err
+		}
+		if refund.OverpaymentID != nil {
+			err := tx.Model(&models.Overpayment{}).Where("id = ?", *refund.OverpaymentID).
+				Update("status", models.OverpaymentRefunded).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		refunded, _ := new(big.Int).SetString(invoice.RefundedWei, 10)
+		if refunded == nil {
+			refunded = new(big.Int)
+		}
+		amount, _ := new(big.Int).SetString(refund.AmountWei, 10)
+		refunded.Add(refunded, amount)
+
+		// Refunding an overpayment leaves a fully paid invoice as it is, and
+		// an expired one stays expired until all of it was refunded.
+		paid := invoice.PaidWei()
+		kept := new(big.Int).Sub(paid, refunded)
+		invoiceAmount, _ := new(big.Int).SetString(invoice.AmountWei, 10)
+		switch {
+		case kept.Sign() <= 0:
+			invoice.Status = models.StatusRefunded
+		case invoice.Status == models.StatusExpired:
+		case invoiceAmount != nil && kept.Cmp(invoiceAmount) < 0:
+			invoice.Status = models.StatusPartiallyRefunded
+		}
+		invoice.RefundedWei = refunded.String()
+		return tx.Model(&invoice).Updates(map[string]interface{}{
+			"status":       invoice.Status,
+			"refunded_wei": invoice.RefundedWei,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/db"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// testDB opens the Postgres database at TEST_DATABASE_URL, migrated, inside
+// a transaction that is rolled back when the test ends. Tests that need it
+// are skipped without one.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.Migrate(conn); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	tx := conn.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSubscriptionChanged is returned by UpdateIf when the subscription's
+// status changed since it was loaded, e.g. it was paused meanwhile.
+var ErrSubscriptionChanged = errors.New("subscription changed concurrently")
+
+// subscriptionColumns are the columns Update and UpdateIf save.
+var subscriptionColumns = []string{
+	"status", "anchor_day", "trial_ends_at", "current_period_start", "current_period_end",
+	"next_invoice_at", "latest_invoice_id", "retry_count", "next_retry_at",
+	"cancel_at_period_end", "paused_at", "canceled_at", "metadata", "updated_at",
+}
+
+type SubscriptionRepository interface {
+	CreatePlan(ctx context.Context, plan *models.SubscriptionPlan) error
+	FindPlan(ctx context.Context, id string) (*models.SubscriptionPlan, error)
+	// ListPlans returns plans newest first, only the merchant's unless
+	// merchant is empty.
+	ListPlans(ctx context.Context, merchant string, limit int, offset int) ([]models.SubscriptionPlan, error)
+	UpdatePlan(ctx context.Context, plan *models.SubscriptionPlan) error
+
+	Create(ctx context.Context, subscription *models.Subscription) error
+	// FindByID loads a subscription with its plan and latest invoice.
+	FindByID(ctx context.Context, id string) (*models.Subscription, error)
+	// List returns subscriptions newest first, filtered by customer and
+	// status when those are not empty.
+	List(ctx context.Context, customerID string, status models.SubscriptionStatus, limit int, offset int) ([]models.Subscription, error)
+	Update(ctx context.Context, subscription *models.Subscription) error
+	// UpdateIf saves the subscription only if its stored status is still
+	// status, returning ErrSubscriptionChanged otherwise.
+	UpdateIf(ctx context.Context, subscription *models.Subscription, status models.SubscriptionStatus) error
+	// FindDue returns the subscriptions with a period or a retry to
+	// invoice at now, with their plans.
+	FindDue(ctx context.Context, now time.Time) ([]models.Subscription, error)
+	// FindSettled returns the active and past due subscriptions whose
+	// latest invoice was paid or can no longer be, with that invoice.
+	FindSettled(ctx context.Context, now time.Time) ([]models.Subscription, error)
+	// ListInvoices returns the subscription's invoices, newest first.
+	ListInvoices(ctx context.Context, subscriptionID string) ([]models.Invoice, error)
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db: db}
+}
+
+func (r *subscriptionRepository) CreatePlan(ctx context.Context, plan *models.SubscriptionPlan) error {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.CreatePlan")
+	err := r.db.WithContext(ctx).Create(plan).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *subscriptionRepository) FindPlan(ctx context.Context, id string) (*models.SubscriptionPlan, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.FindPlan")
+	var plan models.SubscriptionPlan
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&plan).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+func (r *subscriptionRepository) ListPlans(ctx context.Context, merchant string, limit int, offset int) ([]models.SubscriptionPlan, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.ListPlans")
+	query := r.db.WithContext(ctx)
+	if merchant != "" {
+		query = query.Where("LOWER(merchant_address) = LOWER(?)", merchant)
+	}
+	var plans []models.SubscriptionPlan
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&plans).Error
+	telemetry.End(span, err)
+	return plans, err
+}
+
+func (r *subscriptionRepository) UpdatePlan(ctx context.Context, plan *models.SubscriptionPlan) error {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.UpdatePlan")
+	res := r.db.WithContext(ctx).Model(plan).Select(
+		"name", "description", "currency", "amount", "interval", "interval_count", "anchor_day",
+		"trial_days", "mode", "expiry_minutes", "archived",
+	).Updates(plan)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *subscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) error {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.Create")
+	err := r.db.WithContext(ctx).Omit(clause.Associations).Create(subscription).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *subscriptionRepository) FindByID(ctx context.Context, id string) (*models.Subscription, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.FindByID")
+	var subscription models.Subscription
+	err := r.db.WithContext(ctx).Preload("Plan").Preload("LatestInvoice").
+		Where("id = ?", id).First(&subscription).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *subscriptionRepository) List(ctx context.Context, customerID string, status models.SubscriptionStatus, limit int, offset int) ([]models.Subscription, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.List")
+	query := r.db.WithContext(ctx).Preload("Plan")
+	if customerID != "" {
+		query = query.Where("customer_id = ?", customerID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var subscriptions []models.Subscription
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&subscriptions).Error
+	telemetry.End(span, err)
+	return subscriptions, err
+}
+
+func (r *subscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) error {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.Update")
+	res := r.db.WithContext(ctx).Model(subscription).Omit(clause.Associations).Select(subscriptionColumns).Updates(subscription)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = gorm.ErrRecordNotFound
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *subscriptionRepository) UpdateIf(ctx context.Context, subscription *models.Subscription, status models.SubscriptionStatus) error {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.UpdateIf")
+	res := r.db.WithContext(ctx).Model(subscription).Omit(clause.Associations).
+		Where("status = ?", status).Select(subscriptionColumns).Updates(subscription)
+	err := res.Error
+	if err == nil && res.RowsAffected == 0 {
+		err = ErrSubscriptionChanged
+	}
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *subscriptionRepository) FindDue(ctx context.Context, now time.Time) ([]models.Subscription, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.FindDue")
+	var subscriptions []models.Subscription
+	err := r.db.WithContext(ctx).Preload("Plan").
+		Where("(status IN ? AND next_invoice_at <= ?) OR (status = ? AND next_retry_at <= ?)",
+			[]models.SubscriptionStatus{models.SubscriptionTrialing, models.SubscriptionActive}, now,
+			models.SubscriptionPastDue, now).
+		Order("next_invoice_at ASC").
+		Find(&subscriptions).Error
+	telemetry.End(span, err)
+	return subscriptions, err
+}
+
+func (r *subscriptionRepository) FindSettled(ctx context.Context, now time.Time) ([]models.Subscription, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.FindSettled")
+	// Partially paid invoices count as failed from their expiry, before the
+	// expiry checker gets to them
+	var subscriptions []models.Subscription
+	err := r.db.WithContext(ctx).Preload("LatestInvoice").
+		Joins("JOIN invoice ON invoice.id = subscription.latest_invoice_id").
+		Where("subscription.next_retry_at IS NULL").
+		Where(`(subscription.status IN ? AND (invoice.status = ? OR (invoice.status = ? AND invoice.expires_at < ?)))
+			OR (subscription.status = ? AND invoice.status IN ?)`,
+			[]models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionPastDue},
+			models.StatusExpired, models.StatusPartiallyPaid, now,
+			models.SubscriptionPastDue,
+			[]models.InvoiceStatus{models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded}).
+		Find(&subscriptions).Error
+	telemetry.End(span, err)
+	return subscriptions, err
+}
+
+func (r *subscriptionRepository) ListInvoices(ctx context.Context, subscriptionID string) ([]models.Invoice, error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionRepository.ListInvoices")
+	var invoices []models.Invoice
+	err := r.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("created_at DESC").Find(&invoices).Error
+	telemetry.End(span, err)
+	return invoices, err
+}
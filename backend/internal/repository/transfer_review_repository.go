@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrReviewClosed is returned when resolving or dismissing a review that is
+// no longer open.
+var ErrReviewClosed = errors.New("transfer review is not open")
+
+// ErrInvoiceNotPayable is returned when resolving a review against an
+// invoice that is already paid or refunded, or with a transfer already
+// recorded as a payment.
+var ErrInvoiceNotPayable = errors.New("invoice is already paid")
+
+type TransferReviewRepository interface {
+	// Create records a review unless the same transfer is already queued.
+	Create(ctx context.Context, review *models.TransferReview) error
+	FindByID(ctx context.Context, id string) (*models.TransferReview, error)
+	List(ctx context.Context, status models.ReviewStatus) ([]models.TransferReview, error)
+	// Resolve closes the review and records the transfer as a payment that
+	// settles the invoice, atomically.
+	Resolve(ctx context.Context, id string, invoiceID uuid.UUID, chainID int64, note string) (*models.TransferReview, error)
+	Dismiss(ctx context.Context, id string, note string) (*models.TransferReview, error)
+}
+
+type transferReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferReviewRepository(db *gorm.DB) TransferReviewRepository {
+	return &transferReviewRepository{db: db}
+}
+
+func (r *transferReviewRepository) Create(ctx context.Context, review *models.TransferReview) error {
+	ctx, span := telemetry.Start(ctx, "TransferReviewRepository.Create")
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "tx_hash"}, {Name: "trace_path"}}, DoNothing: true}).
+		Create(review).Error
+	telemetry.End(span, err)
+	return err
+}
+
+func (r *transferReviewRepository) FindByID(ctx context.Context, id string) (*models.TransferReview, error) {
+	ctx, span := telemetry.Start(ctx, "TransferReviewRepository.FindByID")
+	var review models.TransferReview
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&review).Error
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *transferReviewRepository) List(ctx context.Context, status models.ReviewStatus) ([]models.TransferReview, error) {
+	ctx, span := telemetry.Start(ctx, "TransferReviewRepository.List")
+	var reviews []models.TransferReview
+	query := r.db.WithContext(ctx).Order("created_at ASC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&reviews).Error
+	telemetry.End(span, err)
+	return reviews, err
+}
+
+func (r *transferReviewRepository) Resolve(ctx context.Context, id string, invoiceID uuid.UUID, chainID int64, note string) (*models.TransferReview, error) {
+	ctx, span := telemetry.Start(ctx, "TransferReviewRepository.Resolve")
+	var review models.TransferReview
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenReview(tx, id, &review); err != nil {
+			return err
+		}
+
+		var invoice models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", invoiceID).First(&invoice).Error; err != nil {
+			return err
+		}
+		if invoice.Status.Settled() {
+			return ErrInvoiceNotPayable
+		}
+		amount, ok := new(big.Int).SetString(invoice.AmountWei, 10)
+		if !ok {
+			return errors.New("invalid wei amount on invoice")
+		}
+		payment := &models.Payment{
+			ChainID:      chainID,
+			TxHash:       review.TxHash,
+			LogIndex:     models.TransferLogIndex(review.TracePath),
+			BlockNumber:  review.BlockNumber,
+			Token:        models.NativeToken,
+			PayerAddress: review.FromAddress,
+			AmountWei:    review.AmountWei,
+		}
+		// The operator accepts the transfer as settling the invoice, so the
+		// whole amount counts as tolerance.
+		outcome, err := applyPayment(tx, &invoice, payment, amount)
+		if err != nil {
+			return err
+		}
+		if outcome.Duplicate {
+			return ErrInvoiceNotPayable
+		}
+
+		now := time.Now()
+		review.Status = models.ReviewResolved
+		review.InvoiceID = &invoiceID
+		review.Note = note
+		review.ResolvedAt = &now
+		return tx.Save(&review).Error
+	})
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *transferReviewRepository) Dismiss(ctx context.Context, id string, note string) (*models.TransferReview, error) {
+	ctx, span := telemetry.Start(ctx, "TransferReviewRepository.Dismiss")
+	var review models.TransferReview
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenReview(tx, id, &review); err != nil {
+			return err
+		}
+		now := time.Now()
+		review.Status = models.ReviewDismissed
+		review.Note = note
+		review.ResolvedAt = &now
+		return tx.Save(&review).Error
+	})
+	telemetry.End(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+func lockOpenReview(tx *gorm.DB, id string, review *models.TransferReview) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(review).Error; err != nil {
+		return err
+	}
+	if review.Status != models.ReviewOpen {
+		return ErrReviewClosed
+	}
+	return nil
+}
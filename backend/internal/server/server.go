@@ -3,15 +3,23 @@ package server
 import (
 	"context"
 	"net/http"
-	"os"
-	"strings"
+	"slices"
 
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/gin-gonic/gin"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
 	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/deposit"
+	"github.com/user/crypto-invoice-generator/backend/internal/email"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/funds"
 	"github.com/user/crypto-invoice-generator/backend/internal/handler"
+	"github.com/user/crypto-invoice-generator/backend/internal/health"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/notify"
 	"github.com/user/crypto-invoice-generator/backend/internal/repository"
 	"github.com/user/crypto-invoice-generator/backend/internal/service"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
 	"github.com/user/crypto-invoice-generator/backend/internal/watcher"
 	"gorm.io/gorm"
 )
@@ -20,14 +28,26 @@ type Server struct {
 	Cfg     *config.Config
 	Gin     *gin.Engine
 	DB      *gorm.DB
+	Eth     chain.Client
+	Signer  signer.Signer
+	Funds   *funds.Monitor
 	Watcher *watcher.Watcher
+	// Deposits signs deposit address sweeps; nil if no xprv is configured.
+	Deposits *signer.DepositWallet
+	// Mailer sends the email outbox; nil without SMTP_HOST.
+	Mailer email.Sender
 }
 
-func NewServer(cfg *config.Config, router *gin.Engine, db *gorm.DB) *Server {
+func NewServer(cfg *config.Config, router *gin.Engine, db *gorm.DB, eth chain.Client, txSigner signer.Signer, deposits *signer.DepositWallet, mailer email.Sender) *Server {
 	return &Server{
-		Cfg: cfg,
-		Gin: router,
-		DB:  db,
+		Cfg:      cfg,
+		Gin:      router,
+		DB:       db,
+		Eth:      eth,
+		Signer:   txSigner,
+		Funds:    funds.NewMonitor(cfg, eth, txSigner.Address()),
+		Deposits: deposits,
+		Mailer:   mailer,
 	}
 }
 
@@ -35,73 +55,215 @@ func (s *Server) Shutdown(ctx context.Context, srv *http.Server) error {
 	return srv.Shutdown(ctx)
 }
 
-func ConfigRoutesAndSchedulers(s *Server) {
-	s.Gin.Use(HandleOption)
+func ConfigRoutes(s *Server) {
+	s.Gin.Use(HandleOption(s.Cfg.HTTP.AllowedOrigins), metrics.GinMiddleware)
+	s.Gin.GET("/metrics", gin.WrapH(metrics.Handler()))
 
-	// Init Eth Client
-	client, err := ethclient.Dial(s.Cfg.Ethereum.RPCURL)
-	if err != nil {
-		// Log fatal since we need blockchain for everything now
-		panic("Failed to connect to Ethereum RPC: " + err.Error())
-	}
+	checker := health.NewChecker(s.Cfg, s.DB, s.Eth, s.Signer.Address())
+	s.Gin.GET("/healthz", gin.WrapF(checker.Liveness))
+	s.Gin.GET("/readyz", gin.WrapF(checker.Readiness))
+	s.Gin.GET("/debug/status", gin.WrapF(checker.DebugStatus))
 
 	// Setup Layers
 	repo := repository.NewInvoiceRepository(s.DB)
-	svc := service.NewInvoiceService(repo, s.Cfg, client)
+	customerRepo := repository.NewCustomerRepository(s.DB)
+	svc := service.NewInvoiceService(repo, customerRepo, s.Cfg, s.Eth, s.Signer, s.Funds)
 	h := handler.NewInvoiceHandler(svc)
 
-	// Start Watcher (Background)
-	w := watcher.NewWatcher(s.DB, repo, s.Cfg, client)
-	s.Watcher = w
-	w.Start()
-
 	// Setup Router
 	api := s.Gin.Group("/api")
 	{
 		api.POST("/invoices", h.CreateInvoice)
 		api.GET("/invoices/:id", h.GetInvoice)
 	}
+
+	merchantRepo := repository.NewMerchantRepository(s.DB)
+	rates := fiat.NewRates(s.Cfg.Fiat)
+	documents := handler.NewDocumentHandler(service.NewDocumentService(repo, customerRepo, merchantRepo, rates, s.Cfg, s.Eth))
+	{
+		api.GET("/invoices/:id/pdf", documents.InvoicePDF)
+		api.GET("/invoices/:id/receipt.pdf", documents.ReceiptPDF)
+		api.GET("/invoices/:id/qr.png", documents.QRPNG)
+		api.GET("/invoices/:id/qr.svg", documents.QRSVG)
+	}
+
+	// The hosted checkout is a page, not part of the JSON API
+	checkouts := handler.NewCheckoutHandler(service.NewCheckoutService(repo, customerRepo, merchantRepo, rates, s.Cfg))
+	s.Gin.GET("/pay/:id", checkouts.Page)
+
+	reviews := handler.NewTransferReviewHandler(service.NewTransferReviewService(repository.NewTransferReviewRepository(s.DB), repo, s.Cfg.Ethereum.ChainID))
+	admin := api.Group("/admin", handler.RequireAdmin(s.Cfg.HTTP.AdminToken))
+	{
+		admin.GET("/transfer-reviews", reviews.List)
+		admin.POST("/transfer-reviews/:id/resolve", reviews.Resolve)
+		admin.POST("/transfer-reviews/:id/dismiss", reviews.Dismiss)
+	}
+
+	customers := handler.NewCustomerHandler(service.NewCustomerService(customerRepo))
+	{
+		admin.POST("/customers", customers.Create)
+		admin.GET("/customers", customers.List)
+		admin.GET("/customers/:id", customers.Get)
+		admin.PUT("/customers/:id", customers.Update)
+		admin.DELETE("/customers/:id", customers.Delete)
+		admin.GET("/customers/:id/invoices", customers.Invoices)
+		admin.POST("/customers/:id/wallets", customers.AddWallet)
+		admin.DELETE("/customers/:id/wallets/:address", customers.RemoveWallet)
+	}
+
+	numbers := handler.NewInvoiceNumberHandler(service.NewInvoiceNumberService(repo, s.Cfg))
+	{
+		admin.GET("/invoices", numbers.Search)
+		admin.GET("/invoice-numbers/:merchant", numbers.GetSequence)
+		admin.PUT("/invoice-numbers/:merchant", numbers.SaveSequence)
+	}
+
+	merchants := handler.NewMerchantHandler(service.NewMerchantService(merchantRepo))
+	{
+		admin.GET("/merchants/:merchant/profile", merchants.GetProfile)
+		admin.PUT("/merchants/:merchant/profile", merchants.SaveProfile)
+	}
+
+	links := handler.NewPaymentLinkHandler(service.NewPaymentLinkService(repository.NewPaymentLinkRepository(s.DB), merchantRepo, svc, rates, s.Funds, s.Cfg))
+	{
+		admin.POST("/payment-links", links.Create)
+		admin.GET("/payment-links", links.List)
+		admin.GET("/payment-links/:id", links.Get)
+		admin.PUT("/payment-links/:id", links.Update)
+		admin.GET("/payment-links/:id/stats", links.Stats)
+	}
+	// Opening a link shows its page; posting it creates an invoice and
+	// redirects to its checkout, which may send a transaction, so it is
+	// rate limited per client
+	s.Gin.GET("/link/:id", links.Show)
+	s.Gin.POST("/link/:id", handler.RateLimit(s.Cfg.HTTP.LinkOpensPerMinute), links.Open)
+
+	// Billing runs in the schedulers; these only manage plans and subscriptions
+	subscriptions := handler.NewSubscriptionHandler(service.NewSubscriptionService(repository.NewSubscriptionRepository(s.DB), customerRepo, svc, rates, s.Cfg))
+	{
+		admin.POST("/subscription-plans", subscriptions.CreatePlan)
+		admin.GET("/subscription-plans", subscriptions.ListPlans)
+		admin.GET("/subscription-plans/:id", subscriptions.GetPlan)
+		admin.PUT("/subscription-plans/:id", subscriptions.UpdatePlan)
+		admin.POST("/subscriptions", subscriptions.Create)
+		admin.GET("/subscriptions", subscriptions.List)
+		admin.GET("/subscriptions/:id", subscriptions.Get)
+		admin.GET("/subscriptions/:id/invoices", subscriptions.Invoices)
+		admin.POST("/subscriptions/:id/pause", subscriptions.Pause)
+		admin.POST("/subscriptions/:id/resume", subscriptions.Resume)
+		admin.POST("/subscriptions/:id/cancel", subscriptions.Cancel)
+	}
+
+	// Reminders are sent by the schedulers; this only shows what was sent
+	reminders := handler.NewReminderHandler(service.NewReminderService(repository.NewNotificationRepository(s.DB), customerRepo, svc, rates, nil, s.Cfg))
+	admin.GET("/invoices/:id/notifications", reminders.Notifications)
+
+	// Emails are queued and sent by the schedulers; these manage templates,
+	// the outbox and preferences
+	emails := handler.NewEmailHandler(service.NewEmailService(repository.NewEmailRepository(s.DB),
+		repository.NewNotificationRepository(s.DB), repo, customerRepo, merchantRepo, nil, s.Cfg))
+	{
+		admin.GET("/email/outbox", emails.Outbox)
+		admin.POST("/email/outbox/:id/retry", emails.Retry)
+		admin.GET("/email/preferences/:email", emails.GetPreferences)
+		admin.PUT("/email/preferences/:email", emails.SavePreferences)
+		admin.GET("/merchants/:merchant/email-templates", emails.ListTemplates)
+		admin.GET("/merchants/:merchant/email-templates/:kind/:audience", emails.GetTemplate)
+		admin.PUT("/merchants/:merchant/email-templates/:kind/:audience", emails.SaveTemplate)
+		admin.DELETE("/merchants/:merchant/email-templates/:kind/:audience", emails.DeleteTemplate)
+	}
+	// Recipients reach their preferences from the links in their emails
+	s.Gin.GET("/email/preferences/:token", emails.PreferencesPage)
+	s.Gin.POST("/email/preferences/:token", emails.SavePreferencesPage)
+
+	// Refunds move funds, so they sit behind the admin token as well
+	refundRepo := repository.NewRefundRepository(s.DB)
+	refunds := handler.NewRefundHandler(service.NewRefundService(refundRepo, repo, s.Cfg, s.Eth, s.Signer))
+	{
+		admin.POST("/invoices/:id/refunds", refunds.Create)
+		admin.GET("/invoices/:id/refunds", refunds.List)
+		admin.POST("/invoices/:id/refunds/:refund_id/tx", refunds.SubmitTx)
+	}
 }
 
-// HandleOption sets security headers and CORS options
-func HandleOption(c *gin.Context) {
-	allowedOriginsStr := os.Getenv("ALLOWED_ORIGINS")
-	requestOrigin := c.Request.Header.Get("Origin")
-
-	// By default, block
-	allowOrigin := ""
-
-	// Special case: allow any origin by echoing back
-	if allowedOriginsStr == "*" {
-		allowOrigin = requestOrigin
-	} else {
-		allowedOrigins := make(map[string]bool)
-		for _, origin := range strings.Split(allowedOriginsStr, ",") {
-			allowedOrigins[strings.TrimSpace(origin)] = true
-		}
-		if allowedOrigins[requestOrigin] {
-			allowOrigin = requestOrigin
+// NewSchedulers builds the background jobs: the payment watcher, the
+// expiry checker, the refund tracker, subscription billing, payment
+// reminders and the email outbox when configured and, with deposit
+// addresses, the sweeper. They must only run while holding the leader lock
+// so that a single instance processes chain events and bills each
+// subscription once.
+func NewSchedulers(s *Server) *lifecycle.Group {
+	repo := repository.NewInvoiceRepository(s.DB)
+	customerRepo := repository.NewCustomerRepository(s.DB)
+	w := watcher.NewWatcher(s.DB, repo, repository.NewTransferReviewRepository(s.DB), customerRepo, s.Cfg, s.Eth)
+	s.Watcher = w
+	invoices := service.NewInvoiceService(repo, customerRepo, s.Cfg, s.Eth, s.Signer, s.Funds)
+	rates := fiat.NewRates(s.Cfg.Fiat)
+	subscriptions := service.NewSubscriptionService(repository.NewSubscriptionRepository(s.DB), customerRepo, invoices, rates, s.Cfg)
+	jobs := []lifecycle.Component{
+		w,
+		watcher.NewExpiryChecker(repo, s.Cfg.Ethereum.ChainID),
+		watcher.NewRefundTracker(s.Cfg, repository.NewRefundRepository(s.DB), s.Eth),
+		watcher.NewSubscriptionBiller(s.Cfg, subscriptions),
+	}
+	notifications := repository.NewNotificationRepository(s.DB)
+	channels := notify.NewChannels(s.Cfg.Reminder)
+	if s.Mailer != nil {
+		emails := service.NewEmailService(repository.NewEmailRepository(s.DB), notifications, repo, customerRepo,
+			repository.NewMerchantRepository(s.DB), s.Mailer, s.Cfg)
+		jobs = append(jobs, watcher.NewEmailSender(s.Cfg, emails))
+		// notify cannot build the email channel without importing service
+		if slices.Contains(s.Cfg.Reminder.Channels, emails.Name()) {
+			channels = append(channels, emails)
 		}
 	}
+	if len(s.Cfg.Reminder.Offsets) > 0 || s.Cfg.Reminder.Reissue {
+		reminders := service.NewReminderService(notifications, customerRepo, invoices, rates, channels, s.Cfg)
+		jobs = append(jobs, watcher.NewReminderSender(s.Cfg, reminders))
+	}
+	if s.Deposits != nil {
+		jobs = append(jobs, deposit.NewSweeper(s.Cfg, repo, s.Eth, s.Deposits))
+	}
+	return lifecycle.NewGroup(jobs...)
+}
 
-	if allowOrigin != "" {
-		c.Header("Access-Control-Allow-Origin", allowOrigin)
-		c.Header("Access-Control-Allow-Credentials", "true")
+// HandleOption sets security headers and CORS options. A single "*" in
+// allowedOrigins echoes back any origin.
+func HandleOption(allowedOrigins []string) gin.HandlerFunc {
+	allowAny := len(allowedOrigins) == 1 && allowedOrigins[0] == "*"
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
 	}
 
-	c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, withcredentials, X-CSRF-Token")
-	c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+	return func(c *gin.Context) {
+		requestOrigin := c.Request.Header.Get("Origin")
 
-	// Prevent caching
-	c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
-	c.Header("Pragma", "no-cache")
-	c.Header("Expires", "0")
+		// By default, block
+		allowOrigin := ""
+		if allowAny || allowed[requestOrigin] {
+			allowOrigin = requestOrigin
+		}
 
-	// Handle preflight
-	if c.Request.Method == "OPTIONS" {
-		c.AbortWithStatus(204)
-		return
-	}
+		if allowOrigin != "" {
+			c.Header("Access-Control-Allow-Origin", allowOrigin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, withcredentials, X-CSRF-Token")
+		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+
+		// Prevent caching
+		c.Header("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+		c.Header("Pragma", "no-cache")
+		c.Header("Expires", "0")
 
-	c.Next()
+		// Handle preflight
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	}
 }
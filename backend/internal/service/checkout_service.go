@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/document"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// defaultCheckoutAccent matches the documents' default accent color.
+const defaultCheckoutAccent = "#2563EB"
+
+// CheckoutService builds the hosted checkout page of an invoice.
+type CheckoutService interface {
+	Page(ctx context.Context, id string) (*checkout.Page, error)
+}
+
+type checkoutService struct {
+	invoices  repository.InvoiceRepository
+	customers repository.CustomerRepository
+	merchants repository.MerchantRepository
+	rates     *fiat.Rates
+	config    *config.Config
+}
+
+func NewCheckoutService(invoices repository.InvoiceRepository, customers repository.CustomerRepository, merchants repository.MerchantRepository, rates *fiat.Rates, cfg *config.Config) CheckoutService {
+	return &checkoutService{
+		invoices:  invoices,
+		customers: customers,
+		merchants: merchants,
+		rates:     rates,
+		config:    cfg,
+	}
+}
+
+func (s *checkoutService) Page(ctx context.Context, id string) (_ *checkout.Page, err error) {
+	ctx, span := telemetry.Start(ctx, "CheckoutService.Page", attribute.String("invoice.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	invoice, err := s.invoices.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile, err := findProfile(ctx, s.merchants, invoice.MerchantAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &checkout.Page{
+		Branding:      branding(profile),
+		InvoiceID:     invoice.ID.String(),
+		Reference:     documentName(invoice),
+		Status:        string(invoice.Status),
+		AmountPaidWei: invoice.AmountPaidWei,
+		TotalETH:      document.FormatETH(invoice.AmountWei),
+		ExpiresAt:     invoice.ExpiresAt,
+		Poll:          invoice.Status == models.StatusPending || invoice.Status == models.StatusPartiallyPaid,
+	}
+	if invoice.TxHash != nil {
+		page.TxHash = *invoice.TxHash
+	}
+
+	switch {
+	case invoice.Status == models.StatusPaid || invoice.Status == models.StatusPartiallyRefunded || invoice.Status == models.StatusRefunded:
+		page.State = checkout.StatePaid
+		page.RedirectURL = checkoutRedirect(invoice.SuccessURL, profile.SuccessURL, invoice)
+	case invoice.Status == models.StatusExpired || time.Now().After(invoice.ExpiresAt):
+		// Until the expiry job marks it, a payment sent just in time may
+		// still settle the invoice, so the payer is not sent away yet
+		page.State = checkout.StateExpired
+		if invoice.Status == models.StatusExpired {
+			page.RedirectURL = checkoutRedirect(invoice.ExpiredURL, profile.ExpiredURL, invoice)
+		}
+	default:
+		page.State = checkout.StatePending
+		if err := s.pending(ctx, page, invoice, profile); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// pending fills in what the payer needs to pay the invoice.
+func (s *checkoutService) pending(ctx context.Context, page *checkout.Page, invoice *models.Invoice, profile *models.MerchantProfile) error {
+	amount, ok := new(big.Int).SetString(invoice.AmountWei, 10)
+	if !ok {
+		amount = new(big.Int)
+	}
+	paid := invoice.PaidWei()
+	due := new(big.Int).Sub(amount, paid)
+	if invoice.Mode == models.ModeTransfer {
+		// Matched by its exact amount, so it is always paid in full
+		due = amount
+	}
+	page.AmountDueETH = document.FormatETH(due.String())
+	if paid.Sign() > 0 {
+		page.PaidETH = document.FormatETH(paid.String())
+	}
+
+	quote, err := fiatQuote(ctx, s.customers, s.rates, s.config, invoice, profile)
+	if err != nil {
+		return err
+	}
+	if quote != nil {
+		page.FiatAmount = fiat.Format(quote.Convert(due)) + " " + quote.Currency
+	}
+
+	page.PaymentURI = paymentURI(s.config, invoice)
+	if page.PaymentURI != "" {
+		page.QRSVG, err = document.QRSVG(page.PaymentURI)
+		if err != nil {
+			// The address and wallet link still let the payer pay
+			logging.From(ctx, "service").WithError(err).WithField(logging.FieldInvoiceID, page.InvoiceID).Warn("Failed to render checkout QR code")
+			page.QRSVG = nil
+		}
+	}
+
+	switch invoice.Mode {
+	case models.ModeOnchain:
+		if invoice.OnchainInvoiceID == "" {
+			page.Notice = "This invoice is still being registered on-chain. The payment request appears here once it is."
+		}
+	case models.ModeSigned:
+		page.Notice = "This invoice is paid with its signed payment request, from the invoice app."
+	case models.ModeTransfer:
+		page.PayTo = invoice.PaymentAddress
+		page.Notice = "Send exactly the amount shown: the payment is matched by its amount."
+	case models.ModeDeposit:
+		if invoice.DepositAddress != nil {
+			page.PayTo = *invoice.DepositAddress
+		}
+	}
+	return nil
+}
+
+// branding is how the merchant's pages look, with the documents' default
+// accent color.
+func branding(profile *models.MerchantProfile) checkout.Branding {
+	accent := profile.AccentColor
+	if accent == "" {
+		accent = defaultCheckoutAccent
+	}
+	return checkout.Branding{MerchantName: profile.Name, Logo: profile.Logo, Accent: accent}
+}
+
+// checkoutRedirect is the invoice's redirect, else the merchant's, with the
+// invoice ID and status added so the merchant's page can tell which invoice
+// it is; "" when neither is set.
+func checkoutRedirect(override *string, fallback string, invoice *models.Invoice) string {
+	raw := fallback
+	if override != nil && *override != "" {
+		raw = *override
+	}
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("invoice_id", invoice.ID.String())
+	query.Set("status", string(invoice.Status))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// validRedirectURL reports whether raw is an absolute http(s) URL, the only
+// kind the checkout redirects to.
+func validRedirectURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxCustomerPage caps the customers returned by one List call.
+const maxCustomerPage = 100
+
+var (
+	// ErrCustomerNotFound is returned for unknown customer IDs or wallets.
+	ErrCustomerNotFound = errors.New("customer not found")
+	// ErrInvalidCustomer is returned for malformed customer fields.
+	ErrInvalidCustomer = errors.New("invalid customer")
+
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// CustomerService manages who invoices are billed to.
+type CustomerService interface {
+	Create(ctx context.Context, params CustomerParams) (*models.Customer, error)
+	Get(ctx context.Context, id string) (*models.Customer, error)
+	// FindByWallet looks up the customer a wallet address belongs to.
+	FindByWallet(ctx context.Context, address string) (*models.Customer, error)
+	List(ctx context.Context, limit int, offset int) ([]models.Customer, error)
+	Update(ctx context.Context, id string, params CustomerParams) (*models.Customer, error)
+	Delete(ctx context.Context, id string) error
+	AddWallet(ctx context.Context, id string, address string) (*models.Customer, error)
+	RemoveWallet(ctx context.Context, id string, address string) (*models.Customer, error)
+	// Invoices returns the customer's invoices, newest first, as a
+	// statement.
+	Invoices(ctx context.Context, id string) ([]models.Invoice, error)
+}
+
+// CustomerParams holds the customer profile. Wallets are only read on
+// Create.
+type CustomerParams struct {
+	Name              string
+	Email             string
+	BillingAddress    string
+	TaxID             string
+	PreferredCurrency string
+	Wallets           []string
+}
+
+type customerService struct {
+	repo repository.CustomerRepository
+}
+
+func NewCustomerService(repo repository.CustomerRepository) CustomerService {
+	return &customerService{repo: repo}
+}
+
+func (s *customerService) Create(ctx context.Context, params CustomerParams) (*models.Customer, error) {
+	customer := &models.Customer{}
+	if err := applyCustomerParams(customer, params); err != nil {
+		return nil, err
+	}
+	for _, address := range params.Wallets {
+		wallet, err := normalizeWallet(address)
+		if err != nil {
+			return nil, err
+		}
+		customer.Wallets = append(customer.Wallets, models.CustomerWallet{Address: wallet, Source: models.WalletManual})
+	}
+
+	if err := s.repo.Create(ctx, customer); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithField("customer_id", customer.ID.String()).Info("Customer created")
+	return customer, nil
+}
+
+func (s *customerService) Get(ctx context.Context, id string) (*models.Customer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	customer, err := s.repo.FindByID(ctx, id)
+	return customer, customerNotFound(err)
+}
+
+func (s *customerService) FindByWallet(ctx context.Context, address string) (*models.Customer, error) {
+	wallet, err := normalizeWallet(address)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := s.repo.FindByWallet(ctx, wallet)
+	return customer, customerNotFound(err)
+}
+
+func (s *customerService) List(ctx context.Context, limit int, offset int) ([]models.Customer, error) {
+	if limit <= 0 || limit > maxCustomerPage {
+		limit = maxCustomerPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.List(ctx, limit, offset)
+}
+
+func (s *customerService) Update(ctx context.Context, id string, params CustomerParams) (*models.Customer, error) {
+	customer, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCustomerParams(customer, params); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, customer); err != nil {
+		return nil, customerNotFound(err)
+	}
+	return customer, nil
+}
+
+func (s *customerService) Delete(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrCustomerNotFound
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return customerNotFound(err)
+	}
+	logging.From(ctx, "service").WithField("customer_id", id).Info("Customer deleted")
+	return nil
+}
+
+func (s *customerService) AddWallet(ctx context.Context, id string, address string) (*models.Customer, error) {
+	customer, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := normalizeWallet(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddWallet(ctx, customer.ID, wallet, models.WalletManual); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *customerService) RemoveWallet(ctx context.Context, id string, address string) (*models.Customer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	wallet, err := normalizeWallet(address)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveWallet(ctx, id, wallet); err != nil {
+		return nil, customerNotFound(err)
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *customerService) Invoices(ctx context.Context, id string) ([]models.Invoice, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvoices(ctx, id)
+}
+
+func applyCustomerParams(customer *models.Customer, params CustomerParams) error {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCustomer)
+	}
+	if params.Email != "" {
+		if _, err := mail.ParseAddress(params.Email); err != nil {
+			return fmt.Errorf("%w: email is not valid", ErrInvalidCustomer)
+		}
+	}
+	currency := strings.ToUpper(params.PreferredCurrency)
+	if currency != "" && !currencyPattern.MatchString(currency) {
+		return fmt.Errorf("%w: preferred_currency must be a three-letter currency code", ErrInvalidCustomer)
+	}
+
+	customer.Name = name
+	customer.Email = params.Email
+	customer.BillingAddress = params.BillingAddress
+	customer.TaxID = params.TaxID
+	customer.PreferredCurrency = currency
+	return nil
+}
+
+func normalizeWallet(address string) (string, error) {
+	if !common.IsHexAddress(address) {
+		return "", fmt.Errorf("%w: %q is not an address", ErrInvalidCustomer, address)
+	}
+	return common.HexToAddress(address).Hex(), nil
+}
+
+func customerNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrCustomerNotFound
+	}
+	return err
+}
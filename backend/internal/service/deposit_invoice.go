@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// ErrDepositModeDisabled is returned for deposit invoices when
+// DEPOSIT_ADDRESSES_ENABLED is off.
+var ErrDepositModeDisabled = errors.New("deposit address mode is not enabled")
+
+// assignDepositAddress allocates the next derivation index and sets the
+// invoice's deposit address to the matching child of DEPOSIT_XPUB.
+func (s *invoiceService) assignDepositAddress(ctx context.Context, invoice *models.Invoice) error {
+	index, err := s.repo.NextDepositIndex(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to allocate deposit index: %v", err)
+	}
+	addr, err := s.deposits.Address(index)
+	if err != nil {
+		return err
+	}
+
+	address := addr.Hex()
+	derivationIndex := int64(index)
+	invoice.DepositAddress = &address
+	invoice.DepositIndex = &derivationIndex
+	logging.From(ctx, "service").WithFields(map[string]interface{}{
+		"deposit_address": address,
+		"deposit_index":   index,
+	}).Info("Deposit address assigned")
+	return nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/document"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvoiceNotFound is returned for unknown invoice IDs.
+	ErrInvoiceNotFound = errors.New("invoice not found")
+	// ErrNotPaid is returned for the receipt of an invoice that has not
+	// been paid.
+	ErrNotPaid = errors.New("invoice is not paid")
+	// ErrNotPayable is returned for the QR code of an invoice without a
+	// payment URI.
+	ErrNotPayable = errors.New("invoice cannot be paid with a payment request")
+	// ErrInvalidQRSize is returned for PNG sizes out of range.
+	ErrInvalidQRSize = fmt.Errorf("size must be between %d and %d pixels", minQRSize, maxQRSize)
+)
+
+// QR code PNG sizes, in pixels.
+const (
+	minQRSize     = 64
+	maxQRSize     = 1024
+	defaultQRSize = 256
+)
+
+// QRFormat is the image format of a payment QR code.
+type QRFormat string
+
+const (
+	QRFormatPNG QRFormat = "png"
+	QRFormatSVG QRFormat = "svg"
+)
+
+// DocumentService renders invoices and receipts as PDFs, and payment QR
+// codes.
+type DocumentService interface {
+	InvoicePDF(ctx context.Context, id string) (*Document, error)
+	// ReceiptPDF is only available once the invoice is paid, and stays
+	// available after refunds.
+	ReceiptPDF(ctx context.Context, id string) (*Document, error)
+	// PaymentQR renders the invoice's payment URI as a QR code; size is
+	// the PNG width in pixels, zero for the default, and ignored for SVG.
+	PaymentQR(ctx context.Context, id string, format QRFormat, size int) (*Document, error)
+}
+
+// Document is a rendered file.
+type Document struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+type documentService struct {
+	invoices  repository.InvoiceRepository
+	customers repository.CustomerRepository
+	merchants repository.MerchantRepository
+	rates     *fiat.Rates
+	config    *config.Config
+	client    chain.Client
+}
+
+func NewDocumentService(invoices repository.InvoiceRepository, customers repository.CustomerRepository, merchants repository.MerchantRepository, rates *fiat.Rates, cfg *config.Config, client chain.Client) DocumentService {
+	return &documentService{
+		invoices:  invoices,
+		customers: customers,
+		merchants: merchants,
+		rates:     rates,
+		config:    cfg,
+		client:    client,
+	}
+}
+
+func (s *documentService) InvoicePDF(ctx context.Context, id string) (_ *Document, err error) {
+	ctx, span := telemetry.Start(ctx, "DocumentService.InvoicePDF", attribute.String("invoice.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	details, err := s.details(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	content, err := document.RenderInvoice(&document.Invoice{
+		Details:    *details,
+		PaymentURI: paymentURI(s.config, details.Invoice),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Filename: "invoice-" + documentName(details.Invoice) + ".pdf", ContentType: "application/pdf", Content: content}, nil
+}
+
+func (s *documentService) ReceiptPDF(ctx context.Context, id string) (_ *Document, err error) {
+	ctx, span := telemetry.Start(ctx, "DocumentService.ReceiptPDF", attribute.String("invoice.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	details, err := s.details(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	invoice := details.Invoice
+	switch invoice.Status {
+	case models.StatusPaid, models.StatusPartiallyRefunded, models.StatusRefunded:
+	default:
+		return nil, ErrNotPaid
+	}
+
+	// Every payment is recorded now; invoices paid before payments were
+	// recorded only keep the transaction that settled them
+	payments := invoice.Payments
+	if len(payments) == 0 {
+		payments = []models.Payment{s.settlement(ctx, invoice)}
+	}
+	content, err := document.RenderReceipt(&document.Receipt{Details: *details, Payments: payments})
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Filename: "receipt-" + documentName(invoice) + ".pdf", ContentType: "application/pdf", Content: content}, nil
+}
+
+func (s *documentService) PaymentQR(ctx context.Context, id string, format QRFormat, size int) (_ *Document, err error) {
+	ctx, span := telemetry.Start(ctx, "DocumentService.PaymentQR", attribute.String("invoice.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	if size == 0 {
+		size = defaultQRSize
+	}
+	if size < minQRSize || size > maxQRSize {
+		return nil, ErrInvalidQRSize
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	invoice, err := s.invoices.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	uri := paymentURI(s.config, invoice)
+	if uri == "" {
+		return nil, ErrNotPayable
+	}
+
+	doc := &Document{Filename: "invoice-" + documentName(invoice) + "-qr." + string(format)}
+	if format == QRFormatSVG {
+		doc.ContentType = "image/svg+xml"
+		doc.Content, err = document.QRSVG(uri)
+	} else {
+		doc.ContentType = "image/png"
+		doc.Content, err = document.QRPNG(uri, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// details loads what both documents show. A missing exchange rate only
+// leaves out the fiat amounts.
+func (s *documentService) details(ctx context.Context, id string) (*document.Details, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	invoice, err := s.invoices.FindByIDWithPayments(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	profile, err := findProfile(ctx, s.merchants, invoice.MerchantAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := fiatQuote(ctx, s.customers, s.rates, s.config, invoice, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &document.Details{
+		Invoice:   invoice,
+		Merchant:  profile,
+		ChainID:   s.config.Ethereum.ChainID,
+		Fiat:      quote,
+		Generated: time.Now(),
+	}, nil
+}
+
+// fiatQuote prices ETH in the currency the invoice's amounts are also shown
+// in: the customer's preference, else the merchant's, else the configured
+// default. It is nil, not an error, when there is no rate.
+func fiatQuote(ctx context.Context, customers repository.CustomerRepository, rates *fiat.Rates, cfg *config.Config, invoice *models.Invoice, profile *models.MerchantProfile) (*fiat.Quote, error) {
+	currency := cfg.Fiat.Currency
+	if profile.Currency != "" {
+		currency = profile.Currency
+	}
+	if invoice.CustomerID != nil {
+		customer, err := customers.FindByID(ctx, invoice.CustomerID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if customer != nil && customer.PreferredCurrency != "" {
+			currency = customer.PreferredCurrency
+		}
+	}
+	quote, err := rates.Quote(ctx, currency)
+	if err != nil {
+		logging.From(ctx, "service").WithError(err).WithField("currency", currency).Warn("Showing invoice without fiat amounts")
+		return nil, nil
+	}
+	return quote, nil
+}
+
+// settlement describes the transaction that settled an invoice paid before
+// payments were recorded, looking up its block on-chain. If the lookup fails the
+// receipt is still rendered, without block details.
+func (s *documentService) settlement(ctx context.Context, invoice *models.Invoice) models.Payment {
+	payment := models.Payment{
+		InvoiceID: invoice.ID,
+		ChainID:   s.config.Ethereum.ChainID,
+		Token:     models.NativeToken,
+		AmountWei: invoice.PaidWei().String(),
+	}
+	if invoice.PayerAddress != nil {
+		payment.PayerAddress = *invoice.PayerAddress
+	}
+	if invoice.TxHash == nil {
+		return payment
+	}
+	payment.TxHash = *invoice.TxHash
+
+	log := logging.From(ctx, "service").WithField(logging.FieldTxHash, payment.TxHash)
+	receipt, err := s.client.TransactionReceipt(ctx, common.HexToHash(payment.TxHash))
+	if err != nil {
+		log.WithError(err).Warn("Failed to look up settlement receipt")
+		return payment
+	}
+	payment.BlockNumber = receipt.BlockNumber.Uint64()
+	payment.BlockHash = receipt.BlockHash.Hex()
+	header, err := s.client.HeaderByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		log.WithError(err).Warn("Failed to look up settlement block")
+		return payment
+	}
+	blockTime := time.Unix(int64(header.Time), 0).UTC()
+	payment.BlockTime = &blockTime
+	return payment
+}
+
+func documentName(invoice *models.Invoice) string {
+	if invoice.InvoiceNumber != nil {
+		return *invoice.InvoiceNumber
+	}
+	return invoice.ID.String()
+}
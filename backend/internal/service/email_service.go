@@ -0,0 +1,651 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/email"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/notify"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+)
+
+const (
+	// emailLookback bounds how long ago an invoice event may have happened
+	// to be emailed, so that turning email on does not email old history.
+	emailLookback = 24 * time.Hour
+	// emailBatch caps the messages one Deliver pass sends.
+	emailBatch = 50
+	// maxOutboxPage caps the messages returned by one Outbox call.
+	maxOutboxPage = 100
+	// maxEmailRetryDelay caps the doubling delay between delivery attempts.
+	maxEmailRetryDelay = 24 * time.Hour
+)
+
+var (
+	// ErrEmailNotFound is returned for unknown outbox message IDs.
+	ErrEmailNotFound = errors.New("email not found")
+	// ErrEmailSent is returned when retrying a message that was sent.
+	ErrEmailSent = errors.New("email was already sent")
+	// ErrEmailTemplateNotFound is returned for templates a merchant has
+	// not saved, or kinds without a default.
+	ErrEmailTemplateNotFound = errors.New("email template not found")
+	// ErrInvalidEmailTemplate is returned for templates that do not parse
+	// or render, and unknown kinds or audiences.
+	ErrInvalidEmailTemplate = errors.New("invalid email template")
+	// ErrEmailPreferencesNotFound is returned for unknown preference
+	// tokens.
+	ErrEmailPreferencesNotFound = errors.New("email preferences not found")
+	// ErrInvalidEmailPreferences is returned for malformed addresses and
+	// unknown kinds.
+	ErrInvalidEmailPreferences = errors.New("invalid email preferences")
+)
+
+// emailKinds are the kinds of invoice email, in the order the preferences
+// page lists them.
+var emailKinds = []struct {
+	kind  models.NotificationKind
+	label string
+}{
+	{models.NotifyCreated, "New invoices"},
+	{models.NotifyReminder, "Payment reminders"},
+	{models.NotifyPaid, "Payment confirmations"},
+	{models.NotifyExpired, "Expired invoices"},
+	{models.NotifyReissued, "Re-issued invoices"},
+}
+
+// EmailService emails customers and merchants about their invoices. It is
+// also the "email" reminder channel: Send queues an event's emails in the
+// outbox, which Deliver sends and retries.
+type EmailService interface {
+	notify.Channel
+	// Notify is one pass of the outbox scheduler over invoice events: it
+	// queues the emails of the EMAIL_EVENTS that happened since the last
+	// pass.
+	Notify(ctx context.Context, now time.Time) error
+	// Deliver sends the queued emails due at now, scheduling failed ones
+	// for another attempt.
+	Deliver(ctx context.Context, now time.Time) error
+	// Outbox returns queued and sent emails newest first, only those in
+	// status if given.
+	Outbox(ctx context.Context, status models.EmailStatus, limit int, offset int) ([]models.EmailMessage, error)
+	// Retry queues a failed or pending email to be sent right away, with
+	// its attempts reset.
+	Retry(ctx context.Context, id string) (*models.EmailMessage, error)
+
+	// Template returns the merchant's template, or the default one with a
+	// zero ID if the merchant has none.
+	Template(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) (*models.EmailTemplate, error)
+	ListTemplates(ctx context.Context, merchant string) ([]models.EmailTemplate, error)
+	SaveTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience, params EmailTemplateParams) (*models.EmailTemplate, error)
+	// DeleteTemplate returns the merchant to the default template.
+	DeleteTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) error
+
+	// Preferences returns an address's preferences, as the admin sees them.
+	Preferences(ctx context.Context, address string) (*models.EmailPreference, error)
+	SavePreferences(ctx context.Context, address string, params EmailPreferenceParams) (*models.EmailPreference, error)
+	// PreferencesPage builds the recipient's preferences page from the
+	// token in their emails.
+	PreferencesPage(ctx context.Context, token string) (*checkout.PreferencesPage, error)
+	SavePreferencesPage(ctx context.Context, token string, params EmailPreferenceParams) (*checkout.PreferencesPage, error)
+	// Unsubscribe stops all email to the token's address, for one-click
+	// unsubscribe.
+	Unsubscribe(ctx context.Context, token string) error
+}
+
+type EmailTemplateParams struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// EmailPreferenceParams replace an address's preferences.
+type EmailPreferenceParams struct {
+	Unsubscribed bool
+	Disabled     []models.NotificationKind
+}
+
+type emailService struct {
+	repo          repository.EmailRepository
+	notifications repository.NotificationRepository
+	invoices      repository.InvoiceRepository
+	customers     repository.CustomerRepository
+	merchants     repository.MerchantRepository
+	// sender is nil when no SMTP server is configured.
+	sender email.Sender
+	config *config.Config
+}
+
+func NewEmailService(repo repository.EmailRepository, notifications repository.NotificationRepository, invoices repository.InvoiceRepository, customers repository.CustomerRepository, merchants repository.MerchantRepository, sender email.Sender, cfg *config.Config) EmailService {
+	return &emailService{
+		repo:          repo,
+		notifications: notifications,
+		invoices:      invoices,
+		customers:     customers,
+		merchants:     merchants,
+		sender:        sender,
+		config:        cfg,
+	}
+}
+
+func (s *emailService) Name() string {
+	return repository.EmailChannel
+}
+
+// Send queues the event's emails to the invoice's customer and merchant,
+// unless they opted out. A merchant only gets the kinds with a default
+// email, or those they saved a template for.
+func (s *emailService) Send(ctx context.Context, event notify.Event) (err error) {
+	ctx, span := telemetry.Start(ctx, "EmailService.Send")
+	defer func() { telemetry.End(span, err) }()
+
+	invoice := event.Invoice
+	profile, err := findProfile(ctx, s.merchants, invoice.MerchantAddress)
+	if err != nil {
+		return err
+	}
+	customer := event.Customer
+	if customer == nil && invoice.CustomerID != nil {
+		customer, err = s.customers.FindByID(ctx, invoice.CustomerID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
+	var errs []error
+	// A re-issued invoice is announced by its own email when reminders go
+	// out by email, so its customer is not told twice
+	reissueMail := event.Kind == models.NotifyCreated && invoice.ReissueOfID != nil &&
+		slices.Contains(s.config.Reminder.Channels, repository.EmailChannel)
+	if customer != nil && customer.Email != "" && !reissueMail {
+		errs = append(errs, s.enqueue(ctx, event, profile, customer, models.AudienceCustomer, customer.Email))
+	}
+	if profile.Email != "" {
+		errs = append(errs, s.enqueue(ctx, event, profile, customer, models.AudienceMerchant, profile.Email))
+	}
+	return errors.Join(errs...)
+}
+
+// enqueue renders one email and adds it to the outbox.
+func (s *emailService) enqueue(ctx context.Context, event notify.Event, profile *models.MerchantProfile, customer *models.Customer, audience models.EmailAudience, to string) error {
+	logger := logging.From(ctx, "email").WithFields(logrus.Fields{
+		logging.FieldInvoiceID: event.Invoice.ID.String(),
+		"kind":                 event.Kind,
+		"audience":             audience,
+	})
+	pref, err := s.repo.Preference(ctx, to)
+	if err != nil {
+		return fmt.Errorf("load email preferences: %w", err)
+	}
+	if !pref.Wants(event.Kind) {
+		logger.Debug("Recipient opted out of email")
+		return nil
+	}
+	t, err := s.template(ctx, profile.MerchantAddress, event.Kind, audience)
+	if errors.Is(err, ErrEmailTemplateNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data := s.data(event, profile, customer, audience, pref.Token)
+	rendered, err := email.Render(email.Template{Subject: t.Subject, HTML: t.HTML, Text: t.Text}, data)
+	if err != nil {
+		return fmt.Errorf("render %s email: %w", event.Kind, err)
+	}
+	msg := &models.EmailMessage{
+		InvoiceID:      &event.Invoice.ID,
+		Kind:           event.Kind,
+		Audience:       audience,
+		To:             to,
+		Subject:        rendered.Subject,
+		HTML:           rendered.HTML,
+		Text:           rendered.Text,
+		UnsubscribeURL: data.PreferencesURL,
+		Status:         models.EmailPending,
+		NextAttemptAt:  time.Now().UTC(),
+	}
+	if err := s.repo.Enqueue(ctx, msg); err != nil {
+		return fmt.Errorf("queue email: %w", err)
+	}
+	logger.WithField("email_id", msg.ID.String()).Info("Email queued")
+	return nil
+}
+
+// data is what the templates show about the event's invoice.
+func (s *emailService) data(event notify.Event, profile *models.MerchantProfile, customer *models.Customer, audience models.EmailAudience, token string) email.Data {
+	invoice := event.Invoice
+	base := s.config.Email.PublicURL
+	id := invoice.ID.String()
+	data := email.Data{
+		Kind:           event.Kind,
+		Audience:       audience,
+		Merchant:       profile.Name,
+		Accent:         branding(profile).Accent,
+		Reference:      documentName(invoice),
+		InvoiceID:      id,
+		Status:         string(invoice.Status),
+		Amount:         formatWeiExact(invoice.AmountWei) + " ETH",
+		ExpiresAt:      invoice.ExpiresAt.UTC().Format("2 Jan 2006 15:04 UTC"),
+		CheckoutURL:    base + event.CheckoutPath(),
+		InvoicePDFURL:  base + "/api/invoices/" + id + "/pdf",
+		ReceiptURL:     base + "/api/invoices/" + id + "/receipt.pdf",
+		PreferencesURL: base + "/email/preferences/" + token,
+		Footer:         profile.FooterText,
+	}
+	if data.Merchant == "" {
+		data.Merchant = invoice.MerchantAddress
+	}
+	for _, item := range invoice.LineItems {
+		data.LineItems = append(data.LineItems, email.LineItem{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			Total:       formatWeiExact(item.TotalWei) + " ETH",
+		})
+	}
+	if event.ExpiresIn > 0 {
+		data.ExpiresIn = durationText(event.ExpiresIn)
+	}
+	if customer != nil {
+		data.Customer = customer.Name
+	}
+	if invoice.PayerAddress != nil {
+		data.Payer = *invoice.PayerAddress
+	}
+	if invoice.TxHash != nil {
+		data.TxHash = *invoice.TxHash
+	}
+	if event.Replaces != nil {
+		data.Replaces = documentName(event.Replaces)
+	}
+	return data
+}
+
+func (s *emailService) Notify(ctx context.Context, now time.Time) (err error) {
+	ctx, span := telemetry.Start(ctx, "EmailService.Notify")
+	defer func() { telemetry.End(span, err) }()
+
+	for _, event := range s.config.Email.Events {
+		kind := models.NotificationKind(event)
+		due, err := s.repo.FindEventDue(ctx, kind, now.Add(-emailLookback))
+		if err != nil {
+			return fmt.Errorf("load invoices due a %s email: %w", kind, err)
+		}
+		for i := range due {
+			s.notify(ctx, kind, &due[i])
+		}
+	}
+	return nil
+}
+
+// notify queues the emails of one invoice event once, recording it like
+// the reminder channels do. For email, a sent notification means queued.
+func (s *emailService) notify(ctx context.Context, kind models.NotificationKind, invoice *models.Invoice) {
+	logger := logging.From(ctx, "email").WithFields(logrus.Fields{
+		logging.FieldInvoiceID: invoice.ID.String(),
+		"kind":                 kind,
+	})
+	record := &models.InvoiceNotification{
+		InvoiceID: invoice.ID,
+		Kind:      kind,
+		Channel:   repository.EmailChannel,
+		Status:    models.NotificationSending,
+	}
+	claimed, err := s.notifications.Claim(ctx, record)
+	if err != nil {
+		logger.WithError(err).Error("Failed to record email notification")
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	if err := s.Send(ctx, notify.Event{Kind: kind, Invoice: invoice}); err != nil {
+		logger.WithError(err).Warn("Failed to queue email")
+		record.Status = models.NotificationFailed
+		record.Error = err.Error()
+	} else {
+		sentAt := time.Now().UTC()
+		record.Status = models.NotificationSent
+		record.SentAt = &sentAt
+	}
+	if err := s.notifications.Finish(ctx, record); err != nil {
+		logger.WithError(err).Error("Failed to save email notification outcome")
+	}
+}
+
+func (s *emailService) Deliver(ctx context.Context, now time.Time) (err error) {
+	ctx, span := telemetry.Start(ctx, "EmailService.Deliver")
+	defer func() { telemetry.End(span, err) }()
+
+	if s.sender == nil {
+		return nil
+	}
+	due, err := s.repo.FindDue(ctx, now, emailBatch)
+	if err != nil {
+		return fmt.Errorf("load queued emails: %w", err)
+	}
+	for i := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+		s.deliver(ctx, &due[i], now)
+	}
+	return nil
+}
+
+// deliver sends one message. Failures are retried after RetryDelay,
+// doubling each time up to maxEmailRetryDelay, until MaxAttempts.
+func (s *emailService) deliver(ctx context.Context, msg *models.EmailMessage, now time.Time) {
+	logger := logging.From(ctx, "email").WithFields(logrus.Fields{
+		"email_id": msg.ID.String(),
+		"kind":     msg.Kind,
+		"audience": msg.Audience,
+	})
+	err := s.sender.Send(ctx, email.Message{
+		ID:             msg.ID.String(),
+		To:             msg.To,
+		Subject:        msg.Subject,
+		HTML:           msg.HTML,
+		Text:           msg.Text,
+		UnsubscribeURL: msg.UnsubscribeURL,
+	})
+	msg.Attempts++
+	switch {
+	case err == nil:
+		sentAt := time.Now().UTC()
+		msg.Status = models.EmailSent
+		msg.SentAt = &sentAt
+		msg.LastError = ""
+		logger.Info("Email sent")
+	case msg.Attempts >= s.config.Email.MaxAttempts:
+		msg.Status = models.EmailFailed
+		msg.LastError = err.Error()
+		logger.WithError(err).Error("Email failed, giving up")
+	default:
+		msg.LastError = err.Error()
+		msg.NextAttemptAt = now.Add(emailRetryDelay(s.config.Email.RetryDelay, msg.Attempts))
+		logger.WithError(err).WithField("next_attempt_at", msg.NextAttemptAt).Warn("Email failed, will retry")
+	}
+	if err := s.repo.SaveAttempt(ctx, msg); err != nil {
+		logger.WithError(err).Error("Failed to save email delivery")
+	}
+}
+
+// emailRetryDelay is the wait after the given number of failed attempts:
+// base doubled for each attempt after the first, capped at
+// maxEmailRetryDelay (or base, if longer) so that large attempt counts
+// cannot overflow.
+func emailRetryDelay(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxEmailRetryDelay; i++ {
+		delay *= 2
+	}
+	return max(base, min(delay, maxEmailRetryDelay))
+}
+
+func (s *emailService) Outbox(ctx context.Context, status models.EmailStatus, limit int, offset int) ([]models.EmailMessage, error) {
+	if limit <= 0 || limit > maxOutboxPage {
+		limit = maxOutboxPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.ListOutbox(ctx, status, limit, offset)
+}
+
+func (s *emailService) Retry(ctx context.Context, id string) (*models.EmailMessage, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrEmailNotFound
+	}
+	msg, err := s.repo.FindMessage(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEmailNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.Status == models.EmailSent {
+		return nil, ErrEmailSent
+	}
+	msg.Status = models.EmailPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = time.Now().UTC()
+	if err := s.repo.SaveAttempt(ctx, msg); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "email").WithField("email_id", msg.ID.String()).Info("Email queued for retry")
+	return msg, nil
+}
+
+func (s *emailService) Template(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) (*models.EmailTemplate, error) {
+	if err := validTemplateKey(merchant, kind, audience); err != nil {
+		return nil, err
+	}
+	return s.template(ctx, merchant, kind, audience)
+}
+
+// template is the merchant's template, else the default one.
+func (s *emailService) template(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) (*models.EmailTemplate, error) {
+	t, err := s.repo.FindTemplate(ctx, merchant, kind, audience)
+	if err == nil {
+		return t, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	def, ok := email.Default(kind, audience)
+	if !ok {
+		return nil, ErrEmailTemplateNotFound
+	}
+	return &models.EmailTemplate{
+		MerchantAddress: strings.ToLower(merchant),
+		Kind:            kind,
+		Audience:        audience,
+		Subject:         def.Subject,
+		HTML:            def.HTML,
+		Text:            def.Text,
+	}, nil
+}
+
+func (s *emailService) ListTemplates(ctx context.Context, merchant string) ([]models.EmailTemplate, error) {
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidEmailTemplate)
+	}
+	return s.repo.ListTemplates(ctx, merchant)
+}
+
+func (s *emailService) SaveTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience, params EmailTemplateParams) (*models.EmailTemplate, error) {
+	if err := validTemplateKey(merchant, kind, audience); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(params.HTML) == "" || strings.TrimSpace(params.Text) == "" {
+		return nil, fmt.Errorf("%w: html and text are required", ErrInvalidEmailTemplate)
+	}
+	if err := email.Validate(email.Template{Subject: params.Subject, HTML: params.HTML, Text: params.Text}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmailTemplate, err)
+	}
+
+	t := &models.EmailTemplate{
+		MerchantAddress: merchant,
+		Kind:            kind,
+		Audience:        audience,
+		Subject:         params.Subject,
+		HTML:            params.HTML,
+		Text:            params.Text,
+	}
+	if err := s.repo.SaveTemplate(ctx, t); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithFields(logrus.Fields{
+		logging.FieldMerchant: t.MerchantAddress,
+		"kind":                kind,
+		"audience":            audience,
+	}).Info("Email template saved")
+	return t, nil
+}
+
+func (s *emailService) DeleteTemplate(ctx context.Context, merchant string, kind models.NotificationKind, audience models.EmailAudience) error {
+	if err := validTemplateKey(merchant, kind, audience); err != nil {
+		return err
+	}
+	err := s.repo.DeleteTemplate(ctx, merchant, kind, audience)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrEmailTemplateNotFound
+	}
+	return err
+}
+
+func (s *emailService) Preferences(ctx context.Context, address string) (*models.EmailPreference, error) {
+	if _, err := mail.ParseAddress(address); err != nil {
+		return nil, fmt.Errorf("%w: email is not valid", ErrInvalidEmailPreferences)
+	}
+	return s.repo.Preference(ctx, address)
+}
+
+func (s *emailService) SavePreferences(ctx context.Context, address string, params EmailPreferenceParams) (*models.EmailPreference, error) {
+	pref, err := s.Preferences(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.savePreferences(ctx, pref, params); err != nil {
+		return nil, err
+	}
+	return pref, nil
+}
+
+func (s *emailService) PreferencesPage(ctx context.Context, token string) (*checkout.PreferencesPage, error) {
+	pref, err := s.preferencesByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return preferencesPage(pref), nil
+}
+
+func (s *emailService) SavePreferencesPage(ctx context.Context, token string, params EmailPreferenceParams) (*checkout.PreferencesPage, error) {
+	pref, err := s.preferencesByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.savePreferences(ctx, pref, params); err != nil {
+		return nil, err
+	}
+	page := preferencesPage(pref)
+	page.Saved = true
+	return page, nil
+}
+
+func (s *emailService) Unsubscribe(ctx context.Context, token string) error {
+	pref, err := s.preferencesByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.savePreferences(ctx, pref, EmailPreferenceParams{Unsubscribed: true, Disabled: pref.Disabled})
+}
+
+func (s *emailService) preferencesByToken(ctx context.Context, token string) (*models.EmailPreference, error) {
+	if token == "" {
+		return nil, ErrEmailPreferencesNotFound
+	}
+	pref, err := s.repo.FindPreferenceByToken(ctx, token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEmailPreferencesNotFound
+	}
+	return pref, err
+}
+
+func (s *emailService) savePreferences(ctx context.Context, pref *models.EmailPreference, params EmailPreferenceParams) error {
+	disabled := []models.NotificationKind{}
+	for _, kind := range params.Disabled {
+		if !knownEmailKind(kind) {
+			return fmt.Errorf("%w: unknown kind %q", ErrInvalidEmailPreferences, kind)
+		}
+		if !slices.Contains(disabled, kind) {
+			disabled = append(disabled, kind)
+		}
+	}
+	pref.Unsubscribed = params.Unsubscribed
+	pref.Disabled = disabled
+	if err := s.repo.SavePreference(ctx, pref); err != nil {
+		return err
+	}
+	logging.From(ctx, "email").WithField("unsubscribed", pref.Unsubscribed).Info("Email preferences saved")
+	return nil
+}
+
+func preferencesPage(pref *models.EmailPreference) *checkout.PreferencesPage {
+	page := &checkout.PreferencesPage{
+		Branding:     checkout.Branding{Accent: defaultCheckoutAccent},
+		Token:        pref.Token,
+		Email:        pref.Email,
+		Unsubscribed: pref.Unsubscribed,
+	}
+	for _, k := range emailKinds {
+		page.Kinds = append(page.Kinds, checkout.PreferenceKind{
+			Kind:    string(k.kind),
+			Label:   k.label,
+			Enabled: !slices.Contains(pref.Disabled, k.kind),
+		})
+	}
+	return page
+}
+
+// EmailKinds are the kinds a recipient can opt out of.
+func EmailKinds() []models.NotificationKind {
+	kinds := make([]models.NotificationKind, len(emailKinds))
+	for i, k := range emailKinds {
+		kinds[i] = k.kind
+	}
+	return kinds
+}
+
+func knownEmailKind(kind models.NotificationKind) bool {
+	return slices.Contains(EmailKinds(), kind)
+}
+
+func validTemplateKey(merchant string, kind models.NotificationKind, audience models.EmailAudience) error {
+	if !common.IsHexAddress(merchant) {
+		return fmt.Errorf("%w: merchant is not an address", ErrInvalidEmailTemplate)
+	}
+	if !knownEmailKind(kind) {
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidEmailTemplate, kind)
+	}
+	if audience != models.AudienceCustomer && audience != models.AudienceMerchant {
+		return fmt.Errorf("%w: audience must be customer or merchant", ErrInvalidEmailTemplate)
+	}
+	return nil
+}
+
+// durationText renders a reminder offset for people, e.g. "1 hour" or
+// "2 days".
+func durationText(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(max(1, int(d/time.Minute)), "minute")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEmailRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		attempts int
+		want     time.Duration
+	}{
+		{"first failure", time.Minute, 1, time.Minute},
+		{"doubles", time.Minute, 2, 2 * time.Minute},
+		{"doubles again", time.Minute, 6, 32 * time.Minute},
+		{"capped", time.Minute, 20, maxEmailRetryDelay},
+		{"does not overflow", time.Minute, 1000, maxEmailRetryDelay},
+		{"long base is kept", 48 * time.Hour, 3, 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := emailRetryDelay(tt.base, tt.attempts); got != tt.want {
+				t.Errorf("emailRetryDelay(%s, %d) = %s, want %s", tt.base, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidNumberSequence is returned for malformed sequence settings.
+var ErrInvalidNumberSequence = errors.New("invalid invoice number sequence")
+
+var prefixPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{0,20}$`)
+
+// InvoiceNumberService manages per-merchant invoice number sequences and
+// finds invoices by number.
+type InvoiceNumberService interface {
+	// GetSequence returns the merchant's sequence, or the configured
+	// format it would start with.
+	GetSequence(ctx context.Context, merchant string) (*models.InvoiceNumberSequence, error)
+	SaveSequence(ctx context.Context, merchant string, params NumberSequenceParams) (*models.InvoiceNumberSequence, error)
+	// Search finds invoices whose number starts with number, optionally for
+	// one merchant.
+	Search(ctx context.Context, merchant string, number string) ([]models.Invoice, error)
+}
+
+type NumberSequenceParams struct {
+	Prefix      string
+	Padding     int
+	YearlyReset bool
+}
+
+type invoiceNumberService struct {
+	repo   repository.InvoiceRepository
+	config *config.Config
+}
+
+func NewInvoiceNumberService(repo repository.InvoiceRepository, cfg *config.Config) InvoiceNumberService {
+	return &invoiceNumberService{repo: repo, config: cfg}
+}
+
+func (s *invoiceNumberService) GetSequence(ctx context.Context, merchant string) (*models.InvoiceNumberSequence, error) {
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidNumberSequence)
+	}
+	seq, err := s.repo.FindNumberSequence(ctx, merchant)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		seq = defaultNumbering(s.config)
+		seq.MerchantAddress = strings.ToLower(merchant)
+		return seq, nil
+	}
+	return seq, err
+}
+
+func (s *invoiceNumberService) SaveSequence(ctx context.Context, merchant string, params NumberSequenceParams) (*models.InvoiceNumberSequence, error) {
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidNumberSequence)
+	}
+	if !prefixPattern.MatchString(params.Prefix) {
+		return nil, fmt.Errorf("%w: prefix must be at most 20 letters, digits, '-' or '_'", ErrInvalidNumberSequence)
+	}
+	if params.Padding < 1 || params.Padding > 12 {
+		return nil, fmt.Errorf("%w: padding must be between 1 and 12", ErrInvalidNumberSequence)
+	}
+
+	seq := &models.InvoiceNumberSequence{
+		MerchantAddress: merchant,
+		Prefix:          params.Prefix,
+		Padding:         params.Padding,
+		YearlyReset:     params.YearlyReset,
+	}
+	if err := s.repo.SaveNumberSequence(ctx, seq); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithFields(map[string]interface{}{
+		logging.FieldMerchant: seq.MerchantAddress,
+		"prefix":              seq.Prefix,
+		"yearly_reset":        seq.YearlyReset,
+	}).Info("Invoice number sequence saved")
+	return seq, nil
+}
+
+func (s *invoiceNumberService) Search(ctx context.Context, merchant string, number string) ([]models.Invoice, error) {
+	if merchant != "" && !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidNumberSequence)
+	}
+	return s.repo.SearchByNumber(ctx, merchant, number)
+}
+
+// numbering is the sequence format for a merchant's first invoice.
+func (s *invoiceService) numbering() *models.InvoiceNumberSequence {
+	return defaultNumbering(s.config)
+}
+
+func defaultNumbering(cfg *config.Config) *models.InvoiceNumberSequence {
+	return &models.InvoiceNumberSequence{
+		Prefix:      cfg.Numbers.Prefix,
+		Padding:     cfg.Numbers.Padding,
+		YearlyReset: cfg.Numbers.YearlyReset,
+	}
+}
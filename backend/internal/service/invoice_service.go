@@ -2,39 +2,100 @@ package service
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
 	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/funds"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
 	"github.com/user/crypto-invoice-generator/backend/internal/models"
 	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ABI file path
 const abiPath = "internal/abi/invoice.json"
 
 type InvoiceService interface {
-	CreateInvoice(merchantAddr string, amountETH float64, expiryMins int) (*models.Invoice, error)
-	GetInvoice(id string) (*models.Invoice, error)
+	CreateInvoice(ctx context.Context, params CreateInvoiceParams) (*models.Invoice, error)
+	GetInvoice(ctx context.Context, id string) (*models.Invoice, error)
 }
 
+// CreateInvoiceParams holds the invoice creation inputs. Zero values fall
+// back to the configured defaults.
+type CreateInvoiceParams struct {
+	MerchantAddress string
+	AmountETH       float64
+	ExpiryMinutes   int
+	Mode            models.InvoiceMode
+	// AllowPartial accepts several smaller payments (on-chain and deposit
+	// invoices only).
+	AllowPartial bool
+	// AmountWei is an exact alternative to AmountETH.
+	AmountWei string
+	// LineItems itemize the invoice instead of AmountETH; the total of the
+	// items, discount, tax and shipping becomes the amount.
+	LineItems   []LineItemParams
+	DiscountETH string
+	DiscountBps int
+	ShippingETH string
+	// CustomerID optionally links the invoice to a customer.
+	CustomerID string
+	// SuccessURL and ExpiredURL override the merchant's hosted checkout
+	// redirects.
+	SuccessURL string
+	ExpiredURL string
+	// PaymentLinkID records the payment link that created the invoice.
+	PaymentLinkID string
+	// SubscriptionID records the subscription that billed the invoice.
+	SubscriptionID string
+	// PriceCurrency and PriceAmount record the fiat price the amount was
+	// converted from, so that a re-issue can convert it again.
+	PriceCurrency string
+	PriceAmount   string
+	// ReissueOfID is the expired invoice this one replaces, ReissueCount
+	// how many replacements in a row it is.
+	ReissueOfID  string
+	ReissueCount int
+}
+
+// ErrInvalidRedirectURL is returned for checkout redirects that are not
+// absolute http(s) URLs.
+var ErrInvalidRedirectURL = errors.New("success_url and expired_url must be absolute http(s) URLs")
+
+// ErrInvalidMerchant is returned when the merchant address, given or
+// defaulted to PAYMENT_ADDRESS, is missing, malformed or the zero address;
+// payments forwarded or swept to it would be lost.
+var ErrInvalidMerchant = errors.New("merchant_address must be a non-zero 20-byte hex address")
+
+// ErrPartialNotSupported is returned when partial payment is requested for
+// a mode that requires one exact payment.
+var ErrPartialNotSupported = errors.New("partial payments are only supported for onchain and deposit invoices")
+
 type invoiceService struct {
 	repo      repository.InvoiceRepository
+	customers repository.CustomerRepository
 	config    *config.Config
-	client    *ethclient.Client
+	client    chain.Client
+	signer    signer.Signer
+	funds     *funds.Monitor
+	deposits  *signer.DepositAddresses
 	parsedABI abi.ABI
 }
 
-func NewInvoiceService(repo repository.InvoiceRepository, cfg *config.Config, client *ethclient.Client) InvoiceService {
+func NewInvoiceService(repo repository.InvoiceRepository, customers repository.CustomerRepository, cfg *config.Config, client chain.Client, txSigner signer.Signer, fundsMonitor *funds.Monitor) InvoiceService {
 	abiFile, err := os.Open(abiPath)
 	if err != nil {
 		panic("Failed to open ABI file: " + err.Error())
@@ -45,69 +106,220 @@ func NewInvoiceService(repo repository.InvoiceRepository, cfg *config.Config, cl
 	if err != nil {
 		panic("Failed to parse contract ABI: " + err.Error())
 	}
+	var deposits *signer.DepositAddresses
+	if cfg.Deposit.Enabled {
+		deposits, err = signer.NewDepositAddresses(cfg.Deposit.XPub)
+		if err != nil {
+			panic("Failed to load deposit xpub: " + err.Error())
+		}
+	}
+
 	return &invoiceService{
 		repo:      repo,
+		customers: customers,
 		config:    cfg,
 		client:    client,
+		signer:    txSigner,
+		funds:     fundsMonitor,
+		deposits:  deposits,
 		parsedABI: parsed,
 	}
 }
 
-func (s *invoiceService) CreateInvoice(merchantAddr string, amountETH float64, expiryMins int) (*models.Invoice, error) {
+func (s *invoiceService) CreateInvoice(ctx context.Context, params CreateInvoiceParams) (_ *models.Invoice, err error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceService.CreateInvoice")
+	defer func() { telemetry.End(span, err) }()
+
+	mode := params.Mode
+	if mode == "" {
+		mode = models.InvoiceMode(s.config.Payment.InvoiceMode)
+	}
+	span.SetAttributes(attribute.String("invoice.mode", string(mode)))
+	if params.AllowPartial && mode != models.ModeOnchain && mode != models.ModeDeposit {
+		return nil, ErrPartialNotSupported
+	}
+	itemized := len(params.LineItems) > 0
+	if itemized == (params.AmountETH > 0 || params.AmountWei != "") {
+		return nil, ErrAmountRequired
+	}
+	if !itemized && (params.DiscountETH != "" || params.DiscountBps != 0 || params.ShippingETH != "") {
+		return nil, fmt.Errorf("%w: discounts and shipping need line items", ErrInvalidLineItems)
+	}
+	for _, redirect := range []string{params.SuccessURL, params.ExpiredURL} {
+		if redirect != "" && !validRedirectURL(redirect) {
+			return nil, ErrInvalidRedirectURL
+		}
+	}
+
+	// Fail fast instead of sending a transaction the wallet cannot pay for
+	if mode == models.ModeOnchain {
+		if err := s.funds.Allow(); err != nil {
+			metrics.InvoicesRejected.WithLabelValues("signer_funds_low").Inc()
+			return nil, err
+		}
+	}
+
 	// 1. Convert inputs
 	// Use explicit big.Float to big.Int conversion for precision
 	amountWei := new(big.Int)
-	amountFloat := big.NewFloat(amountETH)
+	amountFloat := big.NewFloat(params.AmountETH)
 	multiplier := big.NewFloat(1e18)
 	amountFloat.Mul(amountFloat, multiplier)
 	amountFloat.Int(amountWei)
+	if params.AmountWei != "" {
+		if _, ok := amountWei.SetString(params.AmountWei, 10); !ok || amountWei.Sign() <= 0 || params.AmountETH > 0 {
+			return nil, ErrAmountRequired
+		}
+	}
 
+	expiryMins := params.ExpiryMinutes
+	if expiryMins == 0 {
+		expiryMins = s.config.Payment.InvoiceExpiryMins
+	}
 	expiresAt := time.Now().Add(time.Duration(expiryMins) * time.Minute)
 	expiresAtUnix := big.NewInt(expiresAt.Unix())
 
+	merchantAddr := params.MerchantAddress
 	if merchantAddr == "" {
-		// Default to owner/merchant from config if not provided
-		// Assuming for MVP the backend wallet creates invoices for itself or configured merchant
-		// If merchant matches config, use that.
-		// NOTE: In this design, the 'owner' (backend) creates the invoice.
-		// The 'merchant' param in contract is who gets paid.
-		// We'll use the configured payment address if no specific merchant is requested.
-		// But wait, the request asks for 'merchant_address' (optional).
-		// If empty, use config.
+		// Default to the configured payment address; the contract owner
+		// (backend) creates the invoice, the merchant gets paid.
 		if s.config.Payment.Address != "" {
 			merchantAddr = s.config.Payment.Address
 		}
 	}
 	merchantCommonAddr := common.HexToAddress(merchantAddr)
-
-	// 2. Transact with Contract
-	txHash, err := s.createInvoiceOnChain(merchantCommonAddr, amountWei, expiresAtUnix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create invoice on-chain: %v", err)
+	if !common.IsHexAddress(merchantAddr) || merchantCommonAddr == (common.Address{}) {
+		return nil, ErrInvalidMerchant
 	}
+	ctx = logging.With(ctx, logging.FieldMerchant, merchantAddr)
+	span.SetAttributes(attribute.String("invoice.merchant", merchantAddr), attribute.String("invoice.amount_wei", amountWei.String()))
 
-	// 3. Save to DB
 	invoice := &models.Invoice{
+		Mode:            mode,
 		MerchantAddress: merchantAddr,
 		AmountWei:       amountWei.String(),
 		Status:          models.StatusPending,
 		ExpiresAt:       expiresAt,
 		ContractAddress: s.config.Ethereum.ContractAddress,
-		TxHash:          &txHash,
+		AllowPartial:    params.AllowPartial,
+		AmountPaidWei:   "0",
+	}
+	if params.SuccessURL != "" {
+		invoice.SuccessURL = &params.SuccessURL
+	}
+	if params.ExpiredURL != "" {
+		invoice.ExpiredURL = &params.ExpiredURL
+	}
+	if params.PaymentLinkID != "" {
+		linkID, err := uuid.Parse(params.PaymentLinkID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payment link ID: %w", err)
+		}
+		invoice.PaymentLinkID = &linkID
+	}
+	if params.SubscriptionID != "" {
+		subscriptionID, err := uuid.Parse(params.SubscriptionID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subscription ID: %w", err)
+		}
+		invoice.SubscriptionID = &subscriptionID
+	}
+	if params.ReissueOfID != "" {
+		reissueOf, err := uuid.Parse(params.ReissueOfID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reissued invoice ID: %w", err)
+		}
+		invoice.ReissueOfID = &reissueOf
+		invoice.ReissueCount = params.ReissueCount
+	}
+	if params.PriceCurrency != "" {
+		invoice.PriceCurrency = &params.PriceCurrency
+		invoice.PriceAmount = &params.PriceAmount
+	}
+	if params.CustomerID != "" {
+		customer, err := s.findCustomer(ctx, params.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		invoice.CustomerID = &customer.ID
+	}
+	var contentHash *common.Hash
+	if itemized {
+		total, err := itemizeInvoice(invoice, params)
+		if err != nil {
+			return nil, err
+		}
+		amountWei = total
+		hash := common.HexToHash(*invoice.ContentHash)
+		contentHash = &hash
+		span.SetAttributes(attribute.String("invoice.amount_wei", invoice.AmountWei))
+	}
+
+	// 2. Sign off-chain, or transact with the contract. Transfer and
+	// deposit invoices need neither; a transfer invoice's unique amount is
+	// assigned when it is stored.
+	switch mode {
+	case models.ModeDeposit:
+		if s.deposits == nil {
+			return nil, ErrDepositModeDisabled
+		}
+		if err := s.assignDepositAddress(ctx, invoice); err != nil {
+			return nil, err
+		}
+	case models.ModeTransfer:
+		if !s.config.Payment.TransferModeEnabled {
+			return nil, ErrTransferModeDisabled
+		}
+		invoice.PaymentAddress = s.config.Payment.Address
+	case models.ModeSigned:
+		if err := s.signInvoice(ctx, invoice, merchantCommonAddr, amountWei, expiresAtUnix); err != nil {
+			return nil, fmt.Errorf("failed to sign invoice: %v", err)
+		}
+		ctx = logging.With(ctx, logging.FieldInvoiceHash, *invoice.InvoiceHash)
+		span.SetAttributes(attribute.String("invoice.hash", *invoice.InvoiceHash))
+	default:
+		txHash, err := s.createInvoiceOnChain(ctx, merchantCommonAddr, amountWei, expiresAtUnix, params.AllowPartial, contentHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create invoice on-chain: %v", err)
+		}
+		invoice.CreationTxHash = &txHash
+		ctx = logging.With(ctx, logging.FieldTxHash, txHash)
+		span.SetAttributes(attribute.String("eth.tx_hash", txHash))
+		logging.From(ctx, "service").Info("Invoice creation transaction sent")
 	}
 
-	if err := s.repo.Create(invoice); err != nil {
+	// 3. Save to DB
+	if mode == models.ModeTransfer {
+		err = s.createTransferInvoice(ctx, invoice, amountWei)
+	} else {
+		err = s.repo.Create(ctx, invoice, s.numbering())
+	}
+	if err != nil {
+		logging.From(ctx, "service").WithError(err).Error("Failed to store invoice")
 		return nil, err
 	}
+	logging.From(ctx, "service").WithField(logging.FieldInvoiceID, invoice.ID.String()).Info("Invoice created")
+	span.SetAttributes(attribute.String("invoice.id", invoice.ID.String()))
+	if invoice.ContentHash != nil {
+		span.SetAttributes(attribute.String("invoice.content_hash", *invoice.ContentHash))
+	}
+	metrics.InvoicesCreated.WithLabelValues(merchantAddr, strconv.FormatInt(s.config.Ethereum.ChainID, 10)).Inc()
 
 	// Populate display fields
-	invoice.AmountETH = fmt.Sprintf("%f", amountETH)
+	invoice.AmountETH = fmt.Sprintf("%f", params.AmountETH)
+	if mode == models.ModeTransfer || itemized || params.AmountWei != "" {
+		invoice.AmountETH = formatWeiExact(invoice.AmountWei)
+	}
+	invoice.PaymentURI = paymentURI(s.config, invoice)
 
 	return invoice, nil
 }
 
-func (s *invoiceService) GetInvoice(id string) (*models.Invoice, error) {
-	invoice, err := s.repo.FindByID(id)
+func (s *invoiceService) GetInvoice(ctx context.Context, id string) (_ *models.Invoice, err error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceService.GetInvoice", attribute.String("invoice.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	invoice, err := s.repo.FindByIDWithPayments(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -118,32 +330,29 @@ func (s *invoiceService) GetInvoice(id string) (*models.Invoice, error) {
 		ethFloat := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18))
 		invoice.AmountETH = fmt.Sprintf("%f", ethFloat)
 	}
+	if invoice.Mode == models.ModeTransfer {
+		// The payer must send this exact amount
+		invoice.AmountETH = formatWeiExact(invoice.AmountWei)
+	}
 	invoice.ContractAddress = s.config.Ethereum.ContractAddress
+	invoice.PaymentURI = paymentURI(s.config, invoice)
 
 	return invoice, nil
 }
 
-func (s *invoiceService) createInvoiceOnChain(merchant common.Address, amountWei *big.Int, expiresAt *big.Int) (string, error) {
-	ctx := context.Background()
-
-	// Private Key
-	pkStr := strings.TrimPrefix(s.config.Ethereum.PrivateKey, "0x")
-	if pkStr == "" {
-		return "", fmt.Errorf("DEPLOYER_PRIVATE_KEY is missing in your configuration")
-	}
-
-	privateKey, err := crypto.HexToECDSA(pkStr)
-	if err != nil {
-		return "", fmt.Errorf("invalid DEPLOYER_PRIVATE_KEY: %v", err)
+func (s *invoiceService) findCustomer(ctx context.Context, id string) (*models.Customer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrCustomerNotFound
 	}
+	customer, err := s.customers.FindByID(ctx, id)
+	return customer, customerNotFound(err)
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return "", fmt.Errorf("error casting public key to ECDSA")
-	}
+func (s *invoiceService) createInvoiceOnChain(ctx context.Context, merchant common.Address, amountWei *big.Int, expiresAt *big.Int, allowPartial bool, contentHash *common.Hash) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "InvoiceService.createInvoiceOnChain")
+	defer func() { telemetry.End(span, err) }()
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+	fromAddress := s.signer.Address()
 	nonce, err := s.client.PendingNonceAt(ctx, fromAddress)
 	if err != nil {
 		return "", fmt.Errorf("failed to get nonce: %v", err)
@@ -154,15 +363,20 @@ func (s *invoiceService) createInvoiceOnChain(merchant common.Address, amountWei
 		return "", fmt.Errorf("failed to suggest gas price: %v", err)
 	}
 
-	chainID, err := s.client.NetworkID(ctx)
-	if err != nil {
-		return "", fmt.Errorf("failed to get chain ID: %v", err)
-	}
-
+	chainID := big.NewInt(s.config.Ethereum.ChainID)
 	contractAddr := common.HexToAddress(s.config.Ethereum.ContractAddress)
 
-	// Pack input data
-	data, err := s.parsedABI.Pack("createInvoice", merchant, amountWei, expiresAt)
+	// Pack input data; plain createInvoice keeps exact invoices working
+	// against contracts deployed before partial payments
+	var data []byte
+	switch {
+	case contentHash != nil:
+		data, err = s.parsedABI.Pack("createInvoiceWithContent", merchant, amountWei, expiresAt, allowPartial, *contentHash)
+	case allowPartial:
+		data, err = s.parsedABI.Pack("createInvoiceWithOptions", merchant, amountWei, expiresAt, true)
+	default:
+		data, err = s.parsedABI.Pack("createInvoice", merchant, amountWei, expiresAt)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to pack data: %v", err)
 	}
@@ -170,7 +384,7 @@ func (s *invoiceService) createInvoiceOnChain(merchant common.Address, amountWei
 	gasLimit := uint64(300000)
 	tx := types.NewTransaction(nonce, contractAddr, big.NewInt(0), gasLimit, gasPrice, data)
 
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := s.signer.SignTx(ctx, tx, chainID)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign tx: %v", err)
 	}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// maxTaxRateBps caps line item tax rates at 100%.
+const maxTaxRateBps = 10000
+
+var (
+	// ErrInvalidLineItems is returned for malformed line items, discounts
+	// or shipping.
+	ErrInvalidLineItems = errors.New("invalid line items")
+	// ErrAmountRequired is returned when neither an amount nor line items
+	// were given, or both were.
+	ErrAmountRequired = errors.New("exactly one of amount_eth and line_items is required")
+
+	decimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+)
+
+// LineItemParams is one line of an itemized invoice. Prices are decimal
+// ETH strings so that they convert to wei exactly.
+type LineItemParams struct {
+	Description  string
+	Quantity     string
+	UnitPriceETH string
+	TaxRateBps   int
+}
+
+// invoiceContent is the document whose keccak256 hash is an itemized
+// invoice's content hash. Field order is part of the format.
+type invoiceContent struct {
+	Merchant        string        `json:"merchant"`
+	LineItems       []itemContent `json:"line_items"`
+	SubtotalWei     string        `json:"subtotal_wei"`
+	DiscountWei     string        `json:"discount_wei"`
+	TaxWei          string        `json:"tax_wei"`
+	ShippingWei     string        `json:"shipping_wei"`
+	UniqueOffsetWei string        `json:"unique_offset_wei,omitempty"`
+	TotalWei        string        `json:"total_wei"`
+}
+
+type itemContent struct {
+	Description  string `json:"description"`
+	Quantity     string `json:"quantity"`
+	UnitPriceWei string `json:"unit_price_wei"`
+	TaxRateBps   int    `json:"tax_rate_bps"`
+	TotalWei     string `json:"total_wei"`
+}
+
+// itemizeInvoice computes the breakdown of an itemized invoice, stores it
+// with its total and content hash on the invoice and returns the total.
+// The discount is taken off before tax and shared between the lines in
+// proportion to their subtotals; shipping is not taxed.
+func itemizeInvoice(invoice *models.Invoice, params CreateInvoiceParams) (*big.Int, error) {
+	discount, err := parseOptionalETH(params.DiscountETH, "discount_eth")
+	if err != nil {
+		return nil, err
+	}
+	shipping, err := parseOptionalETH(params.ShippingETH, "shipping_eth")
+	if err != nil {
+		return nil, err
+	}
+	if params.DiscountBps < 0 || params.DiscountBps > 10000 {
+		return nil, fmt.Errorf("%w: discount_bps must be between 0 and 10000", ErrInvalidLineItems)
+	}
+	if params.DiscountBps > 0 && discount.Sign() > 0 {
+		return nil, fmt.Errorf("%w: give discount_eth or discount_bps, not both", ErrInvalidLineItems)
+	}
+
+	items := make([]models.LineItem, len(params.LineItems))
+	subtotal := new(big.Int)
+	for i, p := range params.LineItems {
+		if strings.TrimSpace(p.Description) == "" {
+			return nil, fmt.Errorf("%w: line item %d has no description", ErrInvalidLineItems, i+1)
+		}
+		if p.TaxRateBps < 0 || p.TaxRateBps > maxTaxRateBps {
+			return nil, fmt.Errorf("%w: line item %d tax_rate_bps must be between 0 and %d", ErrInvalidLineItems, i+1, maxTaxRateBps)
+		}
+		if !decimalPattern.MatchString(p.Quantity) {
+			return nil, fmt.Errorf("%w: line item %d quantity must be a decimal number", ErrInvalidLineItems, i+1)
+		}
+		quantity, _ := new(big.Rat).SetString(p.Quantity)
+		price, err := parseETH(p.UnitPriceETH)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line item %d unit_price_eth: %v", ErrInvalidLineItems, i+1, err)
+		}
+
+		// Fractional quantities round down to whole wei
+		amount := new(big.Rat).Mul(quantity, new(big.Rat).SetInt(price))
+		lineSubtotal := new(big.Int).Quo(amount.Num(), amount.Denom())
+		if lineSubtotal.Sign() <= 0 {
+			return nil, fmt.Errorf("%w: line item %d amounts to nothing", ErrInvalidLineItems, i+1)
+		}
+		subtotal.Add(subtotal, lineSubtotal)
+		items[i] = models.LineItem{
+			Position:     i + 1,
+			Description:  p.Description,
+			Quantity:     p.Quantity,
+			UnitPriceWei: price.String(),
+			TaxRateBps:   p.TaxRateBps,
+			SubtotalWei:  lineSubtotal.String(),
+		}
+	}
+
+	if params.DiscountBps > 0 {
+		discount.Mul(subtotal, big.NewInt(int64(params.DiscountBps)))
+		discount.Quo(discount, big.NewInt(10000))
+	}
+	if discount.Cmp(subtotal) > 0 {
+		return nil, fmt.Errorf("%w: discount exceeds the subtotal", ErrInvalidLineItems)
+	}
+
+	tax := new(big.Int)
+	allocated := new(big.Int)
+	for i := range items {
+		item := &items[i]
+		lineSubtotal, _ := new(big.Int).SetString(item.SubtotalWei, 10)
+
+		// The last line takes the rounding remainder of the discount
+		lineDiscount := new(big.Int)
+		if i == len(items)-1 {
+			lineDiscount.Sub(discount, allocated)
+		} else {
+			lineDiscount.Mul(discount, lineSubtotal).Quo(lineDiscount, subtotal)
+		}
+		allocated.Add(allocated, lineDiscount)
+
+		taxable := new(big.Int).Sub(lineSubtotal, lineDiscount)
+		lineTax := new(big.Int).Mul(taxable, big.NewInt(int64(item.TaxRateBps)))
+		lineTax.Quo(lineTax, big.NewInt(10000))
+		tax.Add(tax, lineTax)
+
+		item.DiscountWei = lineDiscount.String()
+		item.TaxWei = lineTax.String()
+		item.TotalWei = new(big.Int).Add(taxable, lineTax).String()
+	}
+
+	total := new(big.Int).Sub(subtotal, discount)
+	total.Add(total, tax).Add(total, shipping)
+	if total.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: invoice total must be positive", ErrInvalidLineItems)
+	}
+
+	subtotalWei, discountWei, taxWei, shippingWei := subtotal.String(), discount.String(), tax.String(), shipping.String()
+	invoice.LineItems = items
+	invoice.SubtotalWei = &subtotalWei
+	invoice.DiscountWei = &discountWei
+	invoice.TaxWei = &taxWei
+	invoice.ShippingWei = &shippingWei
+	invoice.AmountWei = total.String()
+	if err := hashContent(invoice); err != nil {
+		return nil, err
+	}
+	return total, nil
+}
+
+// hashContent stores the content hash of an itemized invoice for its
+// current amount_wei. Whatever amount_wei exceeds the itemized total by,
+// i.e. a transfer invoice's unique amount offset, is listed as
+// unique_offset_wei, so the hash must be recomputed whenever the amount
+// changes.
+func hashContent(invoice *models.Invoice) error {
+	content := invoiceContent{
+		Merchant:    invoice.MerchantAddress,
+		LineItems:   make([]itemContent, len(invoice.LineItems)),
+		SubtotalWei: *invoice.SubtotalWei,
+		DiscountWei: *invoice.DiscountWei,
+		TaxWei:      *invoice.TaxWei,
+		ShippingWei: *invoice.ShippingWei,
+		TotalWei:    invoice.AmountWei,
+	}
+	for i, item := range invoice.LineItems {
+		content.LineItems[i] = itemContent{
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			UnitPriceWei: item.UnitPriceWei,
+			TaxRateBps:   item.TaxRateBps,
+			TotalWei:     item.TotalWei,
+		}
+	}
+
+	itemized := new(big.Int)
+	for _, value := range []string{content.SubtotalWei, content.TaxWei, content.ShippingWei} {
+		n, _ := new(big.Int).SetString(value, 10)
+		itemized.Add(itemized, n)
+	}
+	discount, _ := new(big.Int).SetString(content.DiscountWei, 10)
+	itemized.Sub(itemized, discount)
+	total, ok := new(big.Int).SetString(invoice.AmountWei, 10)
+	if !ok || total.Cmp(itemized) < 0 {
+		return fmt.Errorf("invoice amount %q is below its itemized total %s", invoice.AmountWei, itemized)
+	}
+	if offset := total.Sub(total, itemized); offset.Sign() > 0 {
+		content.UniqueOffsetWei = offset.String()
+	}
+
+	// Compact JSON without HTML escaping, so that anyone can rebuild the
+	// document from the API response and check the hash
+	var encoded bytes.Buffer
+	enc := json.NewEncoder(&encoded)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(content); err != nil {
+		return err
+	}
+	contentHash := crypto.Keccak256Hash(bytes.TrimSuffix(encoded.Bytes(), []byte("\n"))).Hex()
+	invoice.ContentHash = &contentHash
+	return nil
+}
+
+// parseETH converts a decimal ETH string to wei without rounding.
+func parseETH(value string) (*big.Int, error) {
+	if !decimalPattern.MatchString(value) {
+		return nil, fmt.Errorf("%q is not a decimal number", value)
+	}
+	whole, frac, _ := strings.Cut(value, ".")
+	if len(frac) > 18 {
+		return nil, fmt.Errorf("%q has more than 18 decimals", value)
+	}
+	wei, _ := new(big.Int).SetString(whole+frac+strings.Repeat("0", 18-len(frac)), 10)
+	return wei, nil
+}
+
+func parseOptionalETH(value string, field string) (*big.Int, error) {
+	if value == "" {
+		return new(big.Int), nil
+	}
+	wei, err := parseETH(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidLineItems, field, err)
+	}
+	return wei, nil
+}
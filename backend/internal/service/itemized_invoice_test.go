@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+const testMerchant = "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+
+func TestItemizeInvoice(t *testing.T) {
+	type line struct{ subtotal, discount, tax, total string }
+	tests := []struct {
+		name   string
+		params CreateInvoiceParams
+		lines  []line
+		// subtotal, discount, tax, shipping and total in wei
+		subtotal, discount, tax, shipping, total string
+	}{
+		{
+			name: "discount before tax, shipping untaxed",
+			params: CreateInvoiceParams{
+				LineItems: []LineItemParams{
+					{Description: "Widget", Quantity: "2", UnitPriceETH: "0.1", TaxRateBps: 1000},
+					{Description: "Service", Quantity: "1", UnitPriceETH: "0.3"},
+				},
+				DiscountBps: 1000,
+				ShippingETH: "0.01",
+			},
+			lines: []line{
+				{subtotal: "200000000000000000", discount: "20000000000000000", tax: "18000000000000000", total: "198000000000000000"},
+				{subtotal: "300000000000000000", discount: "30000000000000000", tax: "0", total: "270000000000000000"},
+			},
+			subtotal: "500000000000000000", discount: "50000000000000000", tax: "18000000000000000",
+			shipping: "10000000000000000", total: "478000000000000000",
+		},
+		{
+			name: "last line takes the discount remainder",
+			params: CreateInvoiceParams{
+				LineItems: []LineItemParams{
+					{Description: "a", Quantity: "1", UnitPriceETH: "0.000000000000000001"},
+					{Description: "b", Quantity: "1", UnitPriceETH: "0.000000000000000001"},
+					{Description: "c", Quantity: "1", UnitPriceETH: "0.00000000000000001"},
+				},
+				DiscountETH: "0.000000000000000005",
+			},
+			lines: []line{
+				{subtotal: "1", discount: "0", tax: "0", total: "1"},
+				{subtotal: "1", discount: "0", tax: "0", total: "1"},
+				{subtotal: "10", discount: "5", tax: "0", total: "5"},
+			},
+			subtotal: "12", discount: "5", tax: "0", shipping: "0", total: "7",
+		},
+		{
+			name: "fractional quantity and tax round down",
+			params: CreateInvoiceParams{
+				LineItems: []LineItemParams{
+					{Description: "Hours", Quantity: "0.333", UnitPriceETH: "0.00000000000000001", TaxRateBps: 1999},
+				},
+			},
+			lines:    []line{{subtotal: "3", discount: "0", tax: "0", total: "3"}},
+			subtotal: "3", discount: "0", tax: "0", shipping: "0", total: "3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := &models.Invoice{MerchantAddress: testMerchant}
+			total, err := itemizeInvoice(invoice, tt.params)
+			if err != nil {
+				t.Fatalf("itemizeInvoice: %v", err)
+			}
+			if total.String() != tt.total || invoice.AmountWei != tt.total {
+				t.Errorf("total = %s, amount_wei = %s, want %s", total, invoice.AmountWei, tt.total)
+			}
+			for name, got := range map[string][2]string{
+				"subtotal": {*invoice.SubtotalWei, tt.subtotal},
+				"discount": {*invoice.DiscountWei, tt.discount},
+				"tax":      {*invoice.TaxWei, tt.tax},
+				"shipping": {*invoice.ShippingWei, tt.shipping},
+			} {
+				if got[0] != got[1] {
+					t.Errorf("%s = %s, want %s", name, got[0], got[1])
+				}
+			}
+			if len(invoice.LineItems) != len(tt.lines) {
+				t.Fatalf("got %d line items, want %d", len(invoice.LineItems), len(tt.lines))
+			}
+			for i, want := range tt.lines {
+				item := invoice.LineItems[i]
+				got := line{subtotal: item.SubtotalWei, discount: item.DiscountWei, tax: item.TaxWei, total: item.TotalWei}
+				if got != want {
+					t.Errorf("line %d = %+v, want %+v", i+1, got, want)
+				}
+				if item.Position != i+1 {
+					t.Errorf("line %d position = %d", i+1, item.Position)
+				}
+			}
+			if invoice.ContentHash == nil {
+				t.Error("content hash not set")
+			}
+		})
+	}
+}
+
+func TestItemizeInvoiceRejects(t *testing.T) {
+	item := LineItemParams{Description: "Widget", Quantity: "1", UnitPriceETH: "0.1"}
+	tests := []struct {
+		name   string
+		params CreateInvoiceParams
+	}{
+		{"no description", CreateInvoiceParams{LineItems: []LineItemParams{{Quantity: "1", UnitPriceETH: "0.1"}}}},
+		{"bad quantity", CreateInvoiceParams{LineItems: []LineItemParams{{Description: "x", Quantity: "1e3", UnitPriceETH: "0.1"}}}},
+		{"too many decimals", CreateInvoiceParams{LineItems: []LineItemParams{{Description: "x", Quantity: "1", UnitPriceETH: "0.0000000000000000001"}}}},
+		{"tax above 100%", CreateInvoiceParams{LineItems: []LineItemParams{{Description: "x", Quantity: "1", UnitPriceETH: "0.1", TaxRateBps: 10001}}}},
+		{"amounts to nothing", CreateInvoiceParams{LineItems: []LineItemParams{{Description: "x", Quantity: "0.1", UnitPriceETH: "0.000000000000000001"}}}},
+		{"both discounts", CreateInvoiceParams{LineItems: []LineItemParams{item}, DiscountETH: "0.01", DiscountBps: 100}},
+		{"discount above subtotal", CreateInvoiceParams{LineItems: []LineItemParams{item}, DiscountETH: "0.2"}},
+		{"discount bps out of range", CreateInvoiceParams{LineItems: []LineItemParams{item}, DiscountBps: 10001}},
+		{"nothing to pay", CreateInvoiceParams{LineItems: []LineItemParams{item}, DiscountBps: 10000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := itemizeInvoice(&models.Invoice{MerchantAddress: testMerchant}, tt.params)
+			if !errors.Is(err, ErrInvalidLineItems) {
+				t.Errorf("err = %v, want ErrInvalidLineItems", err)
+			}
+		})
+	}
+}
+
+func TestHashContent(t *testing.T) {
+	invoice := &models.Invoice{MerchantAddress: testMerchant}
+	_, err := itemizeInvoice(invoice, CreateInvoiceParams{
+		LineItems:   []LineItemParams{{Description: "Tea & <cake>", Quantity: "1.5", UnitPriceETH: "0.02", TaxRateBps: 500}},
+		ShippingETH: "0.001",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := `{"merchant":"` + testMerchant + `","line_items":[{"description":"Tea & <cake>","quantity":"1.5","unit_price_wei":"20000000000000000","tax_rate_bps":500,"total_wei":"31500000000000000"}],` +
+		`"subtotal_wei":"30000000000000000","discount_wei":"0","tax_wei":"1500000000000000","shipping_wei":"1000000000000000"`
+	if want := crypto.Keccak256Hash([]byte(document + `,"total_wei":"32500000000000000"}`)).Hex(); *invoice.ContentHash != want {
+		t.Errorf("content hash = %s, want %s", *invoice.ContentHash, want)
+	}
+
+	// A transfer invoice's unique amount offset is part of the content
+	invoice.AmountWei = "32500000000000003"
+	if err := hashContent(invoice); err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.Keccak256Hash([]byte(document + `,"unique_offset_wei":"3","total_wei":"32500000000000003"}`)).Hex(); *invoice.ContentHash != want {
+		t.Errorf("content hash with offset = %s, want %s", *invoice.ContentHash, want)
+	}
+
+	invoice.AmountWei = "32499999999999999"
+	if err := hashContent(invoice); err == nil {
+		t.Error("amount below the itemized total was hashed")
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxLogoBytes caps the size of a merchant logo, which is embedded in
+// every document.
+const maxLogoBytes = 256 << 10
+
+// ErrInvalidProfile is returned for malformed merchant profile fields.
+var ErrInvalidProfile = errors.New("invalid merchant profile")
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// MerchantService manages the details and branding merchants show on their
+// documents.
+type MerchantService interface {
+	// GetProfile returns the merchant's profile, or an empty one if it has
+	// none.
+	GetProfile(ctx context.Context, merchant string) (*models.MerchantProfile, error)
+	SaveProfile(ctx context.Context, merchant string, params MerchantProfileParams) (*models.MerchantProfile, error)
+}
+
+// MerchantProfileParams replace the whole profile; empty fields are
+// cleared.
+type MerchantProfileParams struct {
+	Name          string
+	PostalAddress string
+	Email         string
+	Website       string
+	TaxID         string
+	Logo          []byte
+	AccentColor   string
+	Template      models.DocumentTemplate
+	FooterText    string
+	Currency      string
+	SuccessURL    string
+	ExpiredURL    string
+}
+
+type merchantService struct {
+	repo repository.MerchantRepository
+}
+
+func NewMerchantService(repo repository.MerchantRepository) MerchantService {
+	return &merchantService{repo: repo}
+}
+
+func (s *merchantService) GetProfile(ctx context.Context, merchant string) (*models.MerchantProfile, error) {
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidProfile)
+	}
+	return findProfile(ctx, s.repo, merchant)
+}
+
+func (s *merchantService) SaveProfile(ctx context.Context, merchant string, params MerchantProfileParams) (*models.MerchantProfile, error) {
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant is not an address", ErrInvalidProfile)
+	}
+	if params.Email != "" {
+		if _, err := mail.ParseAddress(params.Email); err != nil {
+			return nil, fmt.Errorf("%w: email is not valid", ErrInvalidProfile)
+		}
+	}
+	if len(params.Logo) > maxLogoBytes {
+		return nil, fmt.Errorf("%w: logo must be at most %d KiB", ErrInvalidProfile, maxLogoBytes>>10)
+	}
+	if len(params.Logo) > 0 {
+		if kind := http.DetectContentType(params.Logo); kind != "image/png" && kind != "image/jpeg" {
+			return nil, fmt.Errorf("%w: logo must be a PNG or JPEG image", ErrInvalidProfile)
+		}
+	}
+	if params.AccentColor != "" && !colorPattern.MatchString(params.AccentColor) {
+		return nil, fmt.Errorf("%w: accent_color must look like #1A2B3C", ErrInvalidProfile)
+	}
+	template := params.Template
+	switch template {
+	case "":
+		template = models.TemplateClassic
+	case models.TemplateClassic, models.TemplateMinimal:
+	default:
+		return nil, fmt.Errorf("%w: template must be classic or minimal", ErrInvalidProfile)
+	}
+	currency := strings.ToUpper(params.Currency)
+	if currency != "" && !currencyPattern.MatchString(currency) {
+		return nil, fmt.Errorf("%w: currency must be a three-letter currency code", ErrInvalidProfile)
+	}
+	for _, redirect := range []string{params.SuccessURL, params.ExpiredURL} {
+		if redirect != "" && !validRedirectURL(redirect) {
+			return nil, fmt.Errorf("%w: success_url and expired_url must be absolute http(s) URLs", ErrInvalidProfile)
+		}
+	}
+
+	profile := &models.MerchantProfile{
+		MerchantAddress: merchant,
+		Name:            strings.TrimSpace(params.Name),
+		PostalAddress:   strings.TrimSpace(params.PostalAddress),
+		Email:           params.Email,
+		Website:         params.Website,
+		TaxID:           params.TaxID,
+		Logo:            params.Logo,
+		AccentColor:     strings.ToUpper(params.AccentColor),
+		Template:        template,
+		FooterText:      params.FooterText,
+		Currency:        currency,
+		SuccessURL:      params.SuccessURL,
+		ExpiredURL:      params.ExpiredURL,
+	}
+	if err := s.repo.SaveProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithField(logging.FieldMerchant, profile.MerchantAddress).Info("Merchant profile saved")
+	return profile, nil
+}
+
+// findProfile loads the merchant's profile, defaulting to an empty one.
+func findProfile(ctx context.Context, repo repository.MerchantRepository, merchant string) (*models.MerchantProfile, error) {
+	profile, err := repo.FindProfile(ctx, merchant)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.MerchantProfile{
+			MerchantAddress: strings.ToLower(merchant),
+			Template:        models.TemplateClassic,
+		}, nil
+	}
+	return profile, err
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/checkout"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/funds"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxPaymentLinkPage caps the links returned by one List call.
+	maxPaymentLinkPage = 100
+	// maxMetadata bounds the metadata entries of links and subscriptions.
+	maxMetadata = 50
+)
+
+var (
+	// ErrPaymentLinkNotFound is returned for unknown payment link IDs.
+	ErrPaymentLinkNotFound = errors.New("payment link not found")
+	// ErrInvalidPaymentLink is returned for malformed payment link fields.
+	ErrInvalidPaymentLink = errors.New("invalid payment link")
+	// ErrLinkUnavailable is returned when opening a link that is disabled,
+	// expired or used up.
+	ErrLinkUnavailable = errors.New("payment link is no longer available")
+	// ErrLinkAmountRequired is returned when opening a link whose payer
+	// chooses the amount without one.
+	ErrLinkAmountRequired = errors.New("amount is required")
+	// ErrInvalidLinkAmount is returned for payer-chosen amounts that are
+	// malformed or out of the link's bounds.
+	ErrInvalidLinkAmount = errors.New("invalid amount")
+)
+
+// PaymentLinkService manages payment links and creates invoices from them.
+type PaymentLinkService interface {
+	Create(ctx context.Context, params PaymentLinkParams) (*models.PaymentLink, error)
+	Get(ctx context.Context, id string) (*models.PaymentLink, error)
+	// List returns a page of links, only the merchant's unless merchant is
+	// empty.
+	List(ctx context.Context, merchant string, limit int, offset int) ([]models.PaymentLink, error)
+	Update(ctx context.Context, id string, params PaymentLinkParams) (*models.PaymentLink, error)
+	Stats(ctx context.Context, id string) (*models.PaymentLinkStats, error)
+	// Page is the link's landing page: its amount or a form for the payer's
+	// choice, or why the link cannot be opened.
+	Page(ctx context.Context, id string) (*checkout.LinkPage, error)
+	// Open creates a new invoice from the link. amount is the payer's
+	// choice, in the link's currency, and ignored for fixed amounts.
+	Open(ctx context.Context, id string, amount string) (*models.Invoice, error)
+}
+
+// PaymentLinkParams holds a link's settings. An empty Amount lets the payer
+// choose.
+type PaymentLinkParams struct {
+	MerchantAddress string
+	Title           string
+	Description     string
+	Currency        string
+	Amount          string
+	MinAmount       string
+	MaxAmount       string
+	Mode            models.InvoiceMode
+	ExpiryMinutes   int
+	MaxUses         *int
+	ExpiresAt       *time.Time
+	Disabled        bool
+	SuccessURL      string
+	ExpiredURL      string
+	Metadata        map[string]string
+}
+
+type paymentLinkService struct {
+	repo      repository.PaymentLinkRepository
+	merchants repository.MerchantRepository
+	invoices  InvoiceService
+	rates     *fiat.Rates
+	funds     *funds.Monitor
+	config    *config.Config
+}
+
+func NewPaymentLinkService(repo repository.PaymentLinkRepository, merchants repository.MerchantRepository, invoices InvoiceService, rates *fiat.Rates, fundsMonitor *funds.Monitor, cfg *config.Config) PaymentLinkService {
+	return &paymentLinkService{
+		funds:     fundsMonitor,
+		repo:      repo,
+		merchants: merchants,
+		invoices:  invoices,
+		rates:     rates,
+		config:    cfg,
+	}
+}
+
+func (s *paymentLinkService) Create(ctx context.Context, params PaymentLinkParams) (*models.PaymentLink, error) {
+	merchant := params.MerchantAddress
+	if merchant == "" {
+		merchant = s.config.Payment.Address
+	}
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant_address is not an address", ErrInvalidPaymentLink)
+	}
+	link := &models.PaymentLink{MerchantAddress: common.HexToAddress(merchant).Hex()}
+	if err := s.apply(ctx, link, params); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithField("payment_link_id", link.ID.String()).Info("Payment link created")
+	return link, nil
+}
+
+func (s *paymentLinkService) Get(ctx context.Context, id string) (*models.PaymentLink, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPaymentLinkNotFound
+	}
+	link, err := s.repo.FindByID(ctx, id)
+	return link, paymentLinkNotFound(err)
+}
+
+func (s *paymentLinkService) List(ctx context.Context, merchant string, limit int, offset int) ([]models.PaymentLink, error) {
+	if limit <= 0 || limit > maxPaymentLinkPage {
+		limit = maxPaymentLinkPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.repo.List(ctx, merchant, limit, offset)
+}
+
+func (s *paymentLinkService) Update(ctx context.Context, id string, params PaymentLinkParams) (*models.PaymentLink, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(ctx, link, params); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, link); err != nil {
+		return nil, paymentLinkNotFound(err)
+	}
+	return link, nil
+}
+
+func (s *paymentLinkService) Stats(ctx context.Context, id string) (*models.PaymentLinkStats, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPaymentLinkNotFound
+	}
+	stats, err := s.repo.Stats(ctx, id)
+	return stats, paymentLinkNotFound(err)
+}
+
+func (s *paymentLinkService) Page(ctx context.Context, id string) (*checkout.LinkPage, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := findProfile(ctx, s.merchants, link.MerchantAddress)
+	if err != nil {
+		return nil, err
+	}
+	page := &checkout.LinkPage{
+		Branding:    branding(profile),
+		LinkID:      link.ID.String(),
+		Title:       link.Title,
+		Description: link.Description,
+		Currency:    link.Currency,
+		FixedAmount: link.Amount,
+		MinAmount:   link.MinAmount,
+		MaxAmount:   link.MaxAmount,
+	}
+	if err := linkAvailable(link); err != nil {
+		page.Unavailable = true
+		page.Error = err.Error()
+	}
+	return page, nil
+}
+
+func (s *paymentLinkService) Open(ctx context.Context, id string, amount string) (_ *models.Invoice, err error) {
+	ctx, span := telemetry.Start(ctx, "PaymentLinkService.Open", attribute.String("payment_link.id", id))
+	defer func() { telemetry.End(span, err) }()
+
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := linkAvailable(link); err != nil {
+		return nil, err
+	}
+
+	price, err := linkAmount(link, amount)
+	if err != nil {
+		return nil, err
+	}
+	amountWei, err := toWei(ctx, s.rates, price, link.Currency)
+	if errors.Is(err, errBelowOneWei) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLinkAmount, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check funds before taking a use, which a rejected invoice would
+	// only hand back afterwards
+	mode := link.Mode
+	if mode == "" {
+		mode = models.InvoiceMode(s.config.Payment.InvoiceMode)
+	}
+	if mode == models.ModeOnchain {
+		if err := s.funds.Allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.ClaimUse(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrLinkUsedUp) {
+			return nil, fmt.Errorf("%w: it has reached its maximum uses", ErrLinkUnavailable)
+		}
+		return nil, err
+	}
+
+	// A single line item keeps the amount exact, which a float amount_eth
+	// would not, and names what was paid for on the invoice
+	description := link.Title
+	if description == "" {
+		description = "Payment"
+	}
+	params := CreateInvoiceParams{
+		MerchantAddress: link.MerchantAddress,
+		ExpiryMinutes:   link.ExpiryMinutes,
+		Mode:            link.Mode,
+		LineItems:       []LineItemParams{{Description: description, Quantity: "1", UnitPriceETH: formatWeiExact(amountWei.String())}},
+		SuccessURL:      link.SuccessURL,
+		ExpiredURL:      link.ExpiredURL,
+		PaymentLinkID:   link.ID.String(),
+	}
+	if link.Currency != currencyETH {
+		params.PriceCurrency = link.Currency
+		params.PriceAmount = link.Amount
+		if params.PriceAmount == "" {
+			params.PriceAmount = strings.TrimSpace(amount)
+		}
+	}
+	invoice, err := s.invoices.CreateInvoice(ctx, params)
+	if err != nil {
+		if releaseErr := s.repo.ReleaseUse(ctx, id); releaseErr != nil {
+			logging.From(ctx, "service").WithError(releaseErr).WithField("payment_link_id", id).Error("Failed to release payment link use")
+		}
+		return nil, err
+	}
+	logging.From(ctx, "service").WithField("payment_link_id", id).WithField(logging.FieldInvoiceID, invoice.ID.String()).Info("Invoice created from payment link")
+	return invoice, nil
+}
+
+// apply validates params into link.
+func (s *paymentLinkService) apply(ctx context.Context, link *models.PaymentLink, params PaymentLinkParams) error {
+	currency, err := priceCurrency(ctx, s.rates, params.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPaymentLink, err)
+	}
+
+	bounds := map[string]string{"amount": params.Amount, "min_amount": params.MinAmount, "max_amount": params.MaxAmount}
+	parsed := map[string]*big.Rat{}
+	for field, value := range bounds {
+		if value == "" {
+			continue
+		}
+		amount, ok := parseAmount(value)
+		if !ok {
+			return fmt.Errorf("%w: %s must be a positive decimal number", ErrInvalidPaymentLink, field)
+		}
+		parsed[field] = amount
+	}
+	if parsed["amount"] != nil && (parsed["min_amount"] != nil || parsed["max_amount"] != nil) {
+		return fmt.Errorf("%w: min_amount and max_amount are only for links without a fixed amount", ErrInvalidPaymentLink)
+	}
+	if parsed["min_amount"] != nil && parsed["max_amount"] != nil && parsed["min_amount"].Cmp(parsed["max_amount"]) > 0 {
+		return fmt.Errorf("%w: min_amount is more than max_amount", ErrInvalidPaymentLink)
+	}
+
+	switch params.Mode {
+	case "", models.ModeOnchain, models.ModeSigned, models.ModeTransfer, models.ModeDeposit:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidPaymentLink, params.Mode)
+	}
+	if params.ExpiryMinutes < 0 {
+		return fmt.Errorf("%w: expiry_minutes must not be negative", ErrInvalidPaymentLink)
+	}
+	if params.MaxUses != nil && *params.MaxUses <= 0 {
+		return fmt.Errorf("%w: max_uses must be positive", ErrInvalidPaymentLink)
+	}
+	for _, redirect := range []string{params.SuccessURL, params.ExpiredURL} {
+		if redirect != "" && !validRedirectURL(redirect) {
+			return fmt.Errorf("%w: success_url and expired_url must be absolute http(s) URLs", ErrInvalidPaymentLink)
+		}
+	}
+	if len(params.Metadata) > maxMetadata {
+		return fmt.Errorf("%w: metadata has more than %d entries", ErrInvalidPaymentLink, maxMetadata)
+	}
+
+	link.Title = strings.TrimSpace(params.Title)
+	link.Description = strings.TrimSpace(params.Description)
+	link.Currency = currency
+	link.Amount = params.Amount
+	link.MinAmount = params.MinAmount
+	link.MaxAmount = params.MaxAmount
+	link.Mode = params.Mode
+	link.ExpiryMinutes = params.ExpiryMinutes
+	link.MaxUses = params.MaxUses
+	link.ExpiresAt = params.ExpiresAt
+	link.Disabled = params.Disabled
+	link.SuccessURL = params.SuccessURL
+	link.ExpiredURL = params.ExpiredURL
+	link.Metadata = params.Metadata
+	return nil
+}
+
+// linkAvailable reports why the link cannot be opened, if it cannot.
+func linkAvailable(link *models.PaymentLink) error {
+	switch {
+	case link.Disabled:
+		return fmt.Errorf("%w: it was disabled by the merchant", ErrLinkUnavailable)
+	case link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt):
+		return fmt.Errorf("%w: it has expired", ErrLinkUnavailable)
+	case link.MaxUses != nil && link.Uses >= *link.MaxUses:
+		return fmt.Errorf("%w: it has reached its maximum uses", ErrLinkUnavailable)
+	}
+	return nil
+}
+
+// linkAmount is the link's fixed amount, or the payer's choice checked
+// against its bounds.
+func linkAmount(link *models.PaymentLink, chosen string) (*big.Rat, error) {
+	if link.Amount != "" {
+		amount, ok := parseAmount(link.Amount)
+		if !ok {
+			return nil, fmt.Errorf("%w: link amount %q", ErrInvalidPaymentLink, link.Amount)
+		}
+		return amount, nil
+	}
+
+	chosen = strings.TrimSpace(chosen)
+	if chosen == "" {
+		return nil, ErrLinkAmountRequired
+	}
+	amount, ok := parseAmount(chosen)
+	if !ok {
+		return nil, fmt.Errorf("%w: enter a positive number, e.g. 10.50", ErrInvalidLinkAmount)
+	}
+	if lower, ok := parseAmount(link.MinAmount); ok && amount.Cmp(lower) < 0 {
+		return nil, fmt.Errorf("%w: the minimum is %s %s", ErrInvalidLinkAmount, link.MinAmount, link.Currency)
+	}
+	if upper, ok := parseAmount(link.MaxAmount); ok && amount.Cmp(upper) > 0 {
+		return nil, fmt.Errorf("%w: the maximum is %s %s", ErrInvalidLinkAmount, link.MaxAmount, link.Currency)
+	}
+	return amount, nil
+}
+
+func paymentLinkNotFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPaymentLinkNotFound
+	}
+	return err
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/eip681"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// paymentURI is the EIP-681 request that pays what is left of the invoice,
+// or "" if it cannot be paid from a link: it is settled or expired, it is
+// signed (paySignedInvoice takes a struct, which EIP-681 cannot express),
+// or its on-chain ID is not known yet.
+func paymentURI(cfg *config.Config, invoice *models.Invoice) string {
+	if invoice.Status != models.StatusPending && invoice.Status != models.StatusPartiallyPaid {
+		return ""
+	}
+	if time.Now().After(invoice.ExpiresAt) {
+		return ""
+	}
+	amount, ok := new(big.Int).SetString(invoice.AmountWei, 10)
+	if !ok {
+		return ""
+	}
+	remaining := new(big.Int).Sub(amount, invoice.PaidWei())
+	if remaining.Sign() <= 0 {
+		return ""
+	}
+
+	chainID := cfg.Ethereum.ChainID
+	switch invoice.Mode {
+	case models.ModeOnchain:
+		if invoice.OnchainInvoiceID == "" {
+			return ""
+		}
+		contract := common.HexToAddress(cfg.Ethereum.ContractAddress)
+		return eip681.Call(contract, chainID, "payInvoice", remaining, eip681.Param{Type: "uint256", Value: invoice.OnchainInvoiceID}).String()
+	case models.ModeTransfer:
+		// Matched by its exact, unique amount
+		return eip681.Transfer(common.HexToAddress(invoice.PaymentAddress), chainID, amount).String()
+	case models.ModeDeposit:
+		if invoice.DepositAddress == nil {
+			return ""
+		}
+		return eip681.Transfer(common.HexToAddress(*invoice.DepositAddress), chainID, remaining).String()
+	}
+	return ""
+}
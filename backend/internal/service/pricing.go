@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+)
+
+// currencyETH prices payment links and plans in ETH itself.
+const currencyETH = "ETH"
+
+// errBelowOneWei is returned for amounts that convert to nothing.
+var errBelowOneWei = errors.New("amount is less than 1 wei")
+
+// priceCurrency normalizes the currency of a payment link or plan: ETH by
+// default, or an ISO 4217 code that has a rate now, so that converting it
+// later does not fail on every use.
+func priceCurrency(ctx context.Context, rates *fiat.Rates, currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == currencyETH {
+		return currencyETH, nil
+	}
+	if !currencyPattern.MatchString(currency) {
+		return "", errors.New("currency must be ETH or a 3-letter ISO 4217 code")
+	}
+	if _, err := rates.Quote(ctx, currency); err != nil {
+		return "", err
+	}
+	return currency, nil
+}
+
+// toWei converts an amount in currency to wei at the current rate,
+// rounding down.
+func toWei(ctx context.Context, rates *fiat.Rates, amount *big.Rat, currency string) (*big.Int, error) {
+	eth := amount
+	if currency != currencyETH {
+		quote, err := rates.Quote(ctx, currency)
+		if err != nil {
+			return nil, err
+		}
+		eth = new(big.Rat).Quo(amount, quote.Rate)
+	}
+	wei := new(big.Rat).Mul(eth, new(big.Rat).SetInt(big.NewInt(1e18)))
+	result := new(big.Int).Quo(wei.Num(), wei.Denom())
+	if result.Sign() <= 0 {
+		return nil, errBelowOneWei
+	}
+	return result, nil
+}
+
+// parseAmount reads a positive decimal amount such as "10.50".
+func parseAmount(value string) (*big.Rat, bool) {
+	if !decimalPattern.MatchString(value) {
+		return nil, false
+	}
+	amount, ok := new(big.Rat).SetString(value)
+	return amount, ok && amount.Sign() > 0
+}
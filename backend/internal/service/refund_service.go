@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/eip681"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/signer"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// refundGas is the gas limit of a refund, a plain transfer to the payer.
+const refundGas = 21000
+
+var (
+	// ErrRefundNotFound is returned for unknown invoice or refund IDs.
+	ErrRefundNotFound = errors.New("not found")
+	// ErrNotRefundable is returned for invoices that received nothing.
+	ErrNotRefundable = errors.New("invoice has no payments to refund")
+	// ErrRefundExceedsPaid is returned when the refund, with the refunds
+	// already requested, would return more than was paid.
+	ErrRefundExceedsPaid = errors.New("refund exceeds the amount paid")
+	// ErrNoRefundAddress is returned when no address was given and the
+	// payer of the invoice is unknown.
+	ErrNoRefundAddress = errors.New("no refund address given and payer is unknown")
+	// ErrInvalidRefund is returned for malformed refund parameters.
+	ErrInvalidRefund = errors.New("invalid refund")
+)
+
+// RefundService returns payments to payers, either from the backend signer
+// or through a transaction the merchant signs.
+type RefundService interface {
+	Create(ctx context.Context, invoiceID string, params CreateRefundParams) (*models.Refund, error)
+	List(ctx context.Context, invoiceID string) ([]models.Refund, error)
+	// SubmitTx records the transaction the merchant sent for a
+	// merchant-signed refund.
+	SubmitTx(ctx context.Context, invoiceID string, refundID string, txHash string) (*models.Refund, error)
+}
+
+// CreateRefundParams holds the refund inputs. Empty values default to the
+// whole remaining amount and the invoice payer.
+type CreateRefundParams struct {
+	AmountWei string
+	ToAddress string
+	Method    models.RefundMethod
+	// OverpaymentID refunds a recorded overpayment; amount and address
+	// default to those of the overpayment.
+	OverpaymentID string
+	Reason        string
+}
+
+type refundService struct {
+	repo     repository.RefundRepository
+	invoices repository.InvoiceRepository
+	config   *config.Config
+	client   chain.Client
+	signer   signer.Signer
+}
+
+func NewRefundService(repo repository.RefundRepository, invoices repository.InvoiceRepository, cfg *config.Config, client chain.Client, txSigner signer.Signer) RefundService {
+	return &refundService{repo: repo, invoices: invoices, config: cfg, client: client, signer: txSigner}
+}
+
+func (s *refundService) Create(ctx context.Context, invoiceID string, params CreateRefundParams) (_ *models.Refund, err error) {
+	ctx, span := telemetry.Start(ctx, "RefundService.Create", attribute.String("invoice.id", invoiceID))
+	defer func() { telemetry.End(span, err) }()
+
+	if _, err := uuid.Parse(invoiceID); err != nil {
+		return nil, ErrRefundNotFound
+	}
+	method := params.Method
+	if method == "" {
+		method = models.RefundBySigner
+	}
+	var amount *big.Int
+	if params.AmountWei != "" {
+		var ok bool
+		amount, ok = new(big.Int).SetString(params.AmountWei, 10)
+		if !ok || amount.Sign() <= 0 {
+			return nil, fmt.Errorf("%w: amount_wei must be a positive integer", ErrInvalidRefund)
+		}
+	}
+	if params.ToAddress != "" && !common.IsHexAddress(params.ToAddress) {
+		return nil, fmt.Errorf("%w: to_address is not an address", ErrInvalidRefund)
+	}
+
+	refund, err := s.repo.Create(ctx, invoiceID, func(invoice *models.Invoice, committed *big.Int) (*models.Refund, error) {
+		switch invoice.Status {
+		case models.StatusPaid, models.StatusPartiallyPaid, models.StatusPartiallyRefunded:
+		case models.StatusExpired:
+			// Partially paid invoices expire with their payments refundable
+			if invoice.PaidWei().Sign() == 0 {
+				return nil, ErrNotRefundable
+			}
+		default:
+			return nil, ErrNotRefundable
+		}
+
+		refund := &models.Refund{Method: method, Status: models.RefundRequested, Reason: params.Reason}
+		to := params.ToAddress
+		value := amount
+		if params.OverpaymentID != "" {
+			overpayment, err := findOverpayment(invoice, params.OverpaymentID)
+			if err != nil {
+				return nil, err
+			}
+			refund.OverpaymentID = &overpayment.ID
+			if to == "" {
+				to = overpayment.PayerAddress
+			}
+			if value == nil {
+				value, _ = new(big.Int).SetString(overpayment.AmountWei, 10)
+			}
+		}
+
+		remaining := new(big.Int).Sub(invoice.PaidWei(), committed)
+		if value == nil {
+			value = remaining
+		}
+		if value.Sign() <= 0 || value.Cmp(remaining) > 0 {
+			return nil, ErrRefundExceedsPaid
+		}
+		if to == "" && invoice.PayerAddress != nil {
+			to = *invoice.PayerAddress
+		}
+		if to == "" {
+			return nil, ErrNoRefundAddress
+		}
+
+		refund.AmountWei = value.String()
+		refund.ToAddress = common.HexToAddress(to).Hex()
+		if method == models.RefundByMerchant {
+			refund.PaymentRequest = eip681.Transfer(common.HexToAddress(refund.ToAddress), s.config.Ethereum.ChainID, value).String()
+		}
+		return refund, nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	metrics.Refunds.WithLabelValues(string(models.RefundRequested)).Inc()
+
+	logger := logging.From(ctx, "service").WithFields(map[string]interface{}{
+		logging.FieldInvoiceID: invoiceID,
+		"refund_id":            refund.ID.String(),
+		"amount_wei":           refund.AmountWei,
+		"method":               string(refund.Method),
+	})
+	if method == models.RefundByMerchant {
+		logger.Info("Refund requested; waiting for the merchant to send it")
+		return refund, nil
+	}
+
+	txHash, err := s.send(ctx, refund)
+	var rpcErr rpc.Error
+	if err != nil && (txHash == "" || errors.As(err, &rpcErr)) {
+		// Nothing was broadcast, or the node rejected the transaction
+		metrics.Refunds.WithLabelValues(string(models.RefundFailed)).Inc()
+		logger.WithError(err).Error("Failed to send refund")
+		if markErr := s.repo.MarkFailed(ctx, refund.ID.String(), err.Error()); markErr != nil {
+			logger.WithError(markErr).Error("Failed to mark refund as failed")
+		}
+		refund.Status = models.RefundFailed
+		refund.Error = err.Error()
+		return refund, nil
+	}
+	metrics.Refunds.WithLabelValues(string(models.RefundSent)).Inc()
+	refund.Status = models.RefundSent
+	refund.TxHash = &txHash
+	if err != nil {
+		// The node may have received the transaction before the call failed
+		// (a timeout or dropped connection), so it stays sent, and counts
+		// against the amount paid, until the refund tracker sees it mined.
+		logger.WithError(err).WithField(logging.FieldTxHash, txHash).Warn("Refund may not have been broadcast; leaving it to the refund tracker")
+		return refund, nil
+	}
+	logger.WithField(logging.FieldTxHash, txHash).Info("Refund sent")
+	return refund, nil
+}
+
+// send pays the refund from the backend signer. The refund is marked sent
+// before the transaction is broadcast, so one that reaches the chain is
+// always tracked; the hash is returned once recorded, even if broadcasting
+// fails.
+func (s *refundService) send(ctx context.Context, refund *models.Refund) (_ string, err error) {
+	ctx, span := telemetry.Start(ctx, "RefundService.send")
+	defer func() { telemetry.End(span, err) }()
+
+	nonce, err := s.client.PendingNonceAt(ctx, s.signer.Address())
+	if err != nil {
+		return "", fmt.Errorf("failed to get nonce: %v", err)
+	}
+	gasPrice, err := s.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to suggest gas price: %v", err)
+	}
+	value, _ := new(big.Int).SetString(refund.AmountWei, 10)
+
+	tx := types.NewTransaction(nonce, common.HexToAddress(refund.ToAddress), value, refundGas, gasPrice, nil)
+	signedTx, err := s.signer.SignTx(ctx, tx, big.NewInt(s.config.Ethereum.ChainID))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign tx: %v", err)
+	}
+	txHash := signedTx.Hash().Hex()
+	if err := s.repo.MarkSent(ctx, refund.ID.String(), txHash); err != nil {
+		return "", fmt.Errorf("failed to record tx: %v", err)
+	}
+	if err := s.client.SendTransaction(ctx, signedTx); err != nil {
+		return txHash, fmt.Errorf("failed to send tx: %w", err)
+	}
+	return txHash, nil
+}
+
+func (s *refundService) List(ctx context.Context, invoiceID string) ([]models.Refund, error) {
+	if _, err := uuid.Parse(invoiceID); err != nil {
+		return nil, ErrRefundNotFound
+	}
+	if _, err := s.invoices.FindByID(ctx, invoiceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	return s.repo.ListByInvoice(ctx, invoiceID)
+}
+
+func (s *refundService) SubmitTx(ctx context.Context, invoiceID string, refundID string, txHash string) (*models.Refund, error) {
+	if _, err := uuid.Parse(refundID); err != nil {
+		return nil, ErrRefundNotFound
+	}
+	hashBytes := common.FromHex(txHash)
+	if !strings.HasPrefix(txHash, "0x") || len(hashBytes) != common.HashLength {
+		return nil, fmt.Errorf("%w: tx_hash is not a transaction hash", ErrInvalidRefund)
+	}
+
+	refund, err := s.repo.FindByID(ctx, refundID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRefundNotFound
+		}
+		return nil, err
+	}
+	if refund.InvoiceID.String() != invoiceID {
+		return nil, ErrRefundNotFound
+	}
+	if refund.Method != models.RefundByMerchant {
+		return nil, fmt.Errorf("%w: only merchant-signed refunds take a transaction hash", ErrInvalidRefund)
+	}
+
+	hash := common.BytesToHash(hashBytes).Hex()
+	if err := s.repo.MarkSent(ctx, refundID, hash); err != nil {
+		return nil, err
+	}
+	metrics.Refunds.WithLabelValues(string(models.RefundSent)).Inc()
+	refund.Status = models.RefundSent
+	refund.TxHash = &hash
+	logging.From(ctx, "service").WithFields(map[string]interface{}{
+		logging.FieldInvoiceID: invoiceID,
+		logging.FieldTxHash:    hash,
+		"refund_id":            refundID,
+	}).Info("Merchant refund transaction recorded")
+	return refund, nil
+}
+
+func findOverpayment(invoice *models.Invoice, id string) (*models.Overpayment, error) {
+	for i := range invoice.Overpayments {
+		if invoice.Overpayments[i].ID.String() == id {
+			overpayment := &invoice.Overpayments[i]
+			if overpayment.Status != models.OverpaymentOpen {
+				return nil, fmt.Errorf("%w: overpayment was already refunded", ErrInvalidRefund)
+			}
+			for _, refund := range invoice.Refunds {
+				if refund.OverpaymentID != nil && *refund.OverpaymentID == overpayment.ID {
+					return nil, fmt.Errorf("%w: overpayment already has a refund in progress", ErrInvalidRefund)
+				}
+			}
+			return overpayment, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: overpayment not found on this invoice", ErrInvalidRefund)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/notify"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+)
+
+// reissueLookback bounds how long ago an invoice may have expired to be
+// re-issued, so that turning REMINDER_REISSUE on does not re-issue old
+// history.
+const reissueLookback = 24 * time.Hour
+
+// ReminderService reminds customers of unpaid invoices before they expire
+// and re-issues the ones that expired anyway.
+type ReminderService interface {
+	// Remind is one pass of the reminder scheduler: it sends the reminders
+	// due at now, then re-issues expired invoices when enabled.
+	Remind(ctx context.Context, now time.Time) error
+	// Notifications returns what was sent about an invoice, oldest first.
+	Notifications(ctx context.Context, invoiceID string) ([]models.InvoiceNotification, error)
+}
+
+type reminderService struct {
+	repo      repository.NotificationRepository
+	customers repository.CustomerRepository
+	invoices  InvoiceService
+	rates     *fiat.Rates
+	channels  []notify.Channel
+	config    *config.Config
+}
+
+func NewReminderService(repo repository.NotificationRepository, customers repository.CustomerRepository, invoices InvoiceService, rates *fiat.Rates, channels []notify.Channel, cfg *config.Config) ReminderService {
+	return &reminderService{
+		repo:      repo,
+		customers: customers,
+		invoices:  invoices,
+		rates:     rates,
+		channels:  channels,
+		config:    cfg,
+	}
+}
+
+func (s *reminderService) Remind(ctx context.Context, now time.Time) (err error) {
+	ctx, span := telemetry.Start(ctx, "ReminderService.Remind")
+	defer func() { telemetry.End(span, err) }()
+
+	offsets := s.config.Reminder.Offsets
+	for i, offset := range offsets {
+		var next time.Duration
+		if i+1 < len(offsets) {
+			next = offsets[i+1]
+		}
+		due, err := s.repo.FindReminderDue(ctx, now, offset, next)
+		if err != nil {
+			return fmt.Errorf("load invoices due a %s reminder: %w", offset, err)
+		}
+		for i := range due {
+			s.send(ctx, notify.Event{Kind: models.NotifyReminder, Invoice: &due[i], ExpiresIn: offset})
+		}
+	}
+
+	if s.config.Reminder.Reissue {
+		expired, err := s.repo.FindReissuable(ctx, now.Add(-reissueLookback), s.config.Reminder.ReissueMax)
+		if err != nil {
+			return fmt.Errorf("load expired invoices: %w", err)
+		}
+		for i := range expired {
+			s.reissue(ctx, &expired[i])
+		}
+	}
+	return nil
+}
+
+func (s *reminderService) Notifications(ctx context.Context, invoiceID string) ([]models.InvoiceNotification, error) {
+	if _, err := uuid.Parse(invoiceID); err != nil {
+		return nil, ErrInvoiceNotFound
+	}
+	if _, err := s.invoices.GetInvoice(ctx, invoiceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvoiceNotFound
+		}
+		return nil, err
+	}
+	return s.repo.ListByInvoice(ctx, invoiceID)
+}
+
+// send delivers the event on every channel that has not had it yet,
+// recording each outcome. Failed sends are not retried: a late reminder is
+// worth less than a missed one.
+func (s *reminderService) send(ctx context.Context, event notify.Event) {
+	logger := logging.From(ctx, "notify").WithFields(logrus.Fields{
+		logging.FieldInvoiceID: event.Invoice.ID.String(),
+		"kind":                 event.Kind,
+	})
+	// Load the display fields and the customer the channels may show
+	if invoice, err := s.invoices.GetInvoice(ctx, event.Invoice.ID.String()); err == nil {
+		event.Invoice = invoice
+	} else {
+		logger.WithError(err).Warn("Failed to reload invoice for notification")
+	}
+	if event.Invoice.CustomerID != nil && event.Customer == nil {
+		customer, err := s.customers.FindByID(ctx, event.Invoice.CustomerID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.WithError(err).Warn("Failed to load customer for notification")
+		}
+		event.Customer = customer
+	}
+
+	for _, channel := range s.channels {
+		record := &models.InvoiceNotification{
+			InvoiceID:     event.Invoice.ID,
+			Kind:          event.Kind,
+			OffsetSeconds: int64(event.ExpiresIn / time.Second),
+			Channel:       channel.Name(),
+			Status:        models.NotificationSending,
+		}
+		claimed, err := s.repo.Claim(ctx, record)
+		if err != nil {
+			logger.WithError(err).WithField("channel", channel.Name()).Error("Failed to record notification")
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := channel.Send(ctx, event); err != nil {
+			logger.WithError(err).WithField("channel", channel.Name()).Warn("Failed to send notification")
+			record.Status = models.NotificationFailed
+			record.Error = err.Error()
+		} else {
+			sentAt := time.Now().UTC()
+			record.Status = models.NotificationSent
+			record.SentAt = &sentAt
+		}
+		if err := s.repo.Finish(ctx, record); err != nil {
+			logger.WithError(err).WithField("channel", channel.Name()).Error("Failed to save notification outcome")
+		}
+	}
+}
+
+// reissue replaces an expired invoice and notifies about the new one. A
+// failure leaves the expired invoice to be tried again on the next pass.
+func (s *reminderService) reissue(ctx context.Context, expired *models.Invoice) {
+	logger := logging.From(ctx, "notify").WithField(logging.FieldInvoiceID, expired.ID.String())
+	params, err := s.reissueParams(ctx, expired)
+	if err != nil {
+		logger.WithError(err).Error("Failed to price re-issued invoice")
+		return
+	}
+	invoice, err := s.invoices.CreateInvoice(ctx, params)
+	if err != nil {
+		logger.WithError(err).Error("Failed to re-issue expired invoice")
+		return
+	}
+	logger.WithField("new_invoice_id", invoice.ID.String()).Info("Expired invoice re-issued")
+	s.send(ctx, notify.Event{Kind: models.NotifyReissued, Invoice: invoice, Replaces: expired})
+}
+
+// reissueParams copies an expired invoice into a new one with an expiry of
+// the same length. Amounts stay exact; a fiat price is converted again at
+// the current rate.
+func (s *reminderService) reissueParams(ctx context.Context, expired *models.Invoice) (CreateInvoiceParams, error) {
+	params := CreateInvoiceParams{
+		MerchantAddress: expired.MerchantAddress,
+		ExpiryMinutes:   max(1, int(expired.ExpiresAt.Sub(expired.CreatedAt).Round(time.Minute)/time.Minute)),
+		Mode:            expired.Mode,
+		AllowPartial:    expired.AllowPartial,
+		ReissueOfID:     expired.ID.String(),
+		ReissueCount:    expired.ReissueCount + 1,
+	}
+	if expired.CustomerID != nil {
+		params.CustomerID = expired.CustomerID.String()
+	}
+	if expired.PaymentLinkID != nil {
+		params.PaymentLinkID = expired.PaymentLinkID.String()
+	}
+	if expired.SuccessURL != nil {
+		params.SuccessURL = *expired.SuccessURL
+	}
+	if expired.ExpiredURL != nil {
+		params.ExpiredURL = *expired.ExpiredURL
+	}
+
+	for _, item := range expired.LineItems {
+		params.LineItems = append(params.LineItems, LineItemParams{
+			Description:  item.Description,
+			Quantity:     item.Quantity,
+			UnitPriceETH: formatWeiExact(item.UnitPriceWei),
+			TaxRateBps:   item.TaxRateBps,
+		})
+	}
+	if len(params.LineItems) == 0 {
+		params.AmountWei = expired.AmountWei
+		if expired.Mode == models.ModeTransfer {
+			// A unique amount is assigned at least one step above the given
+			// one; starting a step below keeps the expired amount if free
+			amount, _ := new(big.Int).SetString(expired.AmountWei, 10)
+			params.AmountWei = amount.Sub(amount, s.config.Payment.TransferAmountStepWei).String()
+		}
+	}
+	if expired.DiscountWei != nil && *expired.DiscountWei != "0" {
+		params.DiscountETH = formatWeiExact(*expired.DiscountWei)
+	}
+	if expired.ShippingWei != nil && *expired.ShippingWei != "0" {
+		params.ShippingETH = formatWeiExact(*expired.ShippingWei)
+	}
+
+	// Prices are only recorded for single item invoices from payment links
+	if expired.PriceCurrency != nil && expired.PriceAmount != nil && len(params.LineItems) == 1 {
+		amount, ok := parseAmount(*expired.PriceAmount)
+		if !ok {
+			return params, fmt.Errorf("invalid price amount %q", *expired.PriceAmount)
+		}
+		amountWei, err := toWei(ctx, s.rates, amount, *expired.PriceCurrency)
+		if err != nil {
+			return params, err
+		}
+		params.LineItems[0].UnitPriceETH = formatWeiExact(amountWei.String())
+		params.PriceCurrency = *expired.PriceCurrency
+		params.PriceAmount = *expired.PriceAmount
+	}
+	return params, nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+// EIP-712 domain of the InvoiceManager contract; must match its
+// EIP712("InvoiceManager", "1") constructor arguments.
+const (
+	eip712DomainName    = "InvoiceManager"
+	eip712DomainVersion = "1"
+)
+
+// SignedInvoiceTypedData builds the EIP-712 payload that the owner signs
+// and InvoiceManager.paySignedInvoice verifies. contentHash is zero for
+// invoices that are not itemized.
+func SignedInvoiceTypedData(chainID int64, contract, merchant common.Address, amountWei, expiresAt *big.Int, salt, contentHash [32]byte) apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"SignedInvoice": {
+				{Name: "merchant", Type: "address"},
+				{Name: "amountWei", Type: "uint256"},
+				{Name: "expiresAt", Type: "uint256"},
+				{Name: "salt", Type: "bytes32"},
+				{Name: "contentHash", Type: "bytes32"},
+			},
+		},
+		PrimaryType: "SignedInvoice",
+		Domain: apitypes.TypedDataDomain{
+			Name:              eip712DomainName,
+			Version:           eip712DomainVersion,
+			ChainId:           math.NewHexOrDecimal256(chainID),
+			VerifyingContract: contract.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"merchant":    merchant.Hex(),
+			"amountWei":   amountWei.String(),
+			"expiresAt":   expiresAt.String(),
+			"salt":        hexutil.Encode(salt[:]),
+			"contentHash": hexutil.Encode(contentHash[:]),
+		},
+	}
+}
+
+// signInvoice signs the invoice off-chain, including its content hash if
+// itemized, and stores the salt, signature and EIP-712 hash on it. The
+// hash is the invoice's identity on-chain: it is emitted in
+// SignedInvoicePaid when the invoice is paid.
+func (s *invoiceService) signInvoice(ctx context.Context, invoice *models.Invoice, merchant common.Address, amountWei, expiresAt *big.Int) error {
+	var salt [32]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return err
+	}
+
+	var contentHash common.Hash
+	if invoice.ContentHash != nil {
+		contentHash = common.HexToHash(*invoice.ContentHash)
+	}
+	data := SignedInvoiceTypedData(s.config.Ethereum.ChainID, common.HexToAddress(s.config.Ethereum.ContractAddress), merchant, amountWei, expiresAt, salt, contentHash)
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return err
+	}
+	sig, err := s.signer.SignTypedData(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	hashHex := hexutil.Encode(hash)
+	saltHex := hexutil.Encode(salt[:])
+	sigHex := hexutil.Encode(sig)
+	invoice.InvoiceHash = &hashHex
+	invoice.Salt = &saltHex
+	invoice.Signature = &sigHex
+	return nil
+}
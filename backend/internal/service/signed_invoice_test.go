@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// contractDigest hashes a signed invoice the way InvoiceManager does:
+// EIP712("InvoiceManager", "1") and hashSignedInvoice.
+func contractDigest(chainID int64, contract, merchant common.Address, amountWei, expiresAt *big.Int, salt, contentHash [32]byte) []byte {
+	word := func(n *big.Int) []byte { return common.LeftPadBytes(n.Bytes(), 32) }
+
+	domainSeparator := crypto.Keccak256(
+		crypto.Keccak256([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)")),
+		crypto.Keccak256([]byte("InvoiceManager")),
+		crypto.Keccak256([]byte("1")),
+		word(big.NewInt(chainID)),
+		common.LeftPadBytes(contract.Bytes(), 32),
+	)
+	structHash := crypto.Keccak256(
+		crypto.Keccak256([]byte("SignedInvoice(address merchant,uint256 amountWei,uint256 expiresAt,bytes32 salt,bytes32 contentHash)")),
+		common.LeftPadBytes(merchant.Bytes(), 32),
+		word(amountWei),
+		word(expiresAt),
+		salt[:],
+		contentHash[:],
+	)
+	return crypto.Keccak256([]byte("\x19\x01"), domainSeparator, structHash)
+}
+
+func TestSignedInvoiceTypedDataMatchesContract(t *testing.T) {
+	contract := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	merchant := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	amount, _ := new(big.Int).SetString("1500000000000000000", 10)
+	expiresAt := big.NewInt(1767225600)
+	salt := common.HexToHash("0x0102030405060708091011121314151617181920212223242526272829303132")
+	contentHash := crypto.Keccak256Hash([]byte(`{"merchant":"0x70997970C51812dc3A010C7d01b50e0d17dc79C8"}`))
+
+	tests := []struct {
+		name        string
+		chainID     int64
+		contentHash common.Hash
+	}{
+		{name: "not itemized", chainID: 31337},
+		{name: "itemized", chainID: 31337, contentHash: contentHash},
+		{name: "other chain", chainID: 11155111, contentHash: contentHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := SignedInvoiceTypedData(tt.chainID, contract, merchant, amount, expiresAt, salt, tt.contentHash)
+			got, _, err := apitypes.TypedDataAndHash(data)
+			if err != nil {
+				t.Fatalf("TypedDataAndHash: %v", err)
+			}
+			want := contractDigest(tt.chainID, contract, merchant, amount, expiresAt, salt, tt.contentHash)
+			if !bytes.Equal(got, want) {
+				t.Errorf("digest = %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestSignedInvoiceTypedDataCommitsToContentHash(t *testing.T) {
+	contract := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	merchant := common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8")
+	amount, expiresAt := big.NewInt(1000), big.NewInt(1767225600)
+	var salt common.Hash
+
+	plain, _, err := apitypes.TypedDataAndHash(SignedInvoiceTypedData(1, contract, merchant, amount, expiresAt, salt, common.Hash{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemized, _, err := apitypes.TypedDataAndHash(SignedInvoiceTypedData(1, contract, merchant, amount, expiresAt, salt, common.Hash{1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(plain, itemized) {
+		t.Error("digest does not depend on the content hash")
+	}
+}
@@ -0,0 +1,594 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/fiat"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxSubscriptionPage caps the plans or subscriptions returned by one
+	// List call.
+	maxSubscriptionPage = 100
+	// maxIntervalCount and maxTrialDays bound plans to sane schedules.
+	maxIntervalCount = 100
+	maxTrialDays     = 365
+)
+
+var (
+	// ErrPlanNotFound is returned for unknown plan IDs.
+	ErrPlanNotFound = errors.New("plan not found")
+	// ErrInvalidPlan is returned for malformed plan fields.
+	ErrInvalidPlan = errors.New("invalid plan")
+	// ErrSubscriptionNotFound is returned for unknown subscription IDs.
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+	// ErrInvalidSubscription is returned for malformed subscription fields.
+	ErrInvalidSubscription = errors.New("invalid subscription")
+	// ErrSubscriptionState is returned when pausing, resuming or canceling
+	// a subscription whose status does not allow it.
+	ErrSubscriptionState = errors.New("subscription cannot be changed in its current status")
+)
+
+// SubscriptionService manages plans and subscriptions, and bills them.
+type SubscriptionService interface {
+	CreatePlan(ctx context.Context, params PlanParams) (*models.SubscriptionPlan, error)
+	GetPlan(ctx context.Context, id string) (*models.SubscriptionPlan, error)
+	ListPlans(ctx context.Context, merchant string, limit int, offset int) ([]models.SubscriptionPlan, error)
+	// UpdatePlan changes what the plan's subscriptions are billed from
+	// their next invoice on.
+	UpdatePlan(ctx context.Context, id string, params PlanParams) (*models.SubscriptionPlan, error)
+
+	Create(ctx context.Context, params SubscriptionParams) (*models.Subscription, error)
+	Get(ctx context.Context, id string) (*models.Subscription, error)
+	List(ctx context.Context, customerID string, status models.SubscriptionStatus, limit int, offset int) ([]models.Subscription, error)
+	// Pause stops billing until Resume; the paused time is not billed.
+	Pause(ctx context.Context, id string) (*models.Subscription, error)
+	// Resume restarts billing of a paused or unpaid subscription. A period
+	// that was left unpaid is not invoiced again.
+	Resume(ctx context.Context, id string) (*models.Subscription, error)
+	// Cancel ends the subscription now, or when its current period ends.
+	Cancel(ctx context.Context, id string, atPeriodEnd bool) (*models.Subscription, error)
+	// Invoices returns the subscription's invoices, newest first.
+	Invoices(ctx context.Context, id string) ([]models.Invoice, error)
+
+	// Bill is one pass of the billing scheduler: it moves subscriptions
+	// whose latest invoice was paid or expired through dunning, then
+	// invoices the periods and retries that are due at now.
+	Bill(ctx context.Context, now time.Time) error
+}
+
+// PlanParams holds a plan's settings.
+type PlanParams struct {
+	MerchantAddress string
+	Name            string
+	Description     string
+	Currency        string
+	Amount          string
+	Interval        models.BillingInterval
+	IntervalCount   int
+	AnchorDay       int
+	TrialDays       int
+	Mode            models.InvoiceMode
+	ExpiryMinutes   int
+	Archived        bool
+}
+
+// SubscriptionParams starts a subscription. Zero values fall back to the
+// plan's settings.
+type SubscriptionParams struct {
+	PlanID     string
+	CustomerID string
+	// AnchorDay overrides the plan's billing day of the month.
+	AnchorDay int
+	// StartAt is when the subscription (or its trial) starts; nil for now.
+	StartAt *time.Time
+	// TrialDays overrides the plan's trial; zero is no trial.
+	TrialDays *int
+	Metadata  map[string]string
+}
+
+type subscriptionService struct {
+	repo      repository.SubscriptionRepository
+	customers repository.CustomerRepository
+	invoices  InvoiceService
+	rates     *fiat.Rates
+	config    *config.Config
+}
+
+func NewSubscriptionService(repo repository.SubscriptionRepository, customers repository.CustomerRepository, invoices InvoiceService, rates *fiat.Rates, cfg *config.Config) SubscriptionService {
+	return &subscriptionService{
+		repo:      repo,
+		customers: customers,
+		invoices:  invoices,
+		rates:     rates,
+		config:    cfg,
+	}
+}
+
+func (s *subscriptionService) CreatePlan(ctx context.Context, params PlanParams) (*models.SubscriptionPlan, error) {
+	merchant := params.MerchantAddress
+	if merchant == "" {
+		merchant = s.config.Payment.Address
+	}
+	if !common.IsHexAddress(merchant) {
+		return nil, fmt.Errorf("%w: merchant_address is not an address", ErrInvalidPlan)
+	}
+	plan := &models.SubscriptionPlan{MerchantAddress: common.HexToAddress(merchant).Hex()}
+	if err := s.applyPlan(ctx, plan, params); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreatePlan(ctx, plan); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, "service").WithField("plan_id", plan.ID.String()).Info("Subscription plan created")
+	return plan, nil
+}
+
+func (s *subscriptionService) GetPlan(ctx context.Context, id string) (*models.SubscriptionPlan, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPlanNotFound
+	}
+	plan, err := s.repo.FindPlan(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPlanNotFound
+	}
+	return plan, err
+}
+
+func (s *subscriptionService) ListPlans(ctx context.Context, merchant string, limit int, offset int) ([]models.SubscriptionPlan, error) {
+	limit, offset = subscriptionPage(limit, offset)
+	return s.repo.ListPlans(ctx, merchant, limit, offset)
+}
+
+func (s *subscriptionService) UpdatePlan(ctx context.Context, id string, params PlanParams) (*models.SubscriptionPlan, error) {
+	plan, err := s.GetPlan(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyPlan(ctx, plan, params); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdatePlan(ctx, plan); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPlanNotFound
+		}
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *subscriptionService) Create(ctx context.Context, params SubscriptionParams) (*models.Subscription, error) {
+	plan, err := s.GetPlan(ctx, params.PlanID)
+	if errors.Is(err, ErrPlanNotFound) {
+		return nil, fmt.Errorf("%w: unknown plan_id", ErrInvalidSubscription)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if plan.Archived {
+		return nil, fmt.Errorf("%w: plan is archived", ErrInvalidSubscription)
+	}
+	if _, err := uuid.Parse(params.CustomerID); err != nil {
+		return nil, ErrCustomerNotFound
+	}
+	customer, err := s.customers.FindByID(ctx, params.CustomerID)
+	if err != nil {
+		return nil, customerNotFound(err)
+	}
+
+	now := time.Now().UTC()
+	start := now
+	if params.StartAt != nil {
+		if params.StartAt.Before(now.Add(-time.Minute)) {
+			return nil, fmt.Errorf("%w: start_at is in the past", ErrInvalidSubscription)
+		}
+		start = params.StartAt.UTC()
+	}
+	if params.AnchorDay < 0 || params.AnchorDay > 31 {
+		return nil, fmt.Errorf("%w: anchor_day must be between 1 and 31", ErrInvalidSubscription)
+	}
+	trialDays := plan.TrialDays
+	if params.TrialDays != nil {
+		trialDays = *params.TrialDays
+	}
+	if trialDays < 0 || trialDays > maxTrialDays {
+		return nil, fmt.Errorf("%w: trial_days must be between 0 and %d", ErrInvalidSubscription, maxTrialDays)
+	}
+	if len(params.Metadata) > maxMetadata {
+		return nil, fmt.Errorf("%w: metadata has more than %d entries", ErrInvalidSubscription, maxMetadata)
+	}
+
+	subscription := &models.Subscription{
+		PlanID:        plan.ID,
+		CustomerID:    customer.ID,
+		Status:        models.SubscriptionActive,
+		NextInvoiceAt: start,
+		Metadata:      params.Metadata,
+	}
+	if plan.Interval == models.IntervalMonth || plan.Interval == models.IntervalYear {
+		subscription.AnchorDay = params.AnchorDay
+		if subscription.AnchorDay == 0 {
+			subscription.AnchorDay = plan.AnchorDay
+		}
+		if subscription.AnchorDay == 0 {
+			subscription.AnchorDay = start.Day()
+		}
+	}
+	if trialDays > 0 {
+		trialEnd := start.AddDate(0, 0, trialDays)
+		subscription.Status = models.SubscriptionTrialing
+		subscription.TrialEndsAt = &trialEnd
+		subscription.NextInvoiceAt = trialEnd
+	}
+
+	if err := s.repo.Create(ctx, subscription); err != nil {
+		return nil, err
+	}
+	subscription.Plan = plan
+	logging.From(ctx, "service").WithFields(logrus.Fields{
+		"subscription_id": subscription.ID.String(),
+		"plan_id":         plan.ID.String(),
+		"customer_id":     customer.ID.String(),
+	}).Info("Subscription created")
+	return subscription, nil
+}
+
+func (s *subscriptionService) Get(ctx context.Context, id string) (*models.Subscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	subscription, err := s.repo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSubscriptionNotFound
+	}
+	return subscription, err
+}
+
+func (s *subscriptionService) List(ctx context.Context, customerID string, status models.SubscriptionStatus, limit int, offset int) ([]models.Subscription, error) {
+	if customerID != "" {
+		if _, err := uuid.Parse(customerID); err != nil {
+			return nil, fmt.Errorf("%w: customer_id is not a UUID", ErrInvalidSubscription)
+		}
+	}
+	limit, offset = subscriptionPage(limit, offset)
+	return s.repo.List(ctx, customerID, status, limit, offset)
+}
+
+func (s *subscriptionService) Pause(ctx context.Context, id string) (*models.Subscription, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := subscription.Status
+	switch previous {
+	case models.SubscriptionTrialing, models.SubscriptionActive, models.SubscriptionPastDue, models.SubscriptionUnpaid:
+	default:
+		return nil, fmt.Errorf("%w: it is %s", ErrSubscriptionState, previous)
+	}
+	now := time.Now().UTC()
+	subscription.Status = models.SubscriptionPaused
+	subscription.PausedAt = &now
+	return subscription, s.transition(ctx, subscription, previous)
+}
+
+func (s *subscriptionService) Resume(ctx context.Context, id string) (*models.Subscription, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := subscription.Status
+	if previous != models.SubscriptionPaused && previous != models.SubscriptionUnpaid {
+		return nil, fmt.Errorf("%w: it is %s", ErrSubscriptionState, previous)
+	}
+
+	now := time.Now().UTC()
+	subscription.Status = models.SubscriptionActive
+	if subscription.TrialEndsAt != nil && subscription.TrialEndsAt.After(now) {
+		subscription.Status = models.SubscriptionTrialing
+	}
+	subscription.PausedAt = nil
+	subscription.RetryCount = 0
+	subscription.NextRetryAt = nil
+	// The missed period is forgiven rather than sent into dunning again
+	if latest := subscription.LatestInvoice; latest != nil && invoiceFailed(latest, now) {
+		subscription.LatestInvoiceID = nil
+		subscription.LatestInvoice = nil
+	}
+	if subscription.NextInvoiceAt.Before(now) {
+		subscription.NextInvoiceAt = now
+	}
+	return subscription, s.transition(ctx, subscription, previous)
+}
+
+func (s *subscriptionService) Cancel(ctx context.Context, id string, atPeriodEnd bool) (*models.Subscription, error) {
+	subscription, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous := subscription.Status
+	if previous == models.SubscriptionCanceled {
+		return nil, fmt.Errorf("%w: it is %s", ErrSubscriptionState, previous)
+	}
+	if atPeriodEnd && (previous == models.SubscriptionTrialing || previous == models.SubscriptionActive) {
+		subscription.CancelAtPeriodEnd = true
+	} else {
+		now := time.Now().UTC()
+		subscription.Status = models.SubscriptionCanceled
+		subscription.CanceledAt = &now
+		subscription.NextRetryAt = nil
+	}
+	return subscription, s.transition(ctx, subscription, previous)
+}
+
+func (s *subscriptionService) Invoices(ctx context.Context, id string) ([]models.Invoice, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListInvoices(ctx, id)
+}
+
+func (s *subscriptionService) Bill(ctx context.Context, now time.Time) (err error) {
+	ctx, span := telemetry.Start(ctx, "SubscriptionService.Bill")
+	defer func() { telemetry.End(span, err) }()
+	logger := logging.From(ctx, "billing")
+
+	// Dunning first, so a subscription whose invoice just expired is
+	// retried rather than billed for its next period
+	settled, err := s.repo.FindSettled(ctx, now)
+	if err != nil {
+		return fmt.Errorf("load settled subscriptions: %w", err)
+	}
+	for i := range settled {
+		if err := s.settle(ctx, &settled[i], now); err != nil {
+			logger.WithError(err).WithField("subscription_id", settled[i].ID.String()).Error("Failed to update subscription after its invoice settled")
+		}
+	}
+
+	due, err := s.repo.FindDue(ctx, now)
+	if err != nil {
+		return fmt.Errorf("load due subscriptions: %w", err)
+	}
+	for i := range due {
+		if err := s.invoicePeriod(ctx, &due[i], now); err != nil {
+			logger.WithError(err).WithField("subscription_id", due[i].ID.String()).Error("Failed to invoice subscription")
+		}
+	}
+	return nil
+}
+
+// settle moves a subscription on once its latest invoice was paid or
+// failed: back to active, or through the dunning retries to unpaid.
+func (s *subscriptionService) settle(ctx context.Context, subscription *models.Subscription, now time.Time) error {
+	previous := subscription.Status
+	entry := logging.From(ctx, "billing").WithFields(logrus.Fields{
+		"subscription_id":      subscription.ID.String(),
+		logging.FieldInvoiceID: subscription.LatestInvoiceID.String(),
+	})
+	switch {
+	case !invoiceFailed(subscription.LatestInvoice, now):
+		subscription.Status = models.SubscriptionActive
+		subscription.RetryCount = 0
+		entry.Info("Past due subscription paid")
+	case subscription.RetryCount < s.config.Billing.Retries:
+		retryAt := now.Add(s.config.Billing.RetryDelay)
+		subscription.Status = models.SubscriptionPastDue
+		subscription.RetryCount++
+		subscription.NextRetryAt = &retryAt
+		entry.WithField("retry", subscription.RetryCount).Warn("Subscription invoice expired unpaid, retrying")
+	default:
+		subscription.Status = models.SubscriptionUnpaid
+		entry.Warn("Subscription invoice expired unpaid, no retries left")
+	}
+	return s.transition(ctx, subscription, previous)
+}
+
+// invoicePeriod issues the invoice for a subscription's next period, or
+// again for its current one when past due. The period is claimed before
+// the invoice is created and given back if that fails, so a crash in
+// between skips a period rather than billing it twice.
+func (s *subscriptionService) invoicePeriod(ctx context.Context, subscription *models.Subscription, now time.Time) error {
+	previous := *subscription
+	var start, end time.Time
+	if subscription.Status == models.SubscriptionPastDue && subscription.CurrentPeriodStart != nil {
+		start, end = *subscription.CurrentPeriodStart, *subscription.CurrentPeriodEnd
+		subscription.NextRetryAt = nil
+	} else {
+		if subscription.CancelAtPeriodEnd {
+			subscription.Status = models.SubscriptionCanceled
+			subscription.CanceledAt = &now
+			return s.transition(ctx, subscription, previous.Status)
+		}
+		start = subscription.NextInvoiceAt
+		end = periodEnd(start, subscription.Plan, subscription.AnchorDay)
+		subscription.Status = models.SubscriptionActive
+		subscription.CurrentPeriodStart = &start
+		subscription.CurrentPeriodEnd = &end
+		subscription.NextInvoiceAt = end
+		subscription.RetryCount = 0
+	}
+	if err := s.repo.UpdateIf(ctx, subscription, previous.Status); err != nil {
+		return err
+	}
+
+	invoice, err := s.issue(ctx, subscription, start, end)
+	if err != nil {
+		if releaseErr := s.repo.UpdateIf(ctx, &previous, subscription.Status); releaseErr != nil {
+			logging.From(ctx, "billing").WithError(releaseErr).WithField("subscription_id", subscription.ID.String()).Error("Failed to release subscription period")
+		}
+		return err
+	}
+	subscription.LatestInvoiceID = &invoice.ID
+	if err := s.repo.Update(ctx, subscription); err != nil {
+		return err
+	}
+	logging.From(ctx, "billing").WithFields(logrus.Fields{
+		"subscription_id":      subscription.ID.String(),
+		logging.FieldInvoiceID: invoice.ID.String(),
+		"period_start":         start,
+	}).Info("Subscription invoiced")
+	return nil
+}
+
+// issue creates the invoice for one period, priced at the current rate.
+func (s *subscriptionService) issue(ctx context.Context, subscription *models.Subscription, start, end time.Time) (*models.Invoice, error) {
+	plan := subscription.Plan
+	amount, ok := parseAmount(plan.Amount)
+	if !ok {
+		return nil, fmt.Errorf("plan %s has an invalid amount %q", plan.ID, plan.Amount)
+	}
+	amountWei, err := toWei(ctx, s.rates, amount, plan.Currency)
+	if err != nil {
+		return nil, err
+	}
+	params := CreateInvoiceParams{
+		MerchantAddress: plan.MerchantAddress,
+		ExpiryMinutes:   plan.ExpiryMinutes,
+		Mode:            plan.Mode,
+		LineItems: []LineItemParams{{
+			Description:  fmt.Sprintf("%s, %s – %s", plan.Name, start.Format("2 Jan 2006"), end.Format("2 Jan 2006")),
+			Quantity:     "1",
+			UnitPriceETH: formatWeiExact(amountWei.String()),
+		}},
+		CustomerID:     subscription.CustomerID.String(),
+		SubscriptionID: subscription.ID.String(),
+	}
+	if plan.Currency != currencyETH {
+		params.PriceCurrency = plan.Currency
+		params.PriceAmount = plan.Amount
+	}
+	return s.invoices.CreateInvoice(ctx, params)
+}
+
+// transition saves a status change made from previous, failing with
+// ErrSubscriptionState if something else changed it meanwhile.
+func (s *subscriptionService) transition(ctx context.Context, subscription *models.Subscription, previous models.SubscriptionStatus) error {
+	err := s.repo.UpdateIf(ctx, subscription, previous)
+	if errors.Is(err, repository.ErrSubscriptionChanged) {
+		return fmt.Errorf("%w: it changed meanwhile, try again", ErrSubscriptionState)
+	}
+	return err
+}
+
+// applyPlan validates params into plan.
+func (s *subscriptionService) applyPlan(ctx context.Context, plan *models.SubscriptionPlan, params PlanParams) error {
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidPlan)
+	}
+	currency, err := priceCurrency(ctx, s.rates, params.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+	}
+	if _, ok := parseAmount(params.Amount); !ok {
+		return fmt.Errorf("%w: amount must be a positive decimal number", ErrInvalidPlan)
+	}
+	switch params.Interval {
+	case models.IntervalDay, models.IntervalWeek, models.IntervalMonth, models.IntervalYear:
+	default:
+		return fmt.Errorf("%w: interval must be day, week, month or year", ErrInvalidPlan)
+	}
+	count := params.IntervalCount
+	if count == 0 {
+		count = 1
+	}
+	if count < 1 || count > maxIntervalCount {
+		return fmt.Errorf("%w: interval_count must be between 1 and %d", ErrInvalidPlan, maxIntervalCount)
+	}
+	if params.AnchorDay < 0 || params.AnchorDay > 31 {
+		return fmt.Errorf("%w: anchor_day must be between 1 and 31", ErrInvalidPlan)
+	}
+	if params.AnchorDay != 0 && params.Interval != models.IntervalMonth && params.Interval != models.IntervalYear {
+		return fmt.Errorf("%w: anchor_day is only for monthly and yearly plans", ErrInvalidPlan)
+	}
+	if params.TrialDays < 0 || params.TrialDays > maxTrialDays {
+		return fmt.Errorf("%w: trial_days must be between 0 and %d", ErrInvalidPlan, maxTrialDays)
+	}
+	switch params.Mode {
+	case "", models.ModeOnchain, models.ModeSigned, models.ModeTransfer, models.ModeDeposit:
+	default:
+		return fmt.Errorf("%w: unknown mode %q", ErrInvalidPlan, params.Mode)
+	}
+	if params.ExpiryMinutes < 0 {
+		return fmt.Errorf("%w: expiry_minutes must not be negative", ErrInvalidPlan)
+	}
+
+	plan.Name = name
+	plan.Description = strings.TrimSpace(params.Description)
+	plan.Currency = currency
+	plan.Amount = params.Amount
+	plan.Interval = params.Interval
+	plan.IntervalCount = count
+	plan.AnchorDay = params.AnchorDay
+	plan.TrialDays = params.TrialDays
+	plan.Mode = params.Mode
+	plan.ExpiryMinutes = params.ExpiryMinutes
+	plan.Archived = params.Archived
+	return nil
+}
+
+// periodEnd is when the period starting at start ends. Monthly and yearly
+// periods end on the anchor day, clamped to the month's length, so a first
+// period starting before the anchor day in its month is shorter than usual.
+func periodEnd(start time.Time, plan *models.SubscriptionPlan, anchorDay int) time.Time {
+	months := plan.IntervalCount
+	switch plan.Interval {
+	case models.IntervalDay:
+		return start.AddDate(0, 0, months)
+	case models.IntervalWeek:
+		return start.AddDate(0, 0, 7*months)
+	case models.IntervalYear:
+		months *= 12
+	}
+	if anchorDay == 0 {
+		anchorDay = start.Day()
+	}
+	if anchorOf(start, 0, anchorDay).After(start) {
+		months--
+	}
+	return anchorOf(start, months, anchorDay)
+}
+
+// anchorOf is the anchor day of the month months after t's, at t's time of
+// day.
+func anchorOf(t time.Time, months int, anchorDay int) time.Time {
+	year, month, _ := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(anchorDay, lastDay)-1)
+}
+
+// invoiceFailed reports whether the invoice can no longer be paid in full.
+// Open invoices past their expiry count even before the expiry checker
+// marks them EXPIRED.
+func invoiceFailed(invoice *models.Invoice, now time.Time) bool {
+	switch invoice.Status {
+	case models.StatusExpired:
+		return true
+	case models.StatusPending, models.StatusPartiallyPaid:
+		return now.After(invoice.ExpiresAt)
+	}
+	return false
+}
+
+func subscriptionPage(limit int, offset int) (int, int) {
+	if limit <= 0 || limit > maxSubscriptionPage {
+		limit = maxSubscriptionPage
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
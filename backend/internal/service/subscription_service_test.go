@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+)
+
+func TestPeriodEnd(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+	plan := func(interval models.BillingInterval, count int) *models.SubscriptionPlan {
+		return &models.SubscriptionPlan{Interval: interval, IntervalCount: count}
+	}
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		plan      *models.SubscriptionPlan
+		anchorDay int
+		want      time.Time
+	}{
+		{"daily", date(2026, 1, 31), plan(models.IntervalDay, 3), 0, date(2026, 2, 3)},
+		{"weekly", date(2026, 12, 29), plan(models.IntervalWeek, 2), 0, date(2027, 1, 12)},
+		{"monthly", date(2026, 3, 15), plan(models.IntervalMonth, 1), 15, date(2026, 4, 15)},
+		{"anchor from start", date(2026, 3, 15), plan(models.IntervalMonth, 1), 0, date(2026, 4, 15)},
+		{"month end clamps to february", date(2026, 1, 31), plan(models.IntervalMonth, 1), 31, date(2026, 2, 28)},
+		{"leap february", date(2028, 1, 31), plan(models.IntervalMonth, 1), 31, date(2028, 2, 29)},
+		{"back to the anchor after a short month", date(2026, 2, 28), plan(models.IntervalMonth, 1), 31, date(2026, 3, 31)},
+		{"thirty day month", date(2026, 3, 31), plan(models.IntervalMonth, 1), 31, date(2026, 4, 30)},
+		{"first period before the anchor is short", date(2026, 1, 10), plan(models.IntervalMonth, 1), 31, date(2026, 1, 31)},
+		{"first period after the anchor", date(2026, 1, 20), plan(models.IntervalMonth, 1), 5, date(2026, 2, 5)},
+		{"quarterly", date(2026, 11, 30), plan(models.IntervalMonth, 3), 30, date(2027, 2, 28)},
+		{"yearly from leap day", date(2028, 2, 29), plan(models.IntervalYear, 1), 29, date(2029, 2, 28)},
+		{"yearly back to leap day", date(2031, 2, 28), plan(models.IntervalYear, 1), 29, date(2032, 2, 29)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodEnd(tt.start, tt.plan, tt.anchorDay); !got.Equal(tt.want) {
+				t.Errorf("periodEnd() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnchorOf(t *testing.T) {
+	at := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	tests := []struct {
+		months, anchorDay int
+		want              time.Time
+	}{
+		{0, 31, time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)},
+		{1, 31, time.Date(2026, 2, 28, 23, 59, 59, 0, time.UTC)},
+		{1, 1, time.Date(2026, 2, 1, 23, 59, 59, 0, time.UTC)},
+		{11, 31, time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{12, 30, time.Date(2027, 1, 30, 23, 59, 59, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := anchorOf(at, tt.months, tt.anchorDay); !got.Equal(tt.want) {
+			t.Errorf("anchorOf(%d, %d) = %s, want %s", tt.months, tt.anchorDay, got, tt.want)
+		}
+	}
+}
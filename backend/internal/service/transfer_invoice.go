@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTransferModeDisabled is returned for transfer invoices when
+	// TRANSFER_MODE_ENABLED is off.
+	ErrTransferModeDisabled = errors.New("transfer payment mode is not enabled")
+	// ErrNoUniqueAmount is returned when every base + epsilon amount is
+	// already taken by a pending invoice.
+	ErrNoUniqueAmount = errors.New("no unique payment amount available for this base amount; try again later or change the amount")
+)
+
+// transferInsertAttempts bounds retries when a concurrent request takes the
+// chosen amount between the lookup and the insert.
+const transferInsertAttempts = 3
+
+// createTransferInvoice stores a transfer invoice with the smallest amount
+// base + k*step (1 <= k <= TRANSFER_MAX_OFFSETS) not used by another pending
+// invoice to the same payment address, rehashing itemized content for it.
+// The partial unique index on (payment_address, amount_wei) is the final
+// arbiter under concurrency.
+func (s *invoiceService) createTransferInvoice(ctx context.Context, invoice *models.Invoice, baseWei *big.Int) error {
+	step := s.config.Payment.TransferAmountStepWei
+	candidates := make([]string, s.config.Payment.TransferMaxOffsets)
+	for k := range candidates {
+		amount := new(big.Int).Mul(step, big.NewInt(int64(k+1)))
+		candidates[k] = amount.Add(amount, baseWei).String()
+	}
+
+	for attempt := 0; attempt < transferInsertAttempts; attempt++ {
+		taken, err := s.repo.FindPendingTransferAmounts(ctx, invoice.PaymentAddress, candidates)
+		if err != nil {
+			return err
+		}
+		used := make(map[string]bool, len(taken))
+		for _, amount := range taken {
+			used[amount] = true
+		}
+
+		invoice.AmountWei = ""
+		for _, amount := range candidates {
+			if !used[amount] {
+				invoice.AmountWei = amount
+				break
+			}
+		}
+		if invoice.AmountWei == "" {
+			return ErrNoUniqueAmount
+		}
+		if invoice.ContentHash != nil {
+			if err := hashContent(invoice); err != nil {
+				return err
+			}
+		}
+
+		err = s.repo.Create(ctx, invoice, s.numbering())
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+		logging.From(ctx, "service").WithField("amount_wei", invoice.AmountWei).Debug("Transfer amount taken concurrently, retrying")
+	}
+	return ErrNoUniqueAmount
+}
+
+// formatWeiExact renders a wei amount as ETH without rounding, e.g.
+// "0.100001". Transfer invoices are matched by exact amount, so the
+// displayed value must be exact.
+func formatWeiExact(wei string) string {
+	n, ok := new(big.Int).SetString(wei, 10)
+	if !ok || n.Sign() < 0 {
+		return wei
+	}
+	digits := n.String()
+	if len(digits) <= 18 {
+		digits = strings.Repeat("0", 19-len(digits)) + digits
+	}
+	whole, frac := digits[:len(digits)-18], strings.TrimRight(digits[len(digits)-18:], "0")
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
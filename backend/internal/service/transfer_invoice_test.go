@@ -0,0 +1,27 @@
+package service
+
+import "testing"
+
+func TestFormatWeiExact(t *testing.T) {
+	tests := []struct {
+		wei  string
+		want string
+	}{
+		{"0", "0"},
+		{"1", "0.000000000000000001"},
+		{"100001000000000000", "0.100001"},
+		{"1000000000000000000", "1"},
+		{"1500000000000000000", "1.5"},
+		{"123456789012345678901", "123.456789012345678901"},
+		{"100000000000000000000000", "100000"},
+		// Not a wei amount: passed through unchanged
+		{"-1", "-1"},
+		{"1e18", "1e18"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := formatWeiExact(tt.wei); got != tt.want {
+			t.Errorf("formatWeiExact(%q) = %q, want %q", tt.wei, got, tt.want)
+		}
+	}
+}
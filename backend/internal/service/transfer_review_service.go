@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrReviewNotFound is returned for unknown review or invoice IDs.
+var ErrReviewNotFound = errors.New("not found")
+
+// TransferReviewService lets an operator work through transfers that could
+// not be matched to an invoice automatically.
+type TransferReviewService interface {
+	List(ctx context.Context, status models.ReviewStatus) ([]models.TransferReview, error)
+	// Resolve assigns the transfer to an invoice, marking it paid.
+	Resolve(ctx context.Context, id string, invoiceID string, note string) (*models.TransferReview, error)
+	// Dismiss closes the review without touching any invoice, e.g. after a
+	// refund was sent outside the system.
+	Dismiss(ctx context.Context, id string, note string) (*models.TransferReview, error)
+}
+
+type transferReviewService struct {
+	reviews  repository.TransferReviewRepository
+	invoices repository.InvoiceRepository
+	chainID  int64
+}
+
+func NewTransferReviewService(reviews repository.TransferReviewRepository, invoices repository.InvoiceRepository, chainID int64) TransferReviewService {
+	return &transferReviewService{reviews: reviews, invoices: invoices, chainID: chainID}
+}
+
+func (s *transferReviewService) List(ctx context.Context, status models.ReviewStatus) ([]models.TransferReview, error) {
+	return s.reviews.List(ctx, status)
+}
+
+func (s *transferReviewService) Resolve(ctx context.Context, id string, invoiceID string, note string) (*models.TransferReview, error) {
+	parsed, err := uuid.Parse(invoiceID)
+	if err != nil {
+		return nil, ErrReviewNotFound
+	}
+	if _, err := s.invoices.FindByID(ctx, invoiceID); err != nil {
+		return nil, notFound(err)
+	}
+
+	review, err := s.reviews.Resolve(ctx, id, parsed, s.chainID, note)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	logging.From(ctx, "service").WithFields(map[string]interface{}{
+		logging.FieldInvoiceID: invoiceID,
+		logging.FieldTxHash:    review.TxHash,
+		"review_id":            id,
+	}).Info("Transfer review resolved; invoice marked as PAID")
+	return review, nil
+}
+
+func (s *transferReviewService) Dismiss(ctx context.Context, id string, note string) (*models.TransferReview, error) {
+	review, err := s.reviews.Dismiss(ctx, id, note)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	logging.From(ctx, "service").WithField("review_id", id).Info("Transfer review dismissed")
+	return review, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrReviewNotFound
+	}
+	return err
+}
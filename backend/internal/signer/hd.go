@@ -0,0 +1,96 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+)
+
+// depositChain is the BIP-44 external chain under the account key; deposit
+// index i is account/0/i.
+const depositChain = 0
+
+// DepositAddresses derives deposit addresses from the account xpub. It holds
+// no private key material, so every API replica can use it.
+type DepositAddresses struct {
+	chain *bip32.Key
+}
+
+func NewDepositAddresses(xpub string) (*DepositAddresses, error) {
+	account, err := bip32.B58Deserialize(xpub)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEPOSIT_XPUB: %v", err)
+	}
+	chain, err := account.PublicKey().NewChildKey(depositChain)
+	if err != nil {
+		return nil, err
+	}
+	return &DepositAddresses{chain: chain}, nil
+}
+
+// Address returns the deposit address for index.
+func (d *DepositAddresses) Address(index uint32) (common.Address, error) {
+	child, err := d.chain.NewChildKey(index)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to derive deposit address %d: %v", index, err)
+	}
+	pub, err := crypto.DecompressPubkey(child.Key)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// DepositWallet holds the account xprv and signs for deposit addresses,
+// which only the sweeper needs.
+type DepositWallet struct {
+	chain *bip32.Key
+}
+
+// NewDepositWallet loads SIGNER_DEPOSIT_XPRV (or its file) and checks that
+// it matches xpub. It returns nil if no xprv is configured.
+func NewDepositWallet(cfg *config.SignerConfig, xpub string) (*DepositWallet, error) {
+	xprv := cfg.DepositXPrv
+	if cfg.DepositXPrvFile != "" {
+		data, err := os.ReadFile(cfg.DepositXPrvFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read deposit xprv file: %v", err)
+		}
+		xprv = strings.TrimSpace(string(data))
+	}
+	if xprv == "" {
+		return nil, nil
+	}
+
+	account, err := bip32.B58Deserialize(xprv)
+	if err != nil || !account.IsPrivate {
+		return nil, fmt.Errorf("SIGNER_DEPOSIT_XPRV is not an extended private key")
+	}
+	if account.PublicKey().B58Serialize() != xpub {
+		return nil, fmt.Errorf("SIGNER_DEPOSIT_XPRV does not match DEPOSIT_XPUB")
+	}
+	chain, err := account.NewChildKey(depositChain)
+	if err != nil {
+		return nil, err
+	}
+	return &DepositWallet{chain: chain}, nil
+}
+
+// Signer returns a signer for the deposit address at index.
+func (w *DepositWallet) Signer(index uint32) (Signer, error) {
+	child, err := w.chain.NewChildKey(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive deposit key %d: %v", index, err)
+	}
+	// go-bip32 drops leading zero bytes of child keys.
+	key, err := crypto.ToECDSA(common.LeftPadBytes(child.Key, 32))
+	if err != nil {
+		return nil, err
+	}
+	return newKeySigner(key), nil
+}
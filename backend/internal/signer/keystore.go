@@ -0,0 +1,39 @@
+package signer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+)
+
+// NewKeystoreSigner decrypts a go-ethereum (Web3 Secret Storage) keystore
+// file. The decrypted key is held in memory for the life of the process.
+func NewKeystoreSigner(cfg *config.SignerConfig) (Signer, error) {
+	keyJSON, err := os.ReadFile(cfg.KeystorePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keystore: %v", err)
+	}
+
+	passphrase := cfg.KeystorePassphrase
+	if cfg.KeystorePassphraseFile != "" {
+		data, err := os.ReadFile(cfg.KeystorePassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read keystore passphrase file: %v", err)
+		}
+		passphrase = strings.TrimRight(string(data), "\r\n")
+	}
+
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt keystore %s: %v", cfg.KeystorePath, err)
+	}
+
+	s := newKeySigner(key.PrivateKey)
+	if want, ok := parseAddress(cfg.Address); ok && want != s.address {
+		return nil, fmt.Errorf("keystore holds %s, expected SIGNER_ADDRESS %s", s.address.Hex(), want.Hex())
+	}
+	return s, nil
+}
@@ -0,0 +1,152 @@
+package signer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// remoteSigner delegates signing to an external JSON-RPC signer such as
+// Clef or a node with an unlocked account. The key never enters this
+// process.
+type remoteSigner struct {
+	client          *rpc.Client
+	method          string
+	typedDataMethod string
+	address         common.Address
+}
+
+// SendTxArgs is the transaction object accepted by eth_signTransaction and
+// Clef's account_signTransaction.
+type SendTxArgs struct {
+	From     common.MixedcaseAddress `json:"from"`
+	To       *common.Address         `json:"to"`
+	Gas      hexutil.Uint64          `json:"gas"`
+	GasPrice *hexutil.Big            `json:"gasPrice,omitempty"`
+	Value    hexutil.Big             `json:"value"`
+	Nonce    hexutil.Uint64          `json:"nonce"`
+	Data     *hexutil.Bytes          `json:"data,omitempty"`
+	ChainID  *hexutil.Big            `json:"chainId,omitempty"`
+}
+
+// SignTxResult is the signer's response: the RLP encoded signed transaction
+// and its decoded form.
+type SignTxResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// NewRemoteSigner connects to the signer at cfg.RemoteURL. Only legacy
+// transactions are sent, which every signer implementation accepts.
+func NewRemoteSigner(ctx context.Context, cfg *config.SignerConfig) (Signer, error) {
+	address, ok := parseAddress(cfg.Address)
+	if !ok {
+		return nil, fmt.Errorf("SIGNER_ADDRESS is required for the remote signer")
+	}
+	client, err := rpc.DialContext(ctx, cfg.RemoteURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %v", err)
+	}
+	return &remoteSigner{
+		client:          client,
+		method:          cfg.RemoteMethod,
+		typedDataMethod: cfg.RemoteTypedDataMethod,
+		address:         address,
+	}, nil
+}
+
+func (s *remoteSigner) Address() common.Address {
+	return s.address
+}
+
+func (s *remoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (_ *types.Transaction, err error) {
+	ctx, span := telemetry.Start(ctx, "signer "+s.method, attribute.String("signer.address", s.address.Hex()))
+	defer func() { telemetry.End(span, err) }()
+
+	data := hexutil.Bytes(tx.Data())
+	args := SendTxArgs{
+		From:     common.NewMixedcaseAddress(s.address),
+		To:       tx.To(),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: (*hexutil.Big)(tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     &data,
+		ChainID:  (*hexutil.Big)(chainID),
+	}
+
+	var res SignTxResult
+	if err := s.client.CallContext(ctx, &res, s.method, args); err != nil {
+		return nil, fmt.Errorf("remote signer: %v", err)
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res.Raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %v", err)
+	}
+
+	// Never broadcast something other than what was asked for.
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an unverifiable signature: %v", err)
+	}
+	if from != s.address {
+		return nil, fmt.Errorf("remote signer signed as %s, expected %s", from.Hex(), s.address.Hex())
+	}
+	if field := txMismatch(tx, signed, chainID); field != "" {
+		return nil, fmt.Errorf("remote signer returned a transaction that differs from the request in its %s", field)
+	}
+	return signed, nil
+}
+
+// txMismatch names the first field in which signed differs from the
+// requested tx on chainID, or returns "" if every signed field matches.
+func txMismatch(tx, signed *types.Transaction, chainID *big.Int) string {
+	switch {
+	case signed.Type() != tx.Type():
+		return "type"
+	case signed.ChainId().Cmp(chainID) != 0:
+		return "chain ID"
+	case signed.Nonce() != tx.Nonce():
+		return "nonce"
+	case (signed.To() == nil) != (tx.To() == nil) || (tx.To() != nil && *signed.To() != *tx.To()):
+		return "recipient"
+	case signed.Value().Cmp(tx.Value()) != 0:
+		return "value"
+	case !bytes.Equal(signed.Data(), tx.Data()):
+		return "data"
+	case signed.Gas() != tx.Gas():
+		return "gas limit"
+	case signed.GasTipCap().Cmp(tx.GasTipCap()) != 0 || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0:
+		// Both are the gas price of a legacy transaction
+		if tx.Type() == types.LegacyTxType {
+			return "gas price"
+		}
+		return "fee caps"
+	case !reflect.DeepEqual(signed.AccessList(), tx.AccessList()):
+		return "access list"
+	}
+	return ""
+}
+
+func (s *remoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) (_ []byte, err error) {
+	ctx, span := telemetry.Start(ctx, "signer "+s.typedDataMethod, attribute.String("signer.address", s.address.Hex()))
+	defer func() { telemetry.End(span, err) }()
+
+	var sig hexutil.Bytes
+	if err := s.client.CallContext(ctx, &sig, s.typedDataMethod, common.NewMixedcaseAddress(s.address), data); err != nil {
+		return nil, fmt.Errorf("remote signer: %v", err)
+	}
+	return verifyTypedDataSignature(data, sig, s.address)
+}
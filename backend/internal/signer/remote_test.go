@@ -0,0 +1,147 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// stubSigner answers eth_signTransaction, changing the transaction with
+// tamper before signing it.
+type stubSigner struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	tamper  func(*types.LegacyTx)
+}
+
+func (s *stubSigner) SignTransaction(args SendTxArgs) (*SignTxResult, error) {
+	tx := &types.LegacyTx{
+		Nonce:    uint64(args.Nonce),
+		GasPrice: args.GasPrice.ToInt(),
+		Gas:      uint64(args.Gas),
+		To:       args.To,
+		Value:    args.Value.ToInt(),
+		Data:     *args.Data,
+	}
+	if s.tamper != nil {
+		s.tamper(tx)
+	}
+	signed, err := types.SignNewTx(s.key, types.LatestSignerForChainID(s.chainID), tx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTxResult{Raw: raw, Tx: signed}, nil
+}
+
+func TestRemoteSignerSignTx(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(11155111)
+	to := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	request := types.NewTx(&types.LegacyTx{
+		Nonce:    7,
+		GasPrice: big.NewInt(2_000_000_000),
+		Gas:      120_000,
+		To:       &to,
+		Value:    big.NewInt(1000),
+		Data:     []byte{0xde, 0xad, 0xbe, 0xef},
+	})
+
+	tests := []struct {
+		name    string
+		tamper  func(*types.LegacyTx)
+		chainID *big.Int
+		wantErr string
+	}{
+		{name: "unchanged"},
+		{name: "nonce", tamper: func(tx *types.LegacyTx) { tx.Nonce++ }, wantErr: "nonce"},
+		{name: "recipient", tamper: func(tx *types.LegacyTx) { tx.To = &common.Address{1} }, wantErr: "recipient"},
+		{name: "contract creation", tamper: func(tx *types.LegacyTx) { tx.To = nil }, wantErr: "recipient"},
+		{name: "value", tamper: func(tx *types.LegacyTx) { tx.Value = big.NewInt(1001) }, wantErr: "value"},
+		{name: "data", tamper: func(tx *types.LegacyTx) { tx.Data = []byte{0xde, 0xad} }, wantErr: "data"},
+		{name: "gas limit", tamper: func(tx *types.LegacyTx) { tx.Gas = 10_000_000 }, wantErr: "gas limit"},
+		{name: "gas price", tamper: func(tx *types.LegacyTx) { tx.GasPrice = big.NewInt(500_000_000_000) }, wantErr: "gas price"},
+		{name: "other chain", chainID: big.NewInt(1), wantErr: "signature"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubSigner{key: key, chainID: chainID, tamper: tt.tamper}
+			if tt.chainID != nil {
+				stub.chainID = tt.chainID
+			}
+			server := rpc.NewServer()
+			if err := server.RegisterName("eth", stub); err != nil {
+				t.Fatal(err)
+			}
+			defer server.Stop()
+			client := rpc.DialInProc(server)
+			defer client.Close()
+
+			s := &remoteSigner{client: client, method: "eth_signTransaction", address: crypto.PubkeyToAddress(key.PublicKey)}
+			signed, err := s.SignTx(context.Background(), request, chainID)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SignTx: %v", err)
+				}
+				if signed.Hash() == request.Hash() {
+					t.Error("transaction not signed")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTxMismatchFeeFields(t *testing.T) {
+	chainID := big.NewInt(1)
+	to := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	base := types.DynamicFeeTx{ChainID: chainID, Nonce: 1, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Gas: 21000, To: &to, Value: big.NewInt(1)}
+
+	tests := []struct {
+		name   string
+		change func(*types.DynamicFeeTx)
+		want   string
+	}{
+		{"unchanged", func(*types.DynamicFeeTx) {}, ""},
+		{"tip cap", func(tx *types.DynamicFeeTx) { tx.GasTipCap = big.NewInt(2) }, "fee caps"},
+		{"fee cap", func(tx *types.DynamicFeeTx) { tx.GasFeeCap = big.NewInt(20) }, "fee caps"},
+		{"access list", func(tx *types.DynamicFeeTx) {
+			tx.AccessList = types.AccessList{{Address: to, StorageKeys: []common.Hash{{1}}}}
+		}, "access list"},
+		{"chain ID", func(tx *types.DynamicFeeTx) { tx.ChainID = big.NewInt(5) }, "chain ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+			if got := txMismatch(types.NewTx(&base), types.NewTx(&changed), chainID); got != tt.want {
+				t.Errorf("txMismatch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTxMismatchType(t *testing.T) {
+	to := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	legacy := types.NewTx(&types.LegacyTx{Nonce: 1, GasPrice: big.NewInt(10), Gas: 21000, To: &to})
+	dynamic := types.NewTx(&types.DynamicFeeTx{ChainID: big.NewInt(1), Nonce: 1, GasTipCap: big.NewInt(10), GasFeeCap: big.NewInt(10), Gas: 21000, To: &to})
+	if got := txMismatch(legacy, dynamic, big.NewInt(1)); got != "type" {
+		t.Errorf("txMismatch() = %q, want %q", got, "type")
+	}
+}
@@ -0,0 +1,94 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+)
+
+// Signer signs transactions for the backend's wallet. The key material
+// lives behind the implementation: in memory for the dev key and keystore
+// backends, or in a separate process for the remote backend.
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignTypedData returns a 65-byte EIP-712 signature with v in {27, 28}.
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+// New builds the signer selected by cfg.Signer.Backend.
+func New(ctx context.Context, cfg *config.Config) (Signer, error) {
+	switch cfg.Signer.Backend {
+	case config.SignerBackendKey:
+		return NewKeySigner(cfg.Ethereum.PrivateKey)
+	case config.SignerBackendKeystore:
+		return NewKeystoreSigner(cfg.Signer)
+	case config.SignerBackendRemote:
+		return NewRemoteSigner(ctx, cfg.Signer)
+	default:
+		return nil, fmt.Errorf("unknown signer backend %q", cfg.Signer.Backend)
+	}
+}
+
+// ownerSelector is the 4-byte selector of Ownable.owner().
+var ownerSelector = crypto.Keccak256([]byte("owner()"))[:4]
+
+// VerifyOwner checks that s is the owner of the invoice contract. Only the
+// owner may call createInvoice, so a mismatch would fail every invoice.
+func VerifyOwner(ctx context.Context, caller ethereum.ContractCaller, contract common.Address, s Signer) error {
+	out, err := caller.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: ownerSelector}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call owner(): %v", err)
+	}
+	if len(out) != 32 {
+		return fmt.Errorf("unexpected owner() result %x; is %s the invoice contract?", out, contract.Hex())
+	}
+	owner := common.BytesToAddress(out[12:])
+	if owner != s.Address() {
+		return fmt.Errorf("signer %s is not the contract owner %s", s.Address().Hex(), owner.Hex())
+	}
+	return nil
+}
+
+// verifyTypedDataSignature checks that sig over data was made by want and
+// normalises v to 27/28, the form ecrecover in Solidity expects.
+func verifyTypedDataSignature(data apitypes.TypedData, sig []byte, want common.Address) ([]byte, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, fmt.Errorf("signature has %d bytes, want %d", len(sig), crypto.SignatureLength)
+	}
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, len(sig))
+	copy(out, sig)
+	if out[64] >= 27 {
+		out[64] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, out)
+	if err != nil {
+		return nil, fmt.Errorf("unrecoverable signature: %v", err)
+	}
+	if got := crypto.PubkeyToAddress(*pub); got != want {
+		return nil, fmt.Errorf("typed data signed by %s, expected %s", got.Hex(), want.Hex())
+	}
+	out[64] += 27
+	return out, nil
+}
+
+func parseAddress(s string) (common.Address, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(s), true
+}
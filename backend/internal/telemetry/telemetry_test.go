@@ -0,0 +1,57 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestStartEnd(t *testing.T) {
+	provider, exporter := NewInMemoryProvider()
+	defer provider.Shutdown(context.Background())
+
+	ctx, parent := Start(context.Background(), "InvoiceService.CreateInvoice", attribute.String("invoice.mode", "signed"))
+	_, child := Start(ctx, "InvoiceRepository.Create")
+	End(child, errors.New("duplicate key"))
+	End(parent, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	failed, succeeded := spans[0], spans[1]
+
+	if failed.Name != "InvoiceRepository.Create" || failed.Status.Code != codes.Error || failed.Status.Description != "duplicate key" {
+		t.Errorf("failed span = %s %v", failed.Name, failed.Status)
+	}
+	if len(failed.Events) != 1 || failed.Events[0].Name != "exception" {
+		t.Errorf("failed span events = %v, want the recorded error", failed.Events)
+	}
+	if failed.Parent.SpanID() != succeeded.SpanContext.SpanID() {
+		t.Error("child span is not parented to the span in its context")
+	}
+
+	if succeeded.Status.Code != codes.Unset {
+		t.Errorf("span without error has status %v", succeeded.Status)
+	}
+	if len(succeeded.Attributes) != 1 || succeeded.Attributes[0] != attribute.String("invoice.mode", "signed") {
+		t.Errorf("attributes = %v", succeeded.Attributes)
+	}
+}
+
+func TestRecordErrorNil(t *testing.T) {
+	provider, exporter := NewInMemoryProvider()
+	defer provider.Shutdown(context.Background())
+
+	_, span := Start(context.Background(), "noop")
+	RecordError(span, nil)
+	span.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code != codes.Unset || len(spans[0].Events) != 0 {
+		t.Errorf("nil error changed the span: %+v", spans)
+	}
+}
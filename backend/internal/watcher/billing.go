@@ -0,0 +1,32 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+// SubscriptionBiller invoices subscriptions when their periods and retries
+// fall due, and moves them through dunning once those invoices are paid or
+// expire.
+type SubscriptionBiller struct {
+	*lifecycle.Loop
+
+	subscriptions service.SubscriptionService
+}
+
+func NewSubscriptionBiller(cfg *config.Config, subscriptions service.SubscriptionService) *SubscriptionBiller {
+	b := &SubscriptionBiller{subscriptions: subscriptions}
+	b.Loop = lifecycle.NewLoop("subscription billing", cfg.Billing.CheckInterval, b.bill)
+	return b
+}
+
+func (b *SubscriptionBiller) bill(ctx context.Context) {
+	if err := b.subscriptions.Bill(ctx, time.Now().UTC()); err != nil {
+		logging.From(ctx, "billing").WithError(err).Error("Subscription billing failed")
+	}
+}
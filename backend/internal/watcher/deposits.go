@@ -0,0 +1,138 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm"
+)
+
+// applyDepositTransfer records a transfer to an invoice's own deposit
+// address as a payment of that invoice. Payers may split a payment over
+// several transfers; the invoice is paid once they add up to its amount.
+func (w *Watcher) applyDepositTransfer(ctx context.Context, t transfer) {
+	ctx, span := telemetry.Start(ctx, "Watcher.applyDepositTransfer", attribute.String("eth.tx_hash", t.TxHash.Hex()))
+	defer span.End()
+
+	ctx = logging.With(ctx, logging.FieldTxHash, t.TxHash.Hex())
+	logger := logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+		logging.FieldPayer: t.From.Hex(),
+		"deposit_address":  t.To.Hex(),
+		"amount_wei":       t.Amount.String(),
+	})
+	logger.Info("Detected transfer to deposit address")
+
+	invoice, err := w.repo.FindByDepositAddress(ctx, t.To.Hex())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		w.queueReview(ctx, t, models.ReviewUnmatched, nil)
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up invoice by deposit address")
+		telemetry.RecordError(span, err)
+		return
+	}
+
+	switch invoice.Status {
+	case models.StatusPaid:
+		// The address belongs to this invoice alone, so anything sent after
+		// it was paid is recorded as an overpayment of it.
+	case models.StatusPartiallyRefunded, models.StatusRefunded:
+		w.queueReview(ctx, t, models.ReviewDuplicate, invoice)
+		return
+	default:
+		if t.BlockTime.After(invoice.ExpiresAt) {
+			w.queueReview(ctx, t, models.ReviewLate, invoice)
+			return
+		}
+	}
+	w.recordPayment(ctx, invoice, w.transferPayment(ctx, t))
+}
+
+// checkDepositBalances records funds on the addresses of open deposit
+// invoices that no recorded payment accounts for, catching transfers the
+// block scan cannot see (e.g. internal transfers on nodes without tracing).
+// Open invoices have never been swept, so their address balance is all they
+// received.
+func (w *Watcher) checkDepositBalances(ctx context.Context, block uint64) {
+	ctx, span := telemetry.Start(ctx, "Watcher.checkDepositBalances")
+	defer span.End()
+	logger := logging.From(ctx, "watcher")
+
+	invoices, err := w.repo.FindDepositsSince(ctx, time.Now().Add(-w.cfg.Deposit.WatchWindow))
+	if err != nil {
+		logger.WithError(err).Error("Failed to load deposit invoices")
+		telemetry.RecordError(span, err)
+		return
+	}
+
+	var header *types.Header
+	for i := range invoices {
+		invoice := &invoices[i]
+		if invoice.Status != models.StatusPending && invoice.Status != models.StatusPartiallyPaid {
+			continue
+		}
+		if invoice.DepositAddress == nil || invoice.DepositIndex == nil {
+			continue
+		}
+		entry := logger.WithField(logging.FieldInvoiceID, invoice.ID.String())
+
+		if header == nil {
+			header, err = w.client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+			if err != nil {
+				logger.WithError(err).Warn("Failed to get block header for deposit balances")
+				return
+			}
+		}
+		unaccounted, err := w.unaccountedDeposit(ctx, invoice, header.Number)
+		if err != nil {
+			entry.WithError(err).Warn("Failed to read deposit address balance")
+			continue
+		}
+		if unaccounted.Sign() <= 0 {
+			continue
+		}
+		entry.WithField("amount_wei", unaccounted.String()).Info("Deposit balance exceeds recorded payments")
+		w.recordPayment(ctx, invoice, w.balancePayment(invoice, header, unaccounted))
+	}
+}
+
+// unaccountedDeposit is the deposit address balance at block beyond the
+// payments recorded for the invoice.
+func (w *Watcher) unaccountedDeposit(ctx context.Context, invoice *models.Invoice, block *big.Int) (*big.Int, error) {
+	balance, err := w.client.BalanceAt(ctx, common.HexToAddress(*invoice.DepositAddress), block)
+	if err != nil {
+		return nil, err
+	}
+	paid, ok := new(big.Int).SetString(invoice.AmountPaidWei, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount_paid_wei %q", invoice.AmountPaidWei)
+	}
+	return balance.Sub(balance, paid), nil
+}
+
+// balancePayment is a payment found only as deposit balance. It has no
+// transaction or known payer; it is keyed by the block it was seen at and
+// the invoice's deposit index, so replaying the block records it once.
+func (w *Watcher) balancePayment(invoice *models.Invoice, header *types.Header, amount *big.Int) *models.Payment {
+	blockTime := time.Unix(int64(header.Time), 0).UTC()
+	return &models.Payment{
+		ChainID:     w.cfg.Ethereum.ChainID,
+		TxHash:      header.Hash().Hex(),
+		LogIndex:    uint(*invoice.DepositIndex),
+		BlockNumber: header.Number.Uint64(),
+		BlockHash:   header.Hash().Hex(),
+		BlockTime:   &blockTime,
+		Token:       models.NativeToken,
+		AmountWei:   amount.String(),
+	}
+}
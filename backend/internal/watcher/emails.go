@@ -0,0 +1,36 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+// EmailSender queues the emails of new invoice events and delivers the
+// email outbox, retrying failed sends.
+type EmailSender struct {
+	*lifecycle.Loop
+
+	emails service.EmailService
+}
+
+func NewEmailSender(cfg *config.Config, emails service.EmailService) *EmailSender {
+	e := &EmailSender{emails: emails}
+	e.Loop = lifecycle.NewLoop("email outbox", cfg.Email.SendInterval, e.send)
+	return e
+}
+
+func (e *EmailSender) send(ctx context.Context) {
+	now := time.Now().UTC()
+	if err := e.emails.Notify(ctx, now); err != nil {
+		logging.From(ctx, "email").WithError(err).Error("Queueing invoice emails failed")
+	}
+	// Queued emails are still sent when queueing new ones failed
+	if err := e.emails.Deliver(ctx, now); err != nil {
+		logging.From(ctx, "email").WithError(err).Error("Email delivery failed")
+	}
+}
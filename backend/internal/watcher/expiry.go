@@ -0,0 +1,49 @@
+package watcher
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+)
+
+const expiryCheckInterval = 1 * time.Minute
+
+// ExpiryChecker marks pending invoices past their expiry as EXPIRED.
+type ExpiryChecker struct {
+	*lifecycle.Loop
+
+	repo    repository.InvoiceRepository
+	chainID string
+}
+
+func NewExpiryChecker(repo repository.InvoiceRepository, chainID int64) *ExpiryChecker {
+	e := &ExpiryChecker{repo: repo, chainID: strconv.FormatInt(chainID, 10)}
+	e.Loop = lifecycle.NewLoop("expiry checker", expiryCheckInterval, e.expireInvoices)
+	return e
+}
+
+func (e *ExpiryChecker) expireInvoices(ctx context.Context) {
+	ctx, span := telemetry.Start(ctx, "ExpiryChecker.expireInvoices")
+	defer span.End()
+	logger := logging.From(ctx, "expiry")
+
+	expired, err := e.repo.UpdateExpired(ctx, time.Now())
+	if err != nil {
+		logger.WithError(err).Error("Failed to update expired invoices")
+		return
+	}
+	for _, invoice := range expired {
+		logger.WithFields(map[string]interface{}{
+			logging.FieldInvoiceID: invoice.ID.String(),
+			logging.FieldOnchainID: invoice.OnchainInvoiceID,
+			logging.FieldMerchant:  invoice.MerchantAddress,
+		}).Info("Invoice expired")
+		metrics.InvoicesExpired.WithLabelValues(invoice.MerchantAddress, e.chainID).Inc()
+	}
+}
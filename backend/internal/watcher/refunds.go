@@ -0,0 +1,122 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+)
+
+// RefundTracker follows sent refund transactions until they are confirmed
+// or fail, and then updates the refund and its invoice.
+type RefundTracker struct {
+	*lifecycle.Loop
+
+	repo          repository.RefundRepository
+	client        chain.Client
+	confirmations uint64
+}
+
+func NewRefundTracker(cfg *config.Config, repo repository.RefundRepository, client chain.Client) *RefundTracker {
+	t := &RefundTracker{repo: repo, client: client, confirmations: cfg.Refund.Confirmations}
+	t.Loop = lifecycle.NewLoop("refund tracker", cfg.Refund.CheckInterval, t.checkRefunds)
+	return t
+}
+
+func (t *RefundTracker) checkRefunds(ctx context.Context) {
+	ctx, span := telemetry.Start(ctx, "RefundTracker.checkRefunds")
+	defer span.End()
+	logger := logging.From(ctx, "refund")
+
+	refunds, err := t.repo.FindSent(ctx)
+	if err != nil {
+		logger.WithError(err).Error("Failed to load sent refunds")
+		return
+	}
+	if len(refunds) == 0 {
+		return
+	}
+	head, err := t.client.BlockNumber(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to read block number")
+		return
+	}
+
+	for i := range refunds {
+		refund := &refunds[i]
+		entry := logger.WithFields(map[string]interface{}{
+			logging.FieldInvoiceID: refund.InvoiceID.String(),
+			logging.FieldTxHash:    *refund.TxHash,
+			"refund_id":            refund.ID.String(),
+		})
+
+		confirmed, failure, err := t.check(ctx, refund, head)
+		switch {
+		case err != nil:
+			entry.WithError(err).Warn("Failed to check refund transaction")
+		case failure != "":
+			if err := t.repo.MarkFailed(ctx, refund.ID.String(), failure); err != nil {
+				entry.WithError(err).Error("Failed to mark refund as failed")
+				continue
+			}
+			metrics.Refunds.WithLabelValues(string(models.RefundFailed)).Inc()
+			entry.WithField("reason", failure).Warn("Refund failed")
+		case confirmed:
+			invoice, err := t.repo.Confirm(ctx, refund.ID.String())
+			if err != nil {
+				entry.WithError(err).Error("Failed to confirm refund")
+				continue
+			}
+			metrics.Refunds.WithLabelValues(string(models.RefundConfirmed)).Inc()
+			entry.WithField("invoice_status", string(invoice.Status)).Info("Refund confirmed")
+		}
+	}
+}
+
+// check reports whether the refund transaction is confirmed, or why it
+// failed. Both are zero while the transaction is pending.
+func (t *RefundTracker) check(ctx context.Context, refund *models.Refund, head uint64) (bool, string, error) {
+	hash := common.HexToHash(*refund.TxHash)
+	receipt, err := t.client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, "transaction reverted", nil
+	}
+	if mined := receipt.BlockNumber.Uint64(); head < mined || head-mined+1 < t.confirmations {
+		return false, "", nil
+	}
+
+	// The merchant reported this hash; make sure it actually pays the refund
+	if refund.Method == models.RefundByMerchant {
+		tx, _, err := t.client.TransactionByHash(ctx, hash)
+		if err != nil {
+			return false, "", err
+		}
+		amount, _ := new(big.Int).SetString(refund.AmountWei, 10)
+		if tx.To() == nil || !strings.EqualFold(tx.To().Hex(), refund.ToAddress) {
+			return false, fmt.Sprintf("transaction is not sent to %s", refund.ToAddress), nil
+		}
+		if tx.Value().Cmp(amount) < 0 {
+			return false, fmt.Sprintf("transaction sends %s wei, refund is %s wei", tx.Value(), refund.AmountWei), nil
+		}
+	}
+	return true, "", nil
+}
@@ -0,0 +1,31 @@
+package watcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/service"
+)
+
+// ReminderSender sends payment reminders before invoices expire and, when
+// enabled, re-issues the invoices that expired unpaid.
+type ReminderSender struct {
+	*lifecycle.Loop
+
+	reminders service.ReminderService
+}
+
+func NewReminderSender(cfg *config.Config, reminders service.ReminderService) *ReminderSender {
+	r := &ReminderSender{reminders: reminders}
+	r.Loop = lifecycle.NewLoop("payment reminders", cfg.Reminder.CheckInterval, r.remind)
+	return r
+}
+
+func (r *ReminderSender) remind(ctx context.Context) {
+	if err := r.reminders.Remind(ctx, time.Now().UTC()); err != nil {
+		logging.From(ctx, "notify").WithError(err).Error("Payment reminders failed")
+	}
+}
@@ -0,0 +1,286 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
+	"github.com/user/crypto-invoice-generator/backend/internal/models"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// transferBatchBlocks caps a batch when transfer scanning is on, since every
+// block is fetched in full rather than through a single getLogs call.
+const transferBatchBlocks = 100
+
+// rpcMethodNotFound is the JSON-RPC error code for unsupported methods.
+const rpcMethodNotFound = -32601
+
+// transfer is a native ETH transfer to a payment or deposit address, either
+// a transaction's own value or an internal call found by tracing.
+type transfer struct {
+	TxHash    common.Hash
+	TracePath string
+	Block     uint64
+	BlockHash common.Hash
+	BlockTime time.Time
+	From      common.Address
+	To        common.Address
+	Amount    *big.Int
+}
+
+// collectTransfers finds successful native transfers to the payment address
+// and watched deposit addresses in [from, to]. It only writes to the review
+// queue and invoices later, in applyTransfer, so a failure here leaves
+// nothing half-applied.
+func (w *Watcher) collectTransfers(ctx context.Context, from, to uint64) (_ []transfer, err error) {
+	ctx, span := telemetry.Start(ctx, "Watcher.collectTransfers",
+		attribute.Int64("watcher.from_block", int64(from)),
+		attribute.Int64("watcher.to_block", int64(to)),
+	)
+	defer func() { telemetry.End(span, err) }()
+
+	targets, err := w.transferTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	signer := types.LatestSignerForChainID(big.NewInt(w.cfg.Ethereum.ChainID))
+
+	var transfers []transfer
+	for n := from; n <= to; n++ {
+		block, err := w.client.BlockByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get block %d: %v", n, err)
+		}
+		blockTime := time.Unix(int64(block.Time()), 0)
+
+		for _, tx := range block.Transactions() {
+			if tx.To() == nil || !targets[*tx.To()] || tx.Value().Sign() <= 0 {
+				continue
+			}
+			receipt, err := w.client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return nil, fmt.Errorf("failed to get receipt %s: %v", tx.Hash().Hex(), err)
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				continue
+			}
+			sender, err := types.Sender(signer, tx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to recover sender of %s: %v", tx.Hash().Hex(), err)
+			}
+			transfers = append(transfers, transfer{
+				TxHash: tx.Hash(), Block: n, BlockHash: block.Hash(), BlockTime: blockTime,
+				From: sender, To: *tx.To(), Amount: tx.Value(),
+			})
+		}
+
+		internal, err := w.collectInternalTransfers(ctx, n, block.Hash(), blockTime, targets)
+		if err != nil {
+			return nil, err
+		}
+		transfers = append(transfers, internal...)
+	}
+	span.SetAttributes(attribute.Int("watcher.transfer_count", len(transfers)))
+	return transfers, nil
+}
+
+// collectInternalTransfers finds value-carrying calls to targets made from
+// inside contracts, e.g. by multisig or smart contract wallets. Tracing is
+// disabled for the rest of the process if the node does not support it.
+func (w *Watcher) collectInternalTransfers(ctx context.Context, n uint64, blockHash common.Hash, blockTime time.Time, targets map[common.Address]bool) ([]transfer, error) {
+	if !w.cfg.Payment.TransferTraces || w.tracesUnsupported {
+		return nil, nil
+	}
+
+	traces, err := w.client.TraceBlock(ctx, n)
+	if err != nil {
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == rpcMethodNotFound {
+			w.tracesUnsupported = true
+			logging.From(ctx, "watcher").WithError(err).Warn("Node does not support debug_traceBlockByNumber; internal transfers will not be detected")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to trace block %d: %v", n, err)
+	}
+
+	var transfers []transfer
+	for _, trace := range traces {
+		// The root frame is the transaction itself, already covered by the
+		// block scan; a reverted root undoes every inner transfer.
+		if trace.Result.Error != "" {
+			continue
+		}
+		for i, frame := range trace.Result.Calls {
+			walkCalls(frame, strconv.Itoa(i), func(f chain.CallFrame, path string) {
+				if f.To == nil || !targets[*f.To] || f.Value == nil || f.Value.ToInt().Sign() <= 0 {
+					return
+				}
+				if typ := strings.ToUpper(f.Type); typ != "CALL" && typ != "SELFDESTRUCT" {
+					return
+				}
+				transfers = append(transfers, transfer{
+					TxHash: trace.TxHash, TracePath: path, Block: n, BlockHash: blockHash, BlockTime: blockTime,
+					From: f.From, To: *f.To, Amount: new(big.Int).Set(f.Value.ToInt()),
+				})
+			})
+		}
+	}
+	return transfers, nil
+}
+
+// walkCalls visits frame and its sub-calls depth first, skipping reverted
+// subtrees.
+func walkCalls(frame chain.CallFrame, path string, visit func(chain.CallFrame, string)) {
+	if frame.Error != "" {
+		return
+	}
+	visit(frame, path)
+	for i, sub := range frame.Calls {
+		walkCalls(sub, path+"."+strconv.Itoa(i), visit)
+	}
+}
+
+// transferTargets returns the addresses whose incoming transfers are
+// scanned: the shared payment address in transfer mode, and the deposit
+// addresses of recent deposit invoices.
+func (w *Watcher) transferTargets(ctx context.Context) (map[common.Address]bool, error) {
+	targets := map[common.Address]bool{}
+	if w.cfg.Payment.TransferModeEnabled {
+		targets[common.HexToAddress(w.cfg.Payment.Address)] = true
+	}
+	if w.cfg.Deposit.Enabled {
+		deposits, err := w.repo.FindDepositsSince(ctx, time.Now().Add(-w.cfg.Deposit.WatchWindow))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deposit addresses: %v", err)
+		}
+		for _, invoice := range deposits {
+			if invoice.DepositAddress != nil {
+				targets[common.HexToAddress(*invoice.DepositAddress)] = true
+			}
+		}
+	}
+	return targets, nil
+}
+
+// applyTransfer matches a transfer to the one pending invoice with its exact
+// amount and records it as that invoice's payment. Anything else goes to the review queue.
+// Transfers to deposit addresses are matched by address instead.
+func (w *Watcher) applyTransfer(ctx context.Context, t transfer) {
+	if !w.cfg.Payment.TransferModeEnabled || t.To != common.HexToAddress(w.cfg.Payment.Address) {
+		w.applyDepositTransfer(ctx, t)
+		return
+	}
+
+	ctx, span := telemetry.Start(ctx, "Watcher.applyTransfer", attribute.String("eth.tx_hash", t.TxHash.Hex()))
+	defer span.End()
+
+	ctx = logging.With(ctx, logging.FieldTxHash, t.TxHash.Hex())
+	logger := logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+		logging.FieldPayer: t.From.Hex(),
+		"amount_wei":       t.Amount.String(),
+		"trace_path":       t.TracePath,
+	})
+	logger.Info("Detected transfer to payment address")
+
+	invoices, err := w.repo.FindTransferByAmount(ctx, w.cfg.Payment.Address, t.Amount.String())
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up invoices by amount")
+		telemetry.RecordError(span, err)
+		return
+	}
+
+	var payable, paid []models.Invoice
+	for _, invoice := range invoices {
+		switch {
+		case invoice.Status.Settled():
+			if invoice.TxHash != nil && strings.EqualFold(*invoice.TxHash, t.TxHash.Hex()) {
+				logger.WithField(logging.FieldInvoiceID, invoice.ID.String()).Info("Transfer already applied")
+				return
+			}
+			paid = append(paid, invoice)
+		case !t.BlockTime.After(invoice.ExpiresAt):
+			// Paid in time, even if the expiry checker has since run
+			payable = append(payable, invoice)
+		}
+	}
+
+	if len(payable) == 1 {
+		w.recordPayment(ctx, &payable[0], w.transferPayment(ctx, t))
+		return
+	}
+
+	switch {
+	case len(payable) > 1:
+		w.queueReview(ctx, t, models.ReviewAmbiguous, nil)
+	case len(invoices) == 0:
+		w.queueReview(ctx, t, models.ReviewUnmatched, nil)
+	case len(invoices) == 1 && len(paid) == 1:
+		w.queueReview(ctx, t, models.ReviewDuplicate, &invoices[0])
+	case len(invoices) == 1:
+		w.queueReview(ctx, t, models.ReviewLate, &invoices[0])
+	default:
+		w.queueReview(ctx, t, models.ReviewAmbiguous, nil)
+	}
+}
+
+// transferPayment is the payment of a native transfer.
+func (w *Watcher) transferPayment(ctx context.Context, t transfer) *models.Payment {
+	blockTime := t.BlockTime.UTC()
+	payment := &models.Payment{
+		ChainID:      w.cfg.Ethereum.ChainID,
+		TxHash:       t.TxHash.Hex(),
+		LogIndex:     models.TransferLogIndex(t.TracePath),
+		BlockNumber:  t.Block,
+		BlockHash:    t.BlockHash.Hex(),
+		BlockTime:    &blockTime,
+		Token:        models.NativeToken,
+		PayerAddress: t.From.Hex(),
+		AmountWei:    t.Amount.String(),
+	}
+	w.addPaymentDetails(ctx, payment)
+	return payment
+}
+
+// queueReview records a transfer that needs an operator's decision.
+func (w *Watcher) queueReview(ctx context.Context, t transfer, reason models.ReviewReason, invoice *models.Invoice) {
+	review := &models.TransferReview{
+		TxHash:      t.TxHash.Hex(),
+		TracePath:   t.TracePath,
+		BlockNumber: t.Block,
+		FromAddress: t.From.Hex(),
+		ToAddress:   t.To.Hex(),
+		AmountWei:   t.Amount.String(),
+		Reason:      reason,
+		Status:      models.ReviewOpen,
+	}
+	if invoice != nil {
+		review.InvoiceID = &invoice.ID
+	}
+
+	logger := logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+		"reason":     reason,
+		"to":         review.ToAddress,
+		"amount_wei": review.AmountWei,
+	})
+	if err := w.reviews.Create(ctx, review); err != nil {
+		logger.WithError(err).Error("Failed to queue transfer for review")
+		return
+	}
+	metrics.TransferReviews.WithLabelValues(string(reason)).Inc()
+	logger.Warn("Transfer queued for manual review")
+}
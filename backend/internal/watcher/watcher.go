@@ -2,10 +2,10 @@ package watcher
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
 	"math/big"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,25 +13,42 @@ import (
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/user/crypto-invoice-generator/backend/internal/chain"
 	"github.com/user/crypto-invoice-generator/backend/internal/config"
+	"github.com/user/crypto-invoice-generator/backend/internal/lifecycle"
+	"github.com/user/crypto-invoice-generator/backend/internal/logging"
+	"github.com/user/crypto-invoice-generator/backend/internal/metrics"
 	"github.com/user/crypto-invoice-generator/backend/internal/models"
 	"github.com/user/crypto-invoice-generator/backend/internal/repository"
+	"github.com/user/crypto-invoice-generator/backend/internal/telemetry"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 )
 
 const abiPath = "internal/abi/invoice.json"
 
+// Poll frequently for events
+const pollInterval = 10 * time.Second
+
+// Watcher polls the InvoiceManager contract for events. It is a
+// lifecycle.Component; Stop waits for an in-flight log batch to finish.
 type Watcher struct {
-	client          *ethclient.Client
+	*lifecycle.Loop
+
+	client          chain.Client
 	repo            repository.InvoiceRepository
+	reviews         repository.TransferReviewRepository
+	customers       repository.CustomerRepository
 	cfg             *config.Config
 	db              *gorm.DB
 	contractABI     abi.ABI
 	contractAddress string
+
+	// tracesUnsupported is set once the node rejects debug_traceBlockByNumber.
+	tracesUnsupported bool
 }
 
-func NewWatcher(db *gorm.DB, repo repository.InvoiceRepository, cfg *config.Config, client *ethclient.Client) *Watcher {
+func NewWatcher(db *gorm.DB, repo repository.InvoiceRepository, reviews repository.TransferReviewRepository, customers repository.CustomerRepository, cfg *config.Config, client chain.Client) *Watcher {
 	abiFile, err := os.Open(abiPath)
 	if err != nil {
 		panic("Failed to open ABI file: " + err.Error())
@@ -43,66 +60,68 @@ func NewWatcher(db *gorm.DB, repo repository.InvoiceRepository, cfg *config.Conf
 		panic("Failed to parse contract ABI: " + err.Error())
 	}
 
-	return &Watcher{
+	w := &Watcher{
 		client:          client,
 		repo:            repo,
+		reviews:         reviews,
+		customers:       customers,
 		cfg:             cfg,
 		db:              db,
 		contractABI:     parsed,
 		contractAddress: cfg.Ethereum.ContractAddress,
 	}
+	w.Loop = lifecycle.NewLoop("watcher", pollInterval, w.pollLogs)
+	return w
 }
 
-func (w *Watcher) Start() {
-	w.startExpiryChecker()
-	go func() {
-		for {
-			w.pollLogs()
-			time.Sleep(10 * time.Second) // Poll frequently for events
-		}
-	}()
-}
-
-func (w *Watcher) startExpiryChecker() {
-	ticker := time.NewTicker(1 * time.Minute)
-	go func() {
-		for range ticker.C {
-			if err := w.repo.UpdateExpired(time.Now()); err != nil {
-				log.Printf("Failed to update expired invoices: %v", err)
-			}
-		}
-	}()
-}
-
-func (w *Watcher) pollLogs() {
-	ctx := context.Background()
+func (w *Watcher) pollLogs(ctx context.Context) {
+	// Each poll is a trace root; RPC calls, event handling and DB updates of
+	// the batch are recorded as its children.
+	ctx, span := telemetry.Start(ctx, "Watcher.pollLogs")
+	defer span.End()
+	logger := logging.From(ctx, "watcher")
 
 	// Get latest block
 	latestBlock, err := w.client.BlockNumber(ctx)
 	if err != nil {
-		log.Printf("Failed to get latest block: %v", err)
+		logger.WithError(err).Error("Failed to get latest block")
 		return
 	}
 
 	// Safety margin
 	safeBlock := latestBlock - 2
 
-	lastProcessed := w.getLastProcessedBlock()
+	lastProcessed := w.getLastProcessedBlock(ctx)
+	recordProgress(latestBlock, lastProcessed)
 	if lastProcessed >= safeBlock {
 		return
 	}
 
+	batchStart := time.Now()
+	defer func() { metrics.WatcherBatchDuration.Observe(time.Since(batchStart).Seconds()) }()
+
 	startBlock := lastProcessed + 1
 	// Limit range for getLogs to avoid errors (e.g. max 1000 blocks)
 	if safeBlock-startBlock > 1000 {
 		safeBlock = startBlock + 1000
 	}
+	scanTransfers := w.cfg.Payment.TransferModeEnabled || w.cfg.Deposit.Enabled
+	if scanTransfers && safeBlock-startBlock > transferBatchBlocks {
+		safeBlock = startBlock + transferBatchBlocks
+	}
 
-	log.Printf("Scanning logs from %d to %d", startBlock, safeBlock)
+	logger.WithFields(map[string]interface{}{"from_block": startBlock, "to_block": safeBlock}).Debug("Scanning logs")
+	span.SetAttributes(
+		attribute.Int64("watcher.from_block", int64(startBlock)),
+		attribute.Int64("watcher.to_block", int64(safeBlock)),
+	)
 
-	// Filter for both InvoiceCreated and InvoicePaid
+	// Filter for InvoiceCreated, InvoicePaid, InvoicePartiallyPaid and
+	// SignedInvoicePaid
 	paidID := w.contractABI.Events["InvoicePaid"].ID
+	partiallyPaidID := w.contractABI.Events["InvoicePartiallyPaid"].ID
 	createdID := w.contractABI.Events["InvoiceCreated"].ID
+	signedPaidID := w.contractABI.Events["SignedInvoicePaid"].ID
 
 	contractAddr := common.HexToAddress(w.contractAddress)
 
@@ -110,23 +129,54 @@ func (w *Watcher) pollLogs() {
 		FromBlock: big.NewInt(int64(startBlock)),
 		ToBlock:   big.NewInt(int64(safeBlock)),
 		Addresses: []common.Address{contractAddr},
-		Topics:    [][]common.Hash{{paidID, createdID}},
+		Topics:    [][]common.Hash{{paidID, partiallyPaidID, createdID, signedPaidID}},
 	}
 
 	logs, err := w.client.FilterLogs(ctx, query)
 	if err != nil {
-		log.Printf("Failed to fetch logs: %v", err)
+		logger.WithError(err).Error("Failed to fetch logs")
+		telemetry.RecordError(span, err)
 		return
 	}
+	span.SetAttributes(attribute.Int("watcher.log_count", len(logs)))
+
+	var transfers []transfer
+	if scanTransfers {
+		transfers, err = w.collectTransfers(ctx, startBlock, safeBlock)
+		if err != nil {
+			logger.WithError(err).Error("Failed to scan transfers")
+			telemetry.RecordError(span, err)
+			return
+		}
+	}
 
+	// Once fetched, a batch is always applied and the cursor advanced, even
+	// if shutdown starts meanwhile, so no events are handled twice.
+	batchCtx := context.WithoutCancel(ctx)
 	for _, vLog := range logs {
 		// Pass to the new parser method
-		if err := w.parseContractEvents(ctx, nil, &types.Receipt{Logs: []*types.Log{&vLog}}, 0); err != nil {
-			log.Printf("Error parsing event: %v", err)
+		if err := w.parseContractEvents(batchCtx, nil, &types.Receipt{Logs: []*types.Log{&vLog}}, 0); err != nil {
+			logger.WithError(err).Error("Error parsing event")
 		}
 	}
+	for _, t := range transfers {
+		w.applyTransfer(batchCtx, t)
+	}
+	if w.cfg.Deposit.Enabled {
+		w.checkDepositBalances(batchCtx, safeBlock)
+	}
 
-	w.updateLastProcessedBlock(safeBlock)
+	w.updateLastProcessedBlock(batchCtx, safeBlock)
+	recordProgress(latestBlock, safeBlock)
+}
+
+func recordProgress(head, processed uint64) {
+	metrics.WatcherLastProcessedBlock.Set(float64(processed))
+	if head > processed {
+		metrics.WatcherHeadLag.Set(float64(head - processed))
+	} else {
+		metrics.WatcherHeadLag.Set(0)
+	}
 }
 
 func (w *Watcher) parseContractEvents(ctx context.Context, tx *types.Transaction, receipt *types.Receipt, timestamp uint64) error {
@@ -139,13 +189,20 @@ func (w *Watcher) parseContractEvents(ctx context.Context, tx *types.Transaction
 		if err != nil {
 			continue
 		}
-		fmt.Println("Processing event:", event.Name)
+		logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+			"event":             event.Name,
+			logging.FieldTxHash: lg.TxHash.Hex(),
+			logging.FieldBlock:  lg.BlockNumber,
+			"log_index":         lg.Index,
+		}).Debug("Processing event")
 
 		switch event.Name {
 		case "InvoiceCreated":
-			w.handleInvoiceCreated(*lg)
-		case "InvoicePaid":
-			w.handleInvoicePaid(*lg)
+			w.handleInvoiceCreated(ctx, *lg)
+		case "InvoicePaid", "InvoicePartiallyPaid":
+			w.handleInvoicePaid(ctx, event.Name, *lg)
+		case "SignedInvoicePaid":
+			w.handleSignedInvoicePaid(ctx, *lg)
 		}
 	}
 	return nil
@@ -160,94 +217,250 @@ type InvoicePaidEvent struct {
 	AmountWei *big.Int
 }
 
-func (w *Watcher) handleInvoiceCreated(vLog types.Log) {
+func (w *Watcher) handleInvoiceCreated(ctx context.Context, vLog types.Log) {
+	ctx, span := telemetry.Start(ctx, "Watcher.handleInvoiceCreated", attribute.String("eth.tx_hash", vLog.TxHash.Hex()))
+	defer span.End()
+
+	txHash := vLog.TxHash.Hex()
+	ctx = logging.With(ctx, logging.FieldTxHash, txHash)
+	logger := logging.From(ctx, "watcher")
+
 	var raw InvoiceCreatedEvent
 	if err := w.contractABI.UnpackIntoInterface(&raw, "InvoiceCreated", vLog.Data); err != nil {
-		log.Printf("Failed to decode InvoiceCreated event data: %v", err)
+		logger.WithError(err).Error("Failed to decode InvoiceCreated event data")
 		return
 	}
 
 	if len(vLog.Topics) < 2 {
-		log.Printf("WARN: InvoiceCreated event missing indexed invoiceId")
+		logger.Warn("InvoiceCreated event missing indexed invoiceId")
 		return
 	}
 
 	invoiceId := new(big.Int).SetBytes(vLog.Topics[1].Bytes())
-	txHash := vLog.TxHash.Hex()
+	ctx = logging.With(ctx, logging.FieldOnchainID, invoiceId.String())
+	logger = logging.From(ctx, "watcher")
 
-	log.Printf("Detected InvoiceCreated event: ID %s, Tx %s, Amount %s", invoiceId, txHash, raw.AmountWei)
+	logger.WithField("amount_wei", raw.AmountWei.String()).Info("Detected InvoiceCreated event")
 
-	// Find invoice by TxHash
-	invoice, err := w.repo.FindByTxHash(txHash)
+	// Find invoice by the transaction that created it
+	invoice, err := w.repo.FindByCreationTxHash(ctx, txHash)
 	if err != nil {
-		log.Printf("WARN: InvoiceCreated event for unknown TxHash %s", txHash)
+		logger.Warn("InvoiceCreated event for unknown transaction")
 		return
 	}
+	logger = logger.WithField(logging.FieldInvoiceID, invoice.ID.String())
 
 	if invoice.OnchainInvoiceID != "" {
-		log.Printf("Invoice %s already has OnchainID %s", invoice.ID, invoice.OnchainInvoiceID)
+		logger.Infof("Invoice already has on-chain ID %s", invoice.OnchainInvoiceID)
 		return
 	}
 
-	err = w.repo.UpdateOnchainID(invoice.ID.String(), invoiceId.String())
+	err = w.repo.UpdateOnchainID(ctx, invoice.ID.String(), invoiceId.String())
 	if err != nil {
-		log.Printf("Failed to update on-chain ID for invoice %s: %v", invoice.ID, err)
+		logger.WithError(err).Error("Failed to update on-chain ID")
 	} else {
-		log.Printf("Invoice %s linked to OnchainID %s", invoice.ID, invoiceId)
+		logger.Info("Invoice linked to on-chain ID")
 	}
 }
 
-func (w *Watcher) handleInvoicePaid(vLog types.Log) {
-	var raw InvoicePaidEvent
-	if err := w.contractABI.UnpackIntoInterface(&raw, "InvoicePaid", vLog.Data); err != nil {
-		log.Printf("Failed to decode InvoicePaid event data: %v", err)
+// handleInvoicePaid records a payment of an on-chain invoice. Exact
+// invoices emit a single InvoicePaid; partial ones emit InvoicePartiallyPaid
+// per payment until the last one, which emits InvoicePaid.
+func (w *Watcher) handleInvoicePaid(ctx context.Context, eventName string, vLog types.Log) {
+	ctx, span := telemetry.Start(ctx, "Watcher.handleInvoicePaid", attribute.String("eth.tx_hash", vLog.TxHash.Hex()))
+	defer span.End()
+
+	ctx = logging.With(ctx, logging.FieldTxHash, vLog.TxHash.Hex())
+	logger := logging.From(ctx, "watcher")
+
+	// Both events start with the payment's amountWei; InvoicePartiallyPaid
+	// also carries the running total, which the backend recomputes.
+	values, err := w.contractABI.Unpack(eventName, vLog.Data)
+	if err != nil || len(values) == 0 {
+		logger.WithError(err).Errorf("Failed to decode %s event data", eventName)
+		return
+	}
+	raw := InvoicePaidEvent{}
+	raw.AmountWei, _ = values[0].(*big.Int)
+	if raw.AmountWei == nil {
+		logger.Errorf("Unexpected %s event data", eventName)
 		return
 	}
 
 	if len(vLog.Topics) < 3 {
-		log.Printf("WARN: InvoicePaid event missing indexed fields")
+		logger.Warnf("%s event missing indexed fields", eventName)
 		return
 	}
 
 	invoiceId := new(big.Int).SetBytes(vLog.Topics[1].Bytes())
 	payer := common.BytesToAddress(vLog.Topics[2].Bytes())
+	ctx = logging.With(ctx, logging.FieldOnchainID, invoiceId.String())
+	logger = logging.From(ctx, "watcher").WithField(logging.FieldPayer, payer.Hex())
 
-	log.Printf("Detected InvoicePaid event: ID %s from %s in tx %s, Amount %s", invoiceId, payer.Hex(), vLog.TxHash.Hex(), raw.AmountWei)
+	logger.WithField("amount_wei", raw.AmountWei.String()).Infof("Detected %s event", eventName)
 
 	// Find invoice in DB by on-chain ID
-	invoice, err := w.repo.FindByOnchainID(invoiceId.String())
+	invoice, err := w.repo.FindByOnchainID(ctx, invoiceId.String())
+	if err != nil {
+		logger.Warnf("%s event for unknown on-chain ID", eventName)
+		return
+	}
+	w.recordPayment(ctx, invoice, w.eventPayment(ctx, vLog, payer, raw.AmountWei))
+}
+
+// handleSignedInvoicePaid marks an off-chain signed invoice as paid. Such
+// invoices never had a creation transaction; the event carries their
+// EIP-712 hash, which was stored when the invoice was signed.
+func (w *Watcher) handleSignedInvoicePaid(ctx context.Context, vLog types.Log) {
+	ctx, span := telemetry.Start(ctx, "Watcher.handleSignedInvoicePaid", attribute.String("eth.tx_hash", vLog.TxHash.Hex()))
+	defer span.End()
+
+	ctx = logging.With(ctx, logging.FieldTxHash, vLog.TxHash.Hex())
+	logger := logging.From(ctx, "watcher")
+
+	var raw InvoicePaidEvent
+	if err := w.contractABI.UnpackIntoInterface(&raw, "SignedInvoicePaid", vLog.Data); err != nil {
+		logger.WithError(err).Error("Failed to decode SignedInvoicePaid event data")
+		return
+	}
+
+	if len(vLog.Topics) < 4 {
+		logger.Warn("SignedInvoicePaid event missing indexed fields")
+		return
+	}
+
+	invoiceHash := vLog.Topics[1].Hex()
+	payer := common.BytesToAddress(vLog.Topics[3].Bytes())
+	ctx = logging.With(ctx, logging.FieldInvoiceHash, invoiceHash)
+	logger = logging.From(ctx, "watcher").WithField(logging.FieldPayer, payer.Hex())
+
+	logger.WithField("amount_wei", raw.AmountWei.String()).Info("Detected SignedInvoicePaid event")
+
+	invoice, err := w.repo.FindByInvoiceHash(ctx, invoiceHash)
+	if err != nil {
+		logger.Warn("SignedInvoicePaid event for unknown invoice hash")
+		return
+	}
+	w.recordPayment(ctx, invoice, w.eventPayment(ctx, vLog, payer, raw.AmountWei))
+}
+
+// eventPayment is the payment of a contract payment event.
+func (w *Watcher) eventPayment(ctx context.Context, vLog types.Log, payer common.Address, amount *big.Int) *models.Payment {
+	payment := &models.Payment{
+		ChainID:      w.cfg.Ethereum.ChainID,
+		TxHash:       vLog.TxHash.Hex(),
+		LogIndex:     vLog.Index,
+		BlockNumber:  vLog.BlockNumber,
+		BlockHash:    vLog.BlockHash.Hex(),
+		Token:        models.NativeToken,
+		PayerAddress: payer.Hex(),
+		AmountWei:    amount.String(),
+	}
+	w.addPaymentDetails(ctx, payment)
+	return payment
+}
+
+// recordPayment stores a payment and applies it to the invoice status.
+// Payments replayed after a restart are recognised by chain, tx hash and
+// log index and ignored.
+func (w *Watcher) recordPayment(ctx context.Context, invoice *models.Invoice, payment *models.Payment) {
+	logger := logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+		logging.FieldInvoiceID: invoice.ID.String(),
+		logging.FieldPayer:     payment.PayerAddress,
+	})
+
+	tolerance := new(big.Int)
+	if invoice.AllowPartial {
+		if wei, ok := new(big.Int).SetString(invoice.AmountWei, 10); ok {
+			tolerance = w.cfg.Payment.UnderpaymentTolerance(wei)
+		}
+	}
+	outcome, err := w.repo.RecordPayment(ctx, invoice.ID.String(), payment, tolerance)
 	if err != nil {
-		log.Printf("WARN: InvoicePaid event for unknown on-chain ID %s", invoiceId)
+		logger.WithError(err).Error("Failed to record payment")
 		return
 	}
 
-	if invoice.Status == models.StatusPaid {
-		log.Printf("Invoice %s already marked PAID", invoice.ID)
+	logger = logger.WithFields(map[string]interface{}{
+		"status":          outcome.Status,
+		"amount_paid_wei": outcome.AmountPaidWei,
+	})
+	switch {
+	case outcome.Duplicate:
+		logger.Info("Payment already recorded")
 		return
+	case outcome.Settled:
+		logger.Info("Invoice marked as PAID")
+		metrics.InvoicesPaid.WithLabelValues(invoice.MerchantAddress, strconv.FormatInt(w.cfg.Ethereum.ChainID, 10)).Inc()
+	default:
+		logger.Info("Payment recorded")
+	}
+	if outcome.Overpayment != nil {
+		logger.WithField("overpaid_wei", outcome.Overpayment.AmountWei).Warn("Invoice overpaid; excess recorded for refund")
+	}
+	if payment.PayerAddress != "" {
+		w.rememberPayerWallet(ctx, invoice, common.HexToAddress(payment.PayerAddress))
 	}
+}
 
-	err = w.repo.UpdateStatus(invoice.ID.String(), models.StatusPaid, vLog.TxHash.Hex(), payer.Hex())
+// rememberPayerWallet links the payer's wallet to the invoice's customer,
+// so that later payments from it can be attributed to them.
+func (w *Watcher) rememberPayerWallet(ctx context.Context, invoice *models.Invoice, payer common.Address) {
+	if invoice.CustomerID == nil {
+		return
+	}
+	logger := logging.From(ctx, "watcher").WithFields(map[string]interface{}{
+		logging.FieldPayer: payer.Hex(),
+		"customer_id":      invoice.CustomerID.String(),
+	})
+	err := w.customers.AddWallet(ctx, *invoice.CustomerID, payer.Hex(), models.WalletPayment)
+	switch {
+	case errors.Is(err, repository.ErrWalletTaken):
+		logger.Info("Payer wallet already belongs to another customer")
+	case err != nil:
+		logger.WithError(err).Warn("Failed to link payer wallet to customer")
+	}
+}
+
+// addPaymentDetails fills in the block time, unless already known, and the
+// transaction fee of a payment. They are informational, so the payment is
+// recorded without them if the node cannot provide them.
+func (w *Watcher) addPaymentDetails(ctx context.Context, payment *models.Payment) {
+	logger := logging.From(ctx, "watcher")
+
+	if payment.BlockTime == nil {
+		header, err := w.client.HeaderByNumber(ctx, new(big.Int).SetUint64(payment.BlockNumber))
+		if err != nil {
+			logger.WithError(err).Warn("Failed to get payment block header")
+		} else {
+			blockTime := time.Unix(int64(header.Time), 0).UTC()
+			payment.BlockTime = &blockTime
+		}
+	}
+
+	receipt, err := w.client.TransactionReceipt(ctx, common.HexToHash(payment.TxHash))
 	if err != nil {
-		log.Printf("Failed to update invoice status: %v", err)
-	} else {
-		log.Printf("Invoice %s marked as PAID", invoice.ID)
+		logger.WithError(err).Warn("Failed to get payment transaction receipt")
+	} else if receipt.EffectiveGasPrice != nil {
+		fee := new(big.Int).Mul(receipt.EffectiveGasPrice, new(big.Int).SetUint64(receipt.GasUsed))
+		payment.GasPaidWei = fee.String()
 	}
 }
 
-func (w *Watcher) getLastProcessedBlock() uint64 {
+func (w *Watcher) getLastProcessedBlock(ctx context.Context) uint64 {
 	var appState models.AppState
-	if err := w.db.First(&appState).Error; err != nil {
-		currentBlock, _ := w.client.BlockNumber(context.Background())
+	if err := w.db.WithContext(ctx).First(&appState).Error; err != nil {
+		currentBlock, _ := w.client.BlockNumber(ctx)
 		// Start from now if fresh
 		if currentBlock > 0 {
 			currentBlock = currentBlock - 1
 		}
-		w.db.Create(&models.AppState{LastProcessedBlock: currentBlock})
+		w.db.WithContext(ctx).Create(&models.AppState{LastProcessedBlock: currentBlock})
 		return currentBlock
 	}
 	return appState.LastProcessedBlock
 }
 
-func (w *Watcher) updateLastProcessedBlock(blockNum uint64) {
-	w.db.Model(&models.AppState{}).Where("id = 1").Update("last_processed_block", blockNum)
+func (w *Watcher) updateLastProcessedBlock(ctx context.Context, blockNum uint64) {
+	w.db.WithContext(ctx).Model(&models.AppState{}).Where("id = 1").Update("last_processed_block", blockNum)
 }
@@ -3,14 +3,20 @@ pragma solidity ^0.8.20;
 
 import "@openzeppelin/contracts/utils/ReentrancyGuard.sol";
 import "@openzeppelin/contracts/access/Ownable.sol";
+import "@openzeppelin/contracts/utils/cryptography/EIP712.sol";
+import "@openzeppelin/contracts/utils/cryptography/ECDSA.sol";
 
 /**
  * @title InvoiceManager
  * @dev Contract for creating and paying ETH invoices on-chain
  * @notice Invoice creation karne ke liye owner permission chahiye
  * Payment koi bhi kar sakta hai using payInvoice function
+ * Off-chain invoices: owner EIP-712 signature ke saath paySignedInvoice se
+ * bina createInvoice transaction ke pay ho sakte hain
+ * Partial invoices: allowPartial ho to kai payments mein pay ho sakta hai
+ * Itemized invoices: line items ka content hash on-chain commit hota hai
  */
-contract InvoiceManager is ReentrancyGuard, Ownable {
+contract InvoiceManager is ReentrancyGuard, Ownable, EIP712 {
     
     struct Invoice {
         address merchant;      // Merchant ka address jisko payment milegi
@@ -18,14 +24,35 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
         uint256 expiresAt;     // Unix timestamp - iske baad payment accept nahi hogi
         bool paid;             // Payment status
         address payer;         // Jisne payment kiya (zero address if unpaid)
+        uint256 amountPaid;    // Ab tak mila total amount
+        bool allowPartial;     // Partial payments allowed
     }
     
+    // Off-chain invoice, signed by the owner as EIP-712 typed data
+    struct SignedInvoice {
+        address merchant;
+        uint256 amountWei;
+        uint256 expiresAt;
+        bytes32 salt;          // Random value so identical invoices get distinct hashes
+        bytes32 contentHash;   // Itemized invoice ka content hash (zero if not itemized)
+    }
+    
+    bytes32 public constant SIGNED_INVOICE_TYPEHASH = keccak256(
+        "SignedInvoice(address merchant,uint256 amountWei,uint256 expiresAt,bytes32 salt,bytes32 contentHash)"
+    );
+    
     // Invoice ID counter - har naye invoice ke liye increment hoga
     uint256 private _nextInvoiceId = 1;
     
     // Mapping: invoiceId => Invoice struct
     mapping(uint256 => Invoice) public invoices;
     
+    // Mapping: signed invoice hash => payer (zero address if unpaid)
+    mapping(bytes32 => address) public signedInvoicePayer;
+    
+    // Mapping: invoiceId => hash of the itemized invoice (zero if not itemized)
+    mapping(uint256 => bytes32) public invoiceContentHash;
+    
     // Events - Backend watcher in events ko listen karega
     event InvoiceCreated(
         uint256 indexed invoiceId,
@@ -40,7 +67,28 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
         uint256 amountWei
     );
     
-    constructor() Ownable(msg.sender) {}
+    // Partial invoice ka payment jo amountWei tak nahi pahuncha
+    event InvoicePartiallyPaid(
+        uint256 indexed invoiceId,
+        address indexed payer,
+        uint256 amountWei,
+        uint256 amountPaid
+    );
+    
+    // Itemized invoice ka content hash, InvoiceCreated ke saath emit hota hai
+    event InvoiceContentCommitted(
+        uint256 indexed invoiceId,
+        bytes32 contentHash
+    );
+    
+    event SignedInvoicePaid(
+        bytes32 indexed invoiceHash,
+        address indexed merchant,
+        address indexed payer,
+        uint256 amountWei
+    );
+    
+    constructor() Ownable(msg.sender) EIP712("InvoiceManager", "1") {}
     
     /**
      * @dev Create new invoice on-chain
@@ -54,6 +102,56 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
         uint256 amountWei,
         uint256 expiresAt
     ) external onlyOwner returns (uint256) {
+        return _createInvoice(merchant, amountWei, expiresAt, false);
+    }
+    
+    /**
+     * @dev Create new invoice on-chain with payment options
+     * @param merchant Address jisko payment forward hogi
+     * @param amountWei Payment amount in wei
+     * @param expiresAt Unix timestamp for expiry
+     * @param allowPartial True ho to invoice kai payments mein pay ho sakta hai;
+     * amountWei tak pahunchne par paid hota hai, aakhri payment zyada bhi ho sakta hai
+     * @return invoiceId Generated invoice ID
+     */
+    function createInvoiceWithOptions(
+        address merchant,
+        uint256 amountWei,
+        uint256 expiresAt,
+        bool allowPartial
+    ) external onlyOwner returns (uint256) {
+        return _createInvoice(merchant, amountWei, expiresAt, allowPartial);
+    }
+    
+    /**
+     * @dev Create new itemized invoice on-chain, committing to its content
+     * @param merchant Address jisko payment forward hogi
+     * @param amountWei Payment amount in wei (itemized invoice ka total)
+     * @param expiresAt Unix timestamp for expiry
+     * @param allowPartial True ho to invoice kai payments mein pay ho sakta hai
+     * @param contentHash Line items, discounts, tax aur shipping ka hash
+     * @return invoiceId Generated invoice ID
+     */
+    function createInvoiceWithContent(
+        address merchant,
+        uint256 amountWei,
+        uint256 expiresAt,
+        bool allowPartial,
+        bytes32 contentHash
+    ) external onlyOwner returns (uint256) {
+        require(contentHash != bytes32(0), "Invalid content hash");
+        uint256 invoiceId = _createInvoice(merchant, amountWei, expiresAt, allowPartial);
+        invoiceContentHash[invoiceId] = contentHash;
+        emit InvoiceContentCommitted(invoiceId, contentHash);
+        return invoiceId;
+    }
+    
+    function _createInvoice(
+        address merchant,
+        uint256 amountWei,
+        uint256 expiresAt,
+        bool allowPartial
+    ) private returns (uint256) {
         require(merchant != address(0), "Invalid merchant address");
         require(amountWei > 0, "Amount must be greater than 0");
         require(expiresAt > block.timestamp, "Expiry must be in future");
@@ -65,7 +163,9 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
             amountWei: amountWei,
             expiresAt: expiresAt,
             paid: false,
-            payer: address(0)
+            payer: address(0),
+            amountPaid: 0,
+            allowPartial: allowPartial
         });
         
         emit InvoiceCreated(invoiceId, merchant, amountWei, expiresAt);
@@ -76,7 +176,8 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
     /**
      * @dev Pay an existing invoice
      * @param invoiceId ID of invoice to pay
-     * @notice 
+     * @notice Normal invoice ke liye exact amount chahiye; partial invoice koi bhi
+     * non-zero amount accept karta hai jab tak total amountWei tak na pahunch jaye
      */
     function payInvoice(uint256 invoiceId) external payable nonReentrant {
         Invoice storage invoice = invoices[invoiceId];
@@ -85,20 +186,73 @@ contract InvoiceManager is ReentrancyGuard, Ownable {
         require(invoice.merchant != address(0), "Invoice does not exist");
         require(!invoice.paid, "Invoice already paid");
         require(block.timestamp <= invoice.expiresAt, "Invoice expired");
-        require(msg.value == invoice.amountWei, "Incorrect payment amount");
+        if (invoice.allowPartial) {
+            require(msg.value > 0, "Payment must be greater than 0");
+        } else {
+            require(msg.value == invoice.amountWei, "Incorrect payment amount");
+        }
         
         // Effects (state changes pehle, external calls baad mein)
-        invoice.paid = true;
+        invoice.amountPaid += msg.value;
         invoice.payer = msg.sender;
         
-        // Emit event before external call
-        emit InvoicePaid(invoiceId, msg.sender, msg.value);
+        // Emit event before external call; amountWei is this payment's value
+        if (invoice.amountPaid >= invoice.amountWei) {
+            invoice.paid = true;
+            emit InvoicePaid(invoiceId, msg.sender, msg.value);
+        } else {
+            emit InvoicePartiallyPaid(invoiceId, msg.sender, msg.value, invoice.amountPaid);
+        }
         
         // Interactions - merchant ko ETH forward karo
         (bool success, ) = invoice.merchant.call{value: msg.value}("");
         require(success, "Payment forward failed");
     }
     
+    /**
+     * @dev Pay an off-chain invoice signed by the owner
+     * @param invoice Invoice fields exactly as signed
+     * @param signature Owner's EIP-712 signature over the invoice
+     * @notice Invoice identity is its EIP-712 hash; ek hash sirf ek baar pay ho sakta hai
+     */
+    function paySignedInvoice(
+        SignedInvoice calldata invoice,
+        bytes calldata signature
+    ) external payable nonReentrant {
+        bytes32 invoiceHash = hashSignedInvoice(invoice);
+        
+        // Validations
+        require(ECDSA.recover(invoiceHash, signature) == owner(), "Invalid invoice signature");
+        require(invoice.merchant != address(0), "Invalid merchant address");
+        require(signedInvoicePayer[invoiceHash] == address(0), "Invoice already paid");
+        require(block.timestamp <= invoice.expiresAt, "Invoice expired");
+        require(msg.value == invoice.amountWei, "Incorrect payment amount");
+        
+        // Effects
+        signedInvoicePayer[invoiceHash] = msg.sender;
+        
+        emit SignedInvoicePaid(invoiceHash, invoice.merchant, msg.sender, msg.value);
+        
+        // Interactions
+        (bool success, ) = invoice.merchant.call{value: msg.value}("");
+        require(success, "Payment forward failed");
+    }
+    
+    /**
+     * @dev EIP-712 digest of a signed invoice; this is the invoice's identity
+     * @param invoice Invoice fields
+     */
+    function hashSignedInvoice(SignedInvoice calldata invoice) public view returns (bytes32) {
+        return _hashTypedDataV4(keccak256(abi.encode(
+            SIGNED_INVOICE_TYPEHASH,
+            invoice.merchant,
+            invoice.amountWei,
+            invoice.expiresAt,
+            invoice.salt,
+            invoice.contentHash
+        )));
+    }
+    
     /**
      * @dev Get invoice details
      * @param invoiceId Invoice ID to query
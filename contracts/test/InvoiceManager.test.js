@@ -129,4 +129,243 @@ describe("InvoiceManager", function () {
       ).to.be.revertedWith("Invoice expired");
     });
   });
+
+  describe("Partial Payments", function () {
+    let invoiceId, amountWei, expiresAt;
+    
+    beforeEach(async function () {
+      amountWei = ethers.parseEther("0.1");
+      expiresAt = (await time.latest()) + 3600;
+      
+      const tx = await invoiceManager.createInvoiceWithOptions(merchant.address, amountWei, expiresAt, true);
+      const receipt = await tx.wait();
+      const event = receipt.logs.find(log => log.fragment && log.fragment.name === 'InvoiceCreated');
+      invoiceId = event.args.invoiceId;
+    });
+    
+    it("Should track partial payments until the amount is reached", async function () {
+      const merchantBalanceBefore = await ethers.provider.getBalance(merchant.address);
+      const first = ethers.parseEther("0.04");
+      const second = amountWei - first;
+      
+      let receipt = await (await invoiceManager.connect(payer).payInvoice(invoiceId, { value: first })).wait();
+      let event = receipt.logs.find(log => log.fragment && log.fragment.name === 'InvoicePartiallyPaid');
+      expect(event.args.amountWei).to.equal(first);
+      expect(event.args.amountPaid).to.equal(first);
+      expect((await invoiceManager.getInvoice(invoiceId)).paid).to.be.false;
+      
+      receipt = await (await invoiceManager.connect(other).payInvoice(invoiceId, { value: second })).wait();
+      event = receipt.logs.find(log => log.fragment && log.fragment.name === 'InvoicePaid');
+      expect(event.args.payer).to.equal(other.address);
+      expect(event.args.amountWei).to.equal(second);
+      
+      const invoice = await invoiceManager.invoices(invoiceId);
+      expect(invoice.paid).to.be.true;
+      expect(invoice.amountPaid).to.equal(amountWei);
+      
+      const merchantBalanceAfter = await ethers.provider.getBalance(merchant.address);
+      expect(merchantBalanceAfter - merchantBalanceBefore).to.equal(amountWei);
+    });
+    
+    it("Should accept an overpaying final payment", async function () {
+      await invoiceManager.connect(payer).payInvoice(invoiceId, { value: ethers.parseEther("0.04") });
+      await invoiceManager.connect(payer).payInvoice(invoiceId, { value: ethers.parseEther("0.08") });
+      
+      const invoice = await invoiceManager.invoices(invoiceId);
+      expect(invoice.paid).to.be.true;
+      expect(invoice.amountPaid).to.equal(ethers.parseEther("0.12"));
+    });
+    
+    it("Should reject zero payment", async function () {
+      await expect(
+        invoiceManager.connect(payer).payInvoice(invoiceId, { value: 0 })
+      ).to.be.revertedWith("Payment must be greater than 0");
+    });
+    
+    it("Should reject payment after the invoice is paid", async function () {
+      await invoiceManager.connect(payer).payInvoice(invoiceId, { value: amountWei });
+      
+      await expect(
+        invoiceManager.connect(payer).payInvoice(invoiceId, { value: 1 })
+      ).to.be.revertedWith("Invoice already paid");
+    });
+    
+    it("Should reject partial payment after expiry", async function () {
+      await invoiceManager.connect(payer).payInvoice(invoiceId, { value: ethers.parseEther("0.04") });
+      await time.increaseTo(expiresAt + 1);
+      
+      await expect(
+        invoiceManager.connect(payer).payInvoice(invoiceId, { value: ethers.parseEther("0.06") })
+      ).to.be.revertedWith("Invoice expired");
+    });
+    
+    it("Should reject options invoice creation from non-owner", async function () {
+      await expect(
+        invoiceManager.connect(payer).createInvoiceWithOptions(merchant.address, amountWei, expiresAt, true)
+      ).to.be.revertedWithCustomError(invoiceManager, "OwnableUnauthorizedAccount");
+    });
+  });
+
+  describe("Itemized Invoices", function () {
+    let amountWei, expiresAt, contentHash;
+    
+    beforeEach(async function () {
+      amountWei = ethers.parseEther("0.1");
+      expiresAt = (await time.latest()) + 3600;
+      contentHash = ethers.keccak256(ethers.toUtf8Bytes('{"line_items":[]}'));
+    });
+    
+    it("Should commit the content hash on creation", async function () {
+      const tx = await invoiceManager.createInvoiceWithContent(merchant.address, amountWei, expiresAt, false, contentHash);
+      const receipt = await tx.wait();
+      const created = receipt.logs.find(log => log.fragment && log.fragment.name === 'InvoiceCreated');
+      const committed = receipt.logs.find(log => log.fragment && log.fragment.name === 'InvoiceContentCommitted');
+      
+      expect(committed.args.invoiceId).to.equal(created.args.invoiceId);
+      expect(committed.args.contentHash).to.equal(contentHash);
+      expect(await invoiceManager.invoiceContentHash(created.args.invoiceId)).to.equal(contentHash);
+    });
+    
+    it("Should be payable like other invoices", async function () {
+      await invoiceManager.createInvoiceWithContent(merchant.address, amountWei, expiresAt, false, contentHash);
+      
+      await expect(
+        invoiceManager.connect(payer).payInvoice(1, { value: amountWei })
+      ).to.emit(invoiceManager, "InvoicePaid").withArgs(1, payer.address, amountWei);
+    });
+    
+    it("Should reject an empty content hash", async function () {
+      await expect(
+        invoiceManager.createInvoiceWithContent(merchant.address, amountWei, expiresAt, false, ethers.ZeroHash)
+      ).to.be.revertedWith("Invalid content hash");
+    });
+    
+    it("Should reject itemized invoice creation from non-owner", async function () {
+      await expect(
+        invoiceManager.connect(payer).createInvoiceWithContent(merchant.address, amountWei, expiresAt, false, contentHash)
+      ).to.be.revertedWithCustomError(invoiceManager, "OwnableUnauthorizedAccount");
+    });
+  });
+
+  describe("Signed Invoice Payment", function () {
+    let invoice, domain;
+    const types = {
+      SignedInvoice: [
+        { name: "merchant", type: "address" },
+        { name: "amountWei", type: "uint256" },
+        { name: "expiresAt", type: "uint256" },
+        { name: "salt", type: "bytes32" },
+        { name: "contentHash", type: "bytes32" },
+      ],
+    };
+    
+    beforeEach(async function () {
+      const { chainId } = await ethers.provider.getNetwork();
+      domain = {
+        name: "InvoiceManager",
+        version: "1",
+        chainId,
+        verifyingContract: await invoiceManager.getAddress(),
+      };
+      invoice = {
+        merchant: merchant.address,
+        amountWei: ethers.parseEther("0.1"),
+        // Chain time, as earlier tests may have moved it past the wall clock
+        expiresAt: (await time.latest()) + 3600,
+        salt: ethers.hexlify(ethers.randomBytes(32)),
+        contentHash: ethers.ZeroHash,
+      };
+    });
+    
+    it("Should accept payment with owner signature", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      const invoiceHash = ethers.TypedDataEncoder.hash(domain, types, invoice);
+      const merchantBalanceBefore = await ethers.provider.getBalance(merchant.address);
+      
+      expect(await invoiceManager.hashSignedInvoice(invoice)).to.equal(invoiceHash);
+      
+      const tx = await invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei });
+      const receipt = await tx.wait();
+      
+      // Check event carries the invoice hash
+      const event = receipt.logs.find(log => log.fragment && log.fragment.name === 'SignedInvoicePaid');
+      expect(event).to.not.be.undefined;
+      expect(event.args.invoiceHash).to.equal(invoiceHash);
+      expect(event.args.merchant).to.equal(merchant.address);
+      expect(event.args.payer).to.equal(payer.address);
+      expect(event.args.amountWei).to.equal(invoice.amountWei);
+      
+      expect(await invoiceManager.signedInvoicePayer(invoiceHash)).to.equal(payer.address);
+      
+      const merchantBalanceAfter = await ethers.provider.getBalance(merchant.address);
+      expect(merchantBalanceAfter - merchantBalanceBefore).to.equal(invoice.amountWei);
+    });
+    
+    it("Should not consume on-chain invoice IDs", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      await invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei });
+      
+      expect(await invoiceManager.getNextInvoiceId()).to.equal(1);
+    });
+    
+    it("Should reject signature from non-owner", async function () {
+      const signature = await other.signTypedData(domain, types, invoice);
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei })
+      ).to.be.revertedWith("Invalid invoice signature");
+    });
+    
+    it("Should reject tampered invoice fields", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      const tampered = { ...invoice, amountWei: ethers.parseEther("0.01") };
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(tampered, signature, { value: tampered.amountWei })
+      ).to.be.revertedWith("Invalid invoice signature");
+    });
+    
+    it("Should reject tampered content hash", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      const tampered = { ...invoice, contentHash: ethers.keccak256(ethers.toUtf8Bytes("{}")) };
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(tampered, signature, { value: tampered.amountWei })
+      ).to.be.revertedWith("Invalid invoice signature");
+    });
+    
+    it("Should reject signature for another contract", async function () {
+      const signature = await owner.signTypedData({ ...domain, verifyingContract: other.address }, types, invoice);
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei })
+      ).to.be.revertedWith("Invalid invoice signature");
+    });
+    
+    it("Should reject incorrect payment amount", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: ethers.parseEther("0.05") })
+      ).to.be.revertedWith("Incorrect payment amount");
+    });
+    
+    it("Should reject double payment", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      await invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei });
+      
+      await expect(
+        invoiceManager.connect(other).paySignedInvoice(invoice, signature, { value: invoice.amountWei })
+      ).to.be.revertedWith("Invoice already paid");
+    });
+    
+    it("Should reject payment for expired invoice", async function () {
+      const signature = await owner.signTypedData(domain, types, invoice);
+      await time.increaseTo(invoice.expiresAt + 1);
+      
+      await expect(
+        invoiceManager.connect(payer).paySignedInvoice(invoice, signature, { value: invoice.amountWei })
+      ).to.be.revertedWith("Invoice expired");
+    });
+  });
 });
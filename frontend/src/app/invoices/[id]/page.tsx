@@ -1,7 +1,7 @@
 'use client';
 
 import { use, useEffect, useState, useCallback } from 'react';
-import { getInvoice } from '@/lib/api';
+import { checkoutUrl, getInvoice, invoicePdfUrl, receiptPdfUrl } from '@/lib/api';
 import { Invoice } from '@/lib/types';
 import { QRCodeSVG } from 'qrcode.react';
 import { Copy, Check, ExternalLink, Loader2, AlertCircle, CheckCircle2, Clock } from 'lucide-react';
@@ -29,7 +29,7 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
           setInvoice(data);
           setLoading(false);
           
-          if (data.status === 'PAID' || data.status === 'EXPIRED') {
+          if (data.status !== 'PENDING' && data.status !== 'PARTIALLY_PAID') {
             return; // Stop polling
           }
           
@@ -85,8 +85,14 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
     );
   }
 
-  const isPaid = invoice.status === 'PAID';
+  // A refunded invoice was paid first; it just has nothing left to pay
+  const isRefunded = invoice.status === 'REFUNDED' || invoice.status === 'PARTIALLY_REFUNDED';
+  const isPaid = invoice.status === 'PAID' || isRefunded;
   const isExpired = invoice.status === 'EXPIRED';
+  const isSigned = invoice.mode === 'signed';
+  const isDeposit = invoice.mode === 'deposit';
+  const isTransfer = invoice.mode === 'transfer' || isDeposit;
+  const payAddress = (isDeposit ? invoice.deposit_address : isTransfer ? invoice.payment_address : invoice.contract_address) ?? '';
 
   return (
     <div className="min-h-screen bg-gray-50 dark:bg-zinc-900 flex items-center justify-center p-4">
@@ -106,8 +112,11 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
             )}
           </div>
           <h1 className="text-2xl font-bold tracking-wide">
-            {isPaid ? 'PAYMENT RECEIVED' : isExpired ? 'INVOICE EXPIRED' : 'AWAITING PAYMENT'}
+            {isRefunded ? 'PAYMENT REFUNDED' : isPaid ? 'PAYMENT RECEIVED' : isExpired ? 'INVOICE EXPIRED' : 'AWAITING PAYMENT'}
           </h1>
+          {invoice.invoice_number && (
+            <p className="mt-1 text-sm font-mono opacity-90">{invoice.invoice_number}</p>
+          )}
           {isPaid && (
             <a 
               href={`https://testnet.qubetics.work/tx/${invoice.tx_hash}`}
@@ -129,8 +138,36 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
               <span className="text-4xl font-bold">{invoice.amount_eth}</span>
               <span className="text-xl font-medium mb-1.5 text-gray-500">TICS</span>
             </div>
+            {invoice.allow_partial && invoice.amount_paid_wei !== '0' && (
+              <p className="mt-2 text-sm text-gray-500 dark:text-gray-400">
+                Received {invoice.amount_paid_wei} of {invoice.amount_wei} wei in {invoice.payments?.length ?? 0} payment(s)
+              </p>
+            )}
           </div>
 
+          {/* Itemized Breakdown (amounts in wei) */}
+          {invoice.line_items && invoice.line_items.length > 0 && (
+            <div className="mb-8 text-xs text-gray-700 dark:text-gray-300 space-y-1">
+              {invoice.line_items.map((item) => (
+                <div key={item.id} className="flex justify-between gap-4">
+                  <span>{item.description} &times; {item.quantity}</span>
+                  <code className="font-mono">{item.subtotal_wei}</code>
+                </div>
+              ))}
+              {[
+                ['Discount', invoice.discount_wei],
+                ['Tax', invoice.tax_wei],
+                ['Shipping', invoice.shipping_wei],
+              ].filter(([, value]) => value && value !== '0').map(([label, value]) => (
+                <div key={label} className="flex justify-between gap-4 text-gray-500 dark:text-gray-400">
+                  <span>{label}</span>
+                  <code className="font-mono">{label === 'Discount' ? '-' : ''}{value}</code>
+                </div>
+              ))}
+              <p className="pt-1 text-gray-400 break-all">Content hash: <code className="font-mono">{invoice.content_hash}</code></p>
+            </div>
+          )}
+
           {!isPaid && !isExpired && (
             <>
               {/* Payment Instructions */}
@@ -141,7 +178,15 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
                     How to Pay
                   </h3>
                   <p className="text-xs text-blue-700 dark:text-blue-300 leading-relaxed">
-                    Call the <code className="font-mono bg-blue-100 dark:bg-blue-900/30 px-1 py-0.5 rounded">payInvoice</code> function on the smart contract with the exact ID and Amount.
+                    {isDeposit ? (
+                      <>Send the Amount below to this invoice&apos;s deposit address from any wallet or exchange. It may be split over several transfers.</>
+                    ) : isTransfer ? (
+                      <>Send exactly the Amount below to the payment address from any wallet. The amount identifies this invoice, so do not round it.</>
+                    ) : isSigned ? (
+                      <>Call the <code className="font-mono bg-blue-100 dark:bg-blue-900/30 px-1 py-0.5 rounded">paySignedInvoice</code> function with the invoice (merchant, amount in wei, expiry, salt, content hash) and signature below, sending the exact Amount.</>
+                    ) : (
+                      <>Call the <code className="font-mono bg-blue-100 dark:bg-blue-900/30 px-1 py-0.5 rounded">payInvoice</code> function on the smart contract with the exact ID and Amount.</>
+                    )}
                   </p>
                 </div>
 
@@ -149,18 +194,18 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
                 <div className="space-y-3">
                   <div>
                     <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wider">
-                      Contract Address
+                      {isDeposit ? 'Deposit Address' : isTransfer ? 'Payment Address' : 'Contract Address'}
                     </label>
                     <div 
                       onClick={() => {
-                         navigator.clipboard.writeText(invoice.contract_address);
+                         navigator.clipboard.writeText(payAddress);
                          setCopied(true);
                          setTimeout(() => setCopied(false), 2000);
                       }}
                       className="group relative flex items-center justify-between p-3 bg-gray-50 dark:bg-zinc-900/50 border border-gray-200 dark:border-zinc-700 rounded-lg cursor-pointer hover:border-blue-400 dark:hover:border-blue-500 transition-colors"
                     >
                       <code className="text-xs text-gray-800 dark:text-gray-200 truncate pr-8 font-mono">
-                        {invoice.contract_address}
+                        {payAddress}
                       </code>
                       <div className="absolute right-3 p-1.5 rounded-md text-gray-400 group-hover:text-blue-500 group-hover:bg-blue-50 dark:group-hover:bg-blue-900/20 transition-all">
                         {copied ? <Check className="w-4 h-4" /> : <Copy className="w-4 h-4" />}
@@ -168,33 +213,77 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
                     </div>
                   </div>
 
-                  <div>
-                    <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wider">
-                      Invoice ID (uint256)
-                    </label>
-                    <div className="flex items-center justify-center p-4 bg-gray-50 dark:bg-zinc-900/50 border border-gray-200 dark:border-zinc-700 rounded-lg min-h-[60px]">
-                      {invoice.onchain_invoice_id ? (
-                        <code className="text-lg font-bold text-gray-800 dark:text-gray-200 font-mono">
-                          {invoice.onchain_invoice_id}
-                        </code>
-                      ) : (
-                        <div className="flex flex-col items-center gap-2">
-                          <div className="flex items-center gap-2 text-blue-600 dark:text-blue-400">
-                             <Loader2 className="w-4 h-4 animate-spin" />
-                             <span className="text-sm font-medium">Syncing with blockchain...</span>
+                  {isTransfer ? (
+                    <div>
+                      <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wider">
+                        Amount (wei)
+                      </label>
+                      <div className="p-3 bg-gray-50 dark:bg-zinc-900/50 border border-gray-200 dark:border-zinc-700 rounded-lg">
+                        <code className="block text-xs text-gray-800 dark:text-gray-200 break-all font-mono">{invoice.amount_wei}</code>
+                      </div>
+                    </div>
+                  ) : isSigned ? (
+                    <div className="space-y-2">
+                      {[
+                        ['Amount (wei)', invoice.amount_wei],
+                        ['Expires At (unix)', String(Math.floor(new Date(invoice.expires_at).getTime() / 1000))],
+                        ['Salt', invoice.salt],
+                        ['Content Hash', invoice.content_hash ?? '0x' + '0'.repeat(64)],
+                        ['Signature', invoice.signature],
+                      ].map(([label, value]) => (
+                        <div key={label}>
+                          <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wider">
+                            {label}
+                          </label>
+                          <div className="p-3 bg-gray-50 dark:bg-zinc-900/50 border border-gray-200 dark:border-zinc-700 rounded-lg">
+                            <code className="block text-xs text-gray-800 dark:text-gray-200 break-all font-mono">{value}</code>
                           </div>
-                          <p className="text-[10px] text-gray-400 text-center px-4 leading-tight">
-                            Your transaction is being processed. The ID will appear once it's mined.
-                          </p>
                         </div>
-                      )}
+                      ))}
                     </div>
-                  </div>
+                  ) : (
+                    <div>
+                      <label className="text-xs font-semibold text-gray-500 dark:text-gray-400 uppercase tracking-wider">
+                        Invoice ID (uint256)
+                      </label>
+                      <div className="flex items-center justify-center p-4 bg-gray-50 dark:bg-zinc-900/50 border border-gray-200 dark:border-zinc-700 rounded-lg min-h-[60px]">
+                        {invoice.onchain_invoice_id ? (
+                          <code className="text-lg font-bold text-gray-800 dark:text-gray-200 font-mono">
+                            {invoice.onchain_invoice_id}
+                          </code>
+                        ) : (
+                          <div className="flex flex-col items-center gap-2">
+                            <div className="flex items-center gap-2 text-blue-600 dark:text-blue-400">
+                               <Loader2 className="w-4 h-4 animate-spin" />
+                               <span className="text-sm font-medium">Syncing with blockchain...</span>
+                            </div>
+                            <p className="text-[10px] text-gray-400 text-center px-4 leading-tight">
+                              Your transaction is being processed. The ID will appear once it's mined.
+                            </p>
+                          </div>
+                        )}
+                      </div>
+                    </div>
+                  )}
                 </div>
 
+                {invoice.payment_uri && (
+                  <div className="flex flex-col items-center gap-3 pt-2">
+                    <div className="p-3 bg-white rounded-lg border border-gray-200">
+                      <QRCodeSVG value={invoice.payment_uri} size={176} />
+                    </div>
+                    <a
+                      href={invoice.payment_uri}
+                      className="block w-full border border-blue-600 text-blue-600 hover:bg-blue-50 dark:hover:bg-blue-900/20 font-bold py-3 px-4 rounded-lg text-center transition-colors"
+                    >
+                      Open in Wallet
+                    </a>
+                  </div>
+                )}
+
                 <div className="pt-2">
                    <a 
-                     href={`https://testnet.qubetics.work/address/${invoice.contract_address}`}
+                     href={`https://testnet.qubetics.work/address/${payAddress}`}
                      target="_blank"
                      rel="noopener noreferrer"
                      className="block w-full bg-blue-600 hover:bg-blue-700 text-white font-bold py-3 px-4 rounded-lg text-center transition-colors shadow-lg shadow-blue-500/20"
@@ -233,6 +322,20 @@ export default function InvoicePage({ params }: { params: Promise<{ id: string }
             </div>
           </div>
 
+          <div className="mt-6 flex justify-center gap-4 text-sm">
+            <a href={invoicePdfUrl(invoice.id)} target="_blank" rel="noopener noreferrer" className="text-blue-600 hover:text-blue-700 hover:underline">
+              Download invoice (PDF)
+            </a>
+            <a href={checkoutUrl(invoice.id)} target="_blank" rel="noopener noreferrer" className="text-blue-600 hover:text-blue-700 hover:underline">
+              Checkout page
+            </a>
+            {['PAID', 'PARTIALLY_REFUNDED', 'REFUNDED'].includes(invoice.status) && (
+              <a href={receiptPdfUrl(invoice.id)} target="_blank" rel="noopener noreferrer" className="text-blue-600 hover:text-blue-700 hover:underline">
+                Download receipt (PDF)
+              </a>
+            )}
+          </div>
+
           <div className="mt-8 text-center">
             <Link href="/" className="text-sm text-blue-600 hover:text-blue-700 hover:underline">
               Create another invoice
@@ -26,3 +26,17 @@ export async function getInvoice(id: string): Promise<Invoice> {
   
   return res.json();
 }
+
+export function invoicePdfUrl(id: string): string {
+  return `${API_BASE_URL}/invoices/${id}/pdf`;
+}
+
+// The hosted checkout is served by the backend outside /api
+export function checkoutUrl(id: string): string {
+  return `${API_BASE_URL.replace(/\/api$/, '')}/pay/${id}`;
+}
+
+// Only served once the invoice is paid
+export function receiptPdfUrl(id: string): string {
+  return `${API_BASE_URL}/invoices/${id}/receipt.pdf`;
+}
@@ -1,20 +1,151 @@
+export type InvoiceMode = 'onchain' | 'signed' | 'transfer' | 'deposit';
+
 export interface Invoice {
   id: string;
+  mode: InvoiceMode;
+  invoice_number?: string; // Per-merchant, e.g. INV-2026-000123
   onchain_invoice_id: string;
   merchant_address: string;
+  customer_id?: string;
+  payment_link_id?: string; // Set when a payment link created the invoice
+  subscription_id?: string; // Set when subscription billing created the invoice
+  reissue_of_id?: string; // Expired invoice this one replaces
+  reissue_count?: number;
+  price_currency?: string; // Fiat price the amount was converted from
+  price_amount?: string;
   amount_wei: string;
   amount_eth: string; // Display
   contract_address: string;
-  status: 'PENDING' | 'PAID' | 'EXPIRED';
+  // EIP-681 request paying the rest of the invoice, while it can be paid
+  // (not for signed invoices). Also served as /qr.png and /qr.svg.
+  payment_uri?: string;
+  status: 'PENDING' | 'PARTIALLY_PAID' | 'PAID' | 'EXPIRED' | 'PARTIALLY_REFUNDED' | 'REFUNDED';
   expires_at: string;
   payer_address?: string;
+  // On-chain invoices: the createInvoice transaction.
+  creation_tx_hash?: string;
+  // The payment that settled the invoice.
+  tx_hash?: string;
+  // Signed (off-chain) invoices: pass merchant, amount_wei, expiry, salt and
+  // content_hash (zero if absent) as the invoice tuple to paySignedInvoice
+  // together with the signature.
+  invoice_hash?: string;
+  salt?: string;
+  signature?: string;
+  // Transfer invoices: send exactly amount_wei to payment_address.
+  payment_address?: string;
+  // Deposit invoices: send amount_wei (in one or more transfers) to deposit_address.
+  deposit_address?: string;
+  sweep_tx_hash?: string;
+  allow_partial: boolean;
+  amount_paid_wei: string;
+  payments?: Payment[];
+  overpayments?: Overpayment[];
+  refunded_wei: string;
+  // Itemized invoices: the breakdown totalling amount_wei and its hash.
+  line_items?: LineItem[];
+  subtotal_wei?: string;
+  discount_wei?: string;
+  tax_wei?: string;
+  shipping_wei?: string;
+  content_hash?: string;
+  // Where the hosted checkout sends the payer, overriding the merchant's
+  success_url?: string;
+  expired_url?: string;
+  refunds?: Refund[];
+  created_at: string;
+  updated_at: string;
+}
+
+export interface LineItem {
+  id: string;
+  position: number;
+  description: string;
+  quantity: string;
+  unit_price_wei: string;
+  tax_rate_bps: number;
+  subtotal_wei: string;
+  discount_wei: string;
+  tax_wei: string;
+  total_wei: string;
+}
+
+export interface LineItemRequest {
+  description: string;
+  quantity: string;
+  unit_price_eth: string;
+  tax_rate_bps?: number;
+}
+
+export interface Payment {
+  id: string;
+  chain_id: number;
+  tx_hash: string;
+  log_index: number;
+  block_number: number;
+  block_hash?: string;
+  block_time?: string;
+  token: string;
+  payer_address: string;
+  amount_wei: string;
+  gas_paid_wei?: string;
+  created_at: string;
+}
+
+export interface Overpayment {
+  id: string;
+  payment_id: string;
+  payer_address: string;
+  amount_wei: string;
+  status: 'OPEN' | 'REFUNDED';
+}
+
+export interface Refund {
+  id: string;
+  amount_wei: string;
+  to_address: string;
+  method: 'signer' | 'merchant';
+  status: 'REQUESTED' | 'SENT' | 'CONFIRMED' | 'FAILED';
+  overpayment_id?: string;
   tx_hash?: string;
+  // Merchant-signed refunds: EIP-681 URI to pay from the merchant wallet.
+  payment_request?: string;
+  reason?: string;
+  error?: string;
+  confirmed_at?: string;
+  created_at: string;
+}
+
+export interface Customer {
+  id: string;
+  name: string;
+  email?: string;
+  billing_address?: string;
+  tax_id?: string;
+  preferred_currency?: string;
+  wallets?: CustomerWallet[];
   created_at: string;
   updated_at: string;
 }
 
+export interface CustomerWallet {
+  id: string;
+  address: string;
+  source: 'manual' | 'payment';
+  created_at: string;
+}
+
 export interface CreateInvoiceRequest {
   merchant_address?: string;
-  amount_eth: number;
-  expiry_minutes: number;
+  amount_eth?: number; // Required unless line_items are given
+  expiry_minutes?: number;
+  mode?: InvoiceMode;
+  allow_partial?: boolean;
+  line_items?: LineItemRequest[];
+  discount_eth?: string;
+  discount_bps?: number;
+  shipping_eth?: string;
+  customer_id?: string;
+  success_url?: string;
+  expired_url?: string;
 }